@@ -0,0 +1,80 @@
+// Copyright © 2018 Anduin Transactions Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/anduintransaction/rivendell/project"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/spf13/cobra"
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply [plan file]",
+	Short: "Run a plan saved by the plan command",
+	Long: `Run a plan saved by the plan command
+
+The saved manifests are applied as they were rendered, without reading the project file, resource files or variables.
+The plan is refused when it was modified, or when the namespace or kubernetes context differs from the planned ones.
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		p, saved, err := project.ReadSavedPlan(args[0], namespace, context, kubeConfig)
+		if err != nil {
+			fatal(err)
+		}
+		p.SetParallelism(parallelism).SetBatch(batch).SetKeepGoing(keepGoing)
+		setOperationOutput(p)
+		p.PrintCommonInfo()
+		p.PrintSavedPlan(humanOutput, saved)
+		ctx := interruptContext()
+		plan, err := p.Plan(ctx, saved.Operation, false)
+		if err != nil {
+			fatal(err)
+		}
+		p.PrintPlan(humanOutput, plan)
+		if !yes {
+			utils.Askf(humanOutput, "Apply plan?", "yes", "no")
+			ok, err := utils.ExpectAnswer("yes")
+			if err != nil {
+				fatal(err)
+			}
+			if !ok {
+				fatal(project.ErrAborted{})
+			}
+		}
+		ctx = lockNamespace(ctx, p, saved.Operation)
+		defer releaseLock()
+		err = p.CheckPlan(ctx, plan, false, nil)
+		if err != nil {
+			fatal(err)
+		}
+		timings := p.RecordTimings()
+		err = p.Apply(ctx, saved.Operation)
+		reportTimings(p, timings)
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(applyCmd)
+	addOperationOutputFlags(applyCmd)
+	addGroupFlags(applyCmd)
+
+	applyCmd.Flags().BoolVar(&batch, "batch", false, "Apply all resources of a group in a single request")
+	applyCmd.Flags().BoolVar(&forceConflicts, "force-conflicts", false, "With the native backend, take over the fields owned by another field manager instead of failing")
+}
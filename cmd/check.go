@@ -35,7 +35,7 @@ var checkCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		_, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
 		utils.Success("Success")
 	},
@@ -41,7 +41,7 @@ var debugCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
 
 		var filterFns []project.FilterFunc
@@ -0,0 +1,55 @@
+// Copyright © 2018 Anduin Transactions Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/anduintransaction/rivendell/project"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff [project file]",
+	Short: "Show the differences between the rendered resources and the cluster",
+	Long: `Show the differences between the rendered resources and the cluster
+
+Fields set or defaulted by the server are ignored.
+Exit status is 0 when there are no differences, 1 when there are differences and 2 on error.
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
+		if err != nil {
+			utils.Error(err)
+			os.Exit(2)
+		}
+		p.PrintCommonInfo()
+		diffs, err := p.Diff(interruptContext())
+		if err != nil {
+			utils.Error(err)
+			os.Exit(2)
+		}
+		if p.PrintDiff(os.Stdout, diffs) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(diffCmd)
+}
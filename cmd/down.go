@@ -15,8 +15,6 @@
 package cmd
 
 import (
-	"os"
-
 	"github.com/anduintransaction/rivendell/project"
 	"github.com/anduintransaction/rivendell/utils"
 	"github.com/spf13/cobra"
@@ -36,29 +34,42 @@ var downCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
+		p.SetParallelism(parallelism).SetKeepGoing(keepGoing)
+		setOperationOutput(p)
 		p.PrintCommonInfo()
-		p.PrintDownPlan()
+		ctx := interruptContext()
+		plan, err := p.Plan(ctx, project.OperationDown, pvcDown)
+		if err != nil {
+			fatal(err)
+		}
+		p.PrintPlan(humanOutput, plan)
 		if !yes {
-			utils.Ask("Destroy all resource?", "yes", "no")
+			utils.Askf(humanOutput, "Destroy all resource?", "yes", "no")
 			ok, err := utils.ExpectAnswer("yes")
 			if err != nil {
-				utils.Fatal(err)
+				fatal(err)
 			}
 			if !ok {
-				os.Exit(0)
+				fatal(project.ErrAborted{})
 			}
 		}
-		err = p.Down(nsDown, pvcDown)
+		ctx = lockNamespace(ctx, p, project.OperationDown)
+		defer releaseLock()
+		timings := p.RecordTimings()
+		err = p.Down(ctx, nsDown, pvcDown)
+		reportTimings(p, timings)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(downCmd)
+	addOperationOutputFlags(downCmd)
+	addGroupFlags(downCmd)
 
 	downCmd.Flags().BoolVar(&nsDown, "ns", true, "Also remove namespace")
 	downCmd.Flags().BoolVar(&pvcDown, "pvc", true, "Also remove pvc")
@@ -0,0 +1,64 @@
+// Copyright © 2018 Anduin Transactions Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/anduintransaction/rivendell/project"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/spf13/cobra"
+)
+
+var driftOutput string
+
+// driftCmd represents the drift command
+var driftCmd = &cobra.Command{
+	Use:   "drift [project file]",
+	Short: "Report resources which drifted from the project",
+	Long: `Report resources which drifted from the project
+
+Every resource of the project is checked against the cluster: missing resources, resources which differ from the
+rendered manifests, and resources applied by rivendell before which the project does not declare anymore are reported.
+Exit status is 0 when everything is in sync, 1 when something drifted and 2 on error.
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
+		if err != nil {
+			utils.Error(err)
+			os.Exit(2)
+		}
+		report, err := p.Drift(interruptContext())
+		if err != nil {
+			utils.Error(err)
+			os.Exit(2)
+		}
+		err = p.PrintDriftReport(os.Stdout, report, driftOutput)
+		if err != nil {
+			utils.Error(err)
+			os.Exit(2)
+		}
+		if report.Drifted() {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(driftCmd)
+
+	driftCmd.Flags().StringVarP(&driftOutput, "output", "o", "table", "report format, one of: table|json")
+}
@@ -18,7 +18,6 @@ import (
 	"os"
 
 	"github.com/anduintransaction/rivendell/project"
-	"github.com/anduintransaction/rivendell/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -32,7 +31,7 @@ var generateCmd = &cobra.Command{
 		filename := args[0]
 		w, err := os.Create(filename)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
 		defer w.Close()
 		config := &project.Config{
@@ -68,7 +67,7 @@ var generateCmd = &cobra.Command{
 		}
 		err = config.Write(w)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
 	},
 }
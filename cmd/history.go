@@ -0,0 +1,57 @@
+// Copyright © 2018 Anduin Transactions Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/anduintransaction/rivendell/project"
+	"github.com/spf13/cobra"
+)
+
+var historyRevision int
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history [project file]",
+	Short: "List the releases of a project recorded in its namespace",
+	Long:  "List the releases of a project recorded in its namespace",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
+		if err != nil {
+			fatal(err)
+		}
+		ctx := interruptContext()
+		if historyRevision > 0 {
+			release, err := p.Release(ctx, historyRevision)
+			if err != nil {
+				fatal(err)
+			}
+			p.PrintRelease(os.Stdout, release)
+			return
+		}
+		releases, err := p.History(ctx)
+		if err != nil {
+			fatal(err)
+		}
+		p.PrintHistory(os.Stdout, releases)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().IntVar(&historyRevision, "revision", 0, "Print the variables and manifests of a release")
+}
@@ -16,7 +16,6 @@ package cmd
 
 import (
 	"github.com/anduintransaction/rivendell/project"
-	"github.com/anduintransaction/rivendell/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -34,9 +33,9 @@ the first container will be chosen.
 `,
 	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		err := project.Logs(namespace, context, kubeConfig, args[0], logContainer, logTimeout)
+		err := project.Logs(interruptContext(), namespace, context, kubeConfig, args[0], logContainer, logTimeout)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
 	},
 }
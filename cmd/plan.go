@@ -0,0 +1,67 @@
+// Copyright © 2018 Anduin Transactions Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/anduintransaction/rivendell/project"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/spf13/cobra"
+)
+
+var planOutput string
+var planOperation string
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan [project file]",
+	Short: "Show what an operation will do, and save the rendered project to apply it later",
+	Long: `Show what an operation will do, and save the rendered project to apply it later
+
+With --out, the fully rendered resource groups, waits, namespace and context are written to a file.
+The apply command runs the saved plan without reading the project file, resource files or variables again.
+The file holds the rendered manifests, including secrets and variable values: it is only readable by its owner,
+keep it out of version control and shared storage.
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
+		if err != nil {
+			fatal(err)
+		}
+		p.PrintCommonInfo()
+		plan, err := p.Plan(interruptContext(), planOperation, false)
+		if err != nil {
+			fatal(err)
+		}
+		p.PrintPlan(os.Stdout, plan)
+		if planOutput == "" {
+			return
+		}
+		saved, err := p.SavePlan(planOutput, planOperation, plan)
+		if err != nil {
+			fatal(err)
+		}
+		utils.Success("Plan saved to %q, checksum %s", planOutput, saved.Checksum)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(planCmd)
+
+	planCmd.Flags().StringVarP(&planOutput, "out", "o", "", "save the plan to this file, readable by its owner only as it contains secrets")
+	planCmd.Flags().StringVar(&planOperation, "operation", project.OperationUpgrade, "operation to plan, one of: up|update|upgrade")
+}
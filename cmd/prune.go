@@ -0,0 +1,75 @@
+// Copyright © 2018 Anduin Transactions Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/anduintransaction/rivendell/project"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/spf13/cobra"
+)
+
+var prune bool
+
+// pruneCmd represents the prune command
+var pruneCmd = &cobra.Command{
+	Use:   "prune [project file]",
+	Short: "Delete resources applied by rivendell which are not in the project file anymore",
+	Long:  "Delete resources applied by rivendell which are not in the project file anymore",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
+		if err != nil {
+			fatal(err)
+		}
+		setOperationOutput(p)
+		p.PrintCommonInfo()
+		ctx := interruptContext()
+		candidates, err := p.PrunePlan(ctx)
+		if err != nil {
+			fatal(err)
+		}
+		p.PrintPrunePlan(humanOutput, candidates)
+		if len(candidates) == 0 {
+			return
+		}
+		if !yes {
+			utils.Askf(humanOutput, "Prune all resource?", "yes", "no")
+			ok, err := utils.ExpectAnswer("yes")
+			if err != nil {
+				fatal(err)
+			}
+			if !ok {
+				fatal(project.ErrAborted{})
+			}
+		}
+		ctx = lockNamespace(ctx, p, "prune")
+		defer releaseLock()
+		err = p.CheckPlan(ctx, nil, true, candidates)
+		if err != nil {
+			fatal(err)
+		}
+		timings := p.RecordTimings()
+		err = p.Prune(ctx, candidates)
+		reportTimings(p, timings)
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(pruneCmd)
+	addOperationOutputFlags(pruneCmd)
+}
@@ -21,8 +21,6 @@
 package cmd
 
 import (
-	"os"
-
 	"github.com/anduintransaction/rivendell/project"
 	"github.com/anduintransaction/rivendell/utils"
 	"github.com/spf13/cobra"
@@ -37,31 +35,38 @@ var restartCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
+		setOperationOutput(p)
 		pods, err := p.GetServicePods()
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
 		p.PrintCommonInfo()
-		p.PrintRestartPlan(pods)
+		p.PrintRestartPlan(humanOutput, pods)
 		if !yes {
-			utils.Ask("Restart all pods?", "yes", "no")
+			utils.Askf(humanOutput, "Restart all pods?", "yes", "no")
 			ok, err := utils.ExpectAnswer("yes")
 			if err != nil {
-				utils.Fatal(err)
+				fatal(err)
 			}
 			if !ok {
-				os.Exit(0)
+				fatal(project.ErrAborted{})
 			}
 		}
-		err = p.Restart(pods)
+		ctx := interruptContext()
+		ctx = lockNamespace(ctx, p, "restart")
+		defer releaseLock()
+		timings := p.RecordTimings()
+		err = p.Restart(ctx, pods)
+		reportTimings(p, timings)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(restartCmd)
+	addOperationOutputFlags(restartCmd)
 }
@@ -0,0 +1,105 @@
+// Copyright © 2018 Anduin Transactions Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/anduintransaction/rivendell/project"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/spf13/cobra"
+)
+
+var rollbackRevision int
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [project file]",
+	Short: "Re-apply the manifests of a previous release",
+	Long: `Re-apply the manifests of a previous release
+
+The manifests recorded with the release are applied again like the upgrade command,
+following the resource groups and waits of the release. Use the history command to list releases.
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if rollbackRevision <= 0 {
+			fmt.Fprintln(os.Stderr, "Missing release to roll back to, use --to")
+			os.Exit(2)
+		}
+		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
+		if err != nil {
+			fatal(err)
+		}
+		p.SetParallelism(parallelism).SetBatch(batch).SetKeepGoing(keepGoing)
+		setOperationOutput(p)
+		p.PrintCommonInfo()
+		ctx := interruptContext()
+		rolled, err := p.ForRelease(ctx, rollbackRevision)
+		if err != nil {
+			fatal(err)
+		}
+		plan, err := rolled.Plan(ctx, project.OperationUpgrade, false)
+		if err != nil {
+			fatal(err)
+		}
+		rolled.PrintRollbackPlan(humanOutput, plan)
+		var pruneCandidates []*project.InventoryEntry
+		if prune {
+			pruneCandidates, err = rolled.PrunePlan(ctx)
+			if err != nil {
+				fatal(err)
+			}
+			rolled.PrintPrunePlan(humanOutput, pruneCandidates)
+		}
+		if !yes {
+			utils.Askf(humanOutput, "Roll back all resource?", "yes", "no")
+			ok, err := utils.ExpectAnswer("yes")
+			if err != nil {
+				fatal(err)
+			}
+			if !ok {
+				fatal(project.ErrAborted{})
+			}
+		}
+		ctx = lockNamespace(ctx, rolled, "rollback")
+		defer releaseLock()
+		err = rolled.CheckPlan(ctx, plan, prune, pruneCandidates)
+		if err != nil {
+			fatal(err)
+		}
+		timings := rolled.RecordTimings()
+		err = rolled.Rollback(ctx)
+		if err == nil && prune {
+			err = rolled.Prune(ctx, pruneCandidates)
+		}
+		reportTimings(rolled, timings)
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(rollbackCmd)
+	addOperationOutputFlags(rollbackCmd)
+	addGroupFlags(rollbackCmd)
+
+	rollbackCmd.Flags().BoolVar(&batch, "batch", false, "Apply all resources of a group in a single request")
+	rollbackCmd.Flags().BoolVar(&forceConflicts, "force-conflicts", false, "With the native backend, take over the fields owned by another field manager instead of failing")
+	rollbackCmd.Flags().IntVar(&rollbackRevision, "to", 0, "Version of the release to roll back to")
+	rollbackCmd.Flags().BoolVar(&prune, "prune", false, "Also delete resources which did not exist in the release")
+}
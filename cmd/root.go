@@ -21,10 +21,19 @@
 package cmd
 
 import (
+	gocontext "context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/anduintransaction/rivendell/project"
+	"github.com/anduintransaction/rivendell/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -37,6 +46,30 @@ var variableMap = map[string]string{}
 var includeResources []string
 var excludeResources []string
 var yes = false
+var backend string
+var restartThreshold int
+var unschedulableGracePeriod time.Duration
+var diagnosticsDir string
+var diagnosticsTailLines int
+var parallelism int
+var batch bool
+var keepGoing bool
+var forceConflicts bool
+var deployID string
+var operationOutput string
+var reportJUnit string
+var errorFormat string
+var waitForLock time.Duration
+var lockTTL time.Duration
+
+// namespaceLock is the lock held by the running command, see lockNamespace
+var namespaceLock *project.Lock
+
+// operationTimings is the timing report of the last operation, it names the resource of a failure
+var operationTimings *project.TimingReport
+
+// humanOutput receives the logs, plans and prompts of an operation, see setOperationOutput
+var humanOutput io.Writer = os.Stdout
 
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
@@ -52,6 +85,18 @@ var RootCmd = &cobra.Command{
 			}
 			variableMap[segments[0]] = segments[1]
 		}
+		kubernetes.DefaultBackend = backend
+		kubernetes.DefaultRestartThreshold = restartThreshold
+		kubernetes.DefaultUnschedulableGracePeriod = unschedulableGracePeriod
+		kubernetes.ForceConflicts = forceConflicts
+		project.DiagnosticsDir = diagnosticsDir
+		project.DiagnosticsTailLines = diagnosticsTailLines
+		project.DeployID = deployID
+		project.LockTTL = lockTTL
+		if errorFormat != "text" && errorFormat != "json" {
+			fmt.Fprintf(os.Stderr, "Invalid error format: %s, one of: text|json\n", errorFormat)
+			os.Exit(2)
+		}
 	},
 }
 
@@ -64,6 +109,111 @@ func Execute() {
 	}
 }
 
+// interruptContext is cancelled by the first interrupt, which aborts the current operation cleanly.
+// A second interrupt exits immediately.
+func interruptContext() gocontext.Context {
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		utils.Warnf(humanOutput, "Interrupted, aborting... Press Ctrl-C again to exit immediately")
+		cancel()
+		<-signals
+		os.Exit(130)
+	}()
+	return ctx
+}
+
+// addGroupFlags adds the flags deciding how resource groups are walked to a command walking them
+func addGroupFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&parallelism, "parallelism", 1, "maximum number of resource groups processed at the same time")
+	cmd.Flags().BoolVar(&keepGoing, "keep-going", false, "go on after a resource group failed, skipping the groups depending on it")
+}
+
+// addOperationOutputFlags adds --output and --report-junit to a command changing the cluster,
+// see setOperationOutput and reportTimings
+func addOperationOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&operationOutput, "output", "text", "output format, one of: text|jsonl. With jsonl, a json event is written to stdout for every step and the logs go to stderr")
+	cmd.Flags().StringVar(&reportJUnit, "report-junit", "", "write the duration and result of every group and wait to a JUnit XML file")
+}
+
+// setOperationOutput applies --output to a project. With jsonl, stdout only receives the events of the project,
+// the logs, plans and prompts of the project and of the command are written to stderr through humanOutput.
+func setOperationOutput(p *project.Project) {
+	switch operationOutput {
+	case "text":
+		humanOutput = os.Stdout
+	case "jsonl":
+		humanOutput = os.Stderr
+		p.SetEventOutput(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid output: %s, one of: text|jsonl\n", operationOutput)
+		os.Exit(2)
+	}
+	p.SetOutput(humanOutput)
+}
+
+// reportTimings prints the timing report of the operations, and writes it to --report-junit when set
+func reportTimings(p *project.Project, timings *project.TimingReport) {
+	operationTimings = timings
+	fmt.Fprintln(humanOutput)
+	p.PrintTimingReport(humanOutput, timings)
+	if reportJUnit == "" {
+		return
+	}
+	err := timings.WriteJUnit(reportJUnit)
+	if err != nil {
+		utils.Error(err)
+	}
+}
+
+// lockNamespace takes the lock of the namespace of a project for a command, waiting up to --wait-for-lock for it.
+// It returns the context to run the command with, cancelled when the lock is lost.
+// Commands release it with a deferred releaseLock, fatal releases it before exiting.
+func lockNamespace(ctx gocontext.Context, p *project.Project, command string) gocontext.Context {
+	lock, err := p.Lock(ctx, command, waitForLock)
+	if err != nil {
+		fatal(err)
+	}
+	namespaceLock = lock
+	return lock.Context(ctx)
+}
+
+// releaseLock releases the lock of the command, the command fails when it lost the lock
+func releaseLock() {
+	if namespaceLock == nil {
+		return
+	}
+	lost := namespaceLock.Err()
+	err := namespaceLock.Release()
+	namespaceLock = nil
+	if err != nil {
+		utils.Error(err)
+	}
+	if lost != nil {
+		fatal(lost)
+	}
+}
+
+// fatal prints an error as --error-format tells, then exits with the code of its family, see project.ErrorType
+func fatal(err error) {
+	releaseLock()
+	report := project.NewErrorReport(err)
+	if report.Resource == nil && operationTimings != nil {
+		report.Resource = operationTimings.FailedResource()
+	}
+	if errorFormat == "json" {
+		content, jsonErr := json.Marshal(report)
+		if jsonErr == nil {
+			fmt.Fprintln(os.Stderr, string(content))
+			os.Exit(report.ExitCode)
+		}
+	}
+	utils.Error(err)
+	os.Exit(report.ExitCode)
+}
+
 func init() {
 	RootCmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "", "set kubernetes namespace")
 	RootCmd.PersistentFlags().StringVarP(&context, "context", "c", "", "set kubernetes context")
@@ -73,4 +223,13 @@ func init() {
 	RootCmd.PersistentFlags().BoolVarP(&yes, "yes", "y", false, "Run command immediately")
 	RootCmd.PersistentFlags().StringArrayVar(&includeResources, "include", []string{}, "include file patterns, for example --include=**/service.yml --include=**/deployment.yml")
 	RootCmd.PersistentFlags().StringArrayVar(&excludeResources, "exclude", []string{}, "exclude file patterns, for example --exclude=**/config.yml --exclude=**/secret.yml")
+	RootCmd.PersistentFlags().StringVar(&backend, "backend", kubernetes.DefaultBackend, "cluster backend, one of: "+strings.Join(kubernetes.Backends(), "|"))
+	RootCmd.PersistentFlags().IntVar(&restartThreshold, "restart-threshold", kubernetes.DefaultRestartThreshold, "fail waiting for a pod or job once a container restarted this many times (value <= 0 to disable)")
+	RootCmd.PersistentFlags().DurationVar(&unschedulableGracePeriod, "unschedulable-grace-period", kubernetes.DefaultUnschedulableGracePeriod, "fail waiting for a pod or job once a pod stayed unschedulable this long (value <= 0 to fail at once)")
+	RootCmd.PersistentFlags().StringVar(&diagnosticsDir, "diagnostics-dir", "", "write diagnostics of failed resources to this directory")
+	RootCmd.PersistentFlags().IntVar(&diagnosticsTailLines, "diagnostics-tail", project.DiagnosticsTailLines, "number of log lines collected for every container of a failed resource")
+	RootCmd.PersistentFlags().StringVar(&errorFormat, "error-format", "text", "format of the error ending a command, one of: text|json. With json, the type, resource and root cause of the error are written to stderr as one line")
+	RootCmd.PersistentFlags().DurationVar(&waitForLock, "wait-for-lock", 0, "wait this long for another run holding the lock of the namespace, fail at once when 0")
+	RootCmd.PersistentFlags().DurationVar(&lockTTL, "lock-ttl", project.LockTTL, "time after which the lock of a run which stopped renewing it can be taken over")
+	RootCmd.PersistentFlags().StringVar(&deployID, "deploy-id", "", "identifier of this run stamped on applied resources, generated when empty")
 }
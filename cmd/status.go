@@ -22,29 +22,77 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/anduintransaction/rivendell/project"
-	"github.com/anduintransaction/rivendell/utils"
 	"github.com/spf13/cobra"
 )
 
+var statusOutput string
+var statusWatch bool
+var statusInterval time.Duration
+
 // statusCmd represents the status command
 var statusCmd = &cobra.Command{
-	Use:   "status [kind] [name]",
-	Short: "Get status of a resource",
-	Long: `Get status of a resource.
+	Use:   "status [project file] | status [kind] [name]",
+	Short: "Get status of a project or of a resource",
+	Long: `Get status of a project or of a resource.
+
+With a project file, the status of every resource of the project is reported group by group. Workloads, jobs and pods
+also show their ready and desired replicas, container restarts and age. With --watch, the report is refreshed until
+interrupted.
 
+With a kind and a name, the status of the resource is printed.
 Possible return values: Unknown, NotExist, Pending, Active, Terminating, Succeeded, Failed`,
-	Args: cobra.MinimumNArgs(2),
+	Args: cobra.RangeArgs(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
-		rsStatus, err := project.Status(namespace, context, kubeConfig, args[0], args[1])
+		if len(args) == 2 {
+			rsStatus, err := project.Status(namespace, context, kubeConfig, args[0], args[1])
+			if err != nil {
+				fatal(err)
+			}
+			fmt.Printf("%s\n", rsStatus)
+			return
+		}
+		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
+		}
+		ctx := interruptContext()
+		for {
+			report, err := p.Status(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				fatal(err)
+			}
+			if statusWatch && statusOutput == "table" {
+				fmt.Print("\033[H\033[2J")
+			} else if statusWatch && statusOutput == "yaml" {
+				fmt.Println("---")
+			}
+			err = p.PrintStatusReport(os.Stdout, report, statusOutput)
+			if err != nil {
+				fatal(err)
+			}
+			if !statusWatch {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(statusInterval):
+			}
 		}
-		fmt.Printf("%s\n", rsStatus)
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().StringVarP(&statusOutput, "output", "o", "table", "project status format, one of: table|json|yaml")
+	statusCmd.Flags().BoolVarP(&statusWatch, "watch", "w", false, "refresh the project status until interrupted")
+	statusCmd.Flags().DurationVar(&statusInterval, "interval", 2*time.Second, "refresh interval of --watch")
 }
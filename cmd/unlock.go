@@ -0,0 +1,76 @@
+// Copyright © 2018 Anduin Transactions Inc
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/anduintransaction/rivendell/project"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/spf13/cobra"
+)
+
+// unlockCmd represents the unlock command
+var unlockCmd = &cobra.Command{
+	Use:   "unlock [project file]",
+	Short: "Remove the lock of a namespace left by a run which was killed",
+	Long: `Remove the lock of a namespace left by a run which was killed.
+
+Mutating commands lock the namespace while they run. The lock expires when its holder stops renewing it for longer
+than --lock-ttl, unlock removes it at once. The namespace is the one of the project file when given, else --namespace.`,
+	Args: cobra.RangeArgs(0, 1),
+	Run: func(cmd *cobra.Command, args []string) {
+		lockedNamespace := namespace
+		if len(args) == 1 {
+			p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
+			if err != nil {
+				fatal(err)
+			}
+			lockedNamespace = p.Namespace()
+		}
+		info, err := project.ReadLock(lockedNamespace, context, kubeConfig)
+		if err != nil {
+			fatal(err)
+		}
+		if info == nil {
+			utils.Info("Namespace %q is not locked", lockedNamespace)
+			return
+		}
+		state := "active"
+		if info.Expired(time.Now()) {
+			state = "expired"
+		}
+		utils.Warn("Namespace %q is locked by %s running %s on project %q since %s (%s)", lockedNamespace, info.Holder, info.Command, info.Project, info.Started.Local().Format("2006-01-02 15:04:05"), state)
+		if !yes {
+			utils.Ask("Remove the lock?", "yes", "no")
+			ok, err := utils.ExpectAnswer("yes")
+			if err != nil {
+				fatal(err)
+			}
+			if !ok {
+				fatal(project.ErrAborted{})
+			}
+		}
+		err = project.Unlock(lockedNamespace, context, kubeConfig)
+		if err != nil {
+			fatal(err)
+		}
+		utils.Success("====> Unlocked")
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(unlockCmd)
+}
@@ -15,13 +15,13 @@
 package cmd
 
 import (
-	"os"
-
 	"github.com/anduintransaction/rivendell/project"
 	"github.com/anduintransaction/rivendell/utils"
 	"github.com/spf13/cobra"
 )
 
+var resume bool
+
 // upCmd represents the up command
 var upCmd = &cobra.Command{
 	Use:   "up [project file]",
@@ -31,27 +31,48 @@ var upCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
+		p.SetParallelism(parallelism).SetBatch(batch).SetKeepGoing(keepGoing).SetResume(resume)
+		setOperationOutput(p)
 		p.PrintCommonInfo()
-		p.PrintUpPlan()
+		ctx := interruptContext()
+		plan, err := p.Plan(ctx, project.OperationUp, false)
+		if err != nil {
+			fatal(err)
+		}
+		p.PrintPlan(humanOutput, plan)
 		if !yes {
-			utils.Ask("Create all resource?", "yes", "no")
+			utils.Askf(humanOutput, "Create all resource?", "yes", "no")
 			ok, err := utils.ExpectAnswer("yes")
 			if err != nil {
-				utils.Fatal(err)
+				fatal(err)
 			}
 			if !ok {
-				os.Exit(0)
+				fatal(project.ErrAborted{})
 			}
 		}
-		err = p.Up()
+		ctx = lockNamespace(ctx, p, project.OperationUp)
+		defer releaseLock()
+		err = p.CheckPlan(ctx, plan, false, nil)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
+		}
+		timings := p.RecordTimings()
+		err = p.Up(ctx)
+		reportTimings(p, timings)
+		if err != nil {
+			fatal(err)
 		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(upCmd)
+	addOperationOutputFlags(upCmd)
+	addGroupFlags(upCmd)
+
+	upCmd.Flags().BoolVar(&batch, "batch", false, "Apply all resources of a group in a single request")
+	upCmd.Flags().BoolVar(&forceConflicts, "force-conflicts", false, "With the native backend, take over the fields owned by another field manager instead of failing")
+	upCmd.Flags().BoolVar(&resume, "resume", false, "Skip the groups completed by the last failed up which did not change since")
 }
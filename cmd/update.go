@@ -15,8 +15,6 @@
 package cmd
 
 import (
-	"os"
-
 	"github.com/anduintransaction/rivendell/project"
 	"github.com/anduintransaction/rivendell/utils"
 	"github.com/spf13/cobra"
@@ -31,27 +29,59 @@ var updateCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
+		p.SetParallelism(parallelism).SetBatch(batch).SetKeepGoing(keepGoing)
+		setOperationOutput(p)
 		p.PrintCommonInfo()
-		p.PrintUpdatePlan()
+		ctx := interruptContext()
+		plan, err := p.Plan(ctx, project.OperationUpdate, false)
+		if err != nil {
+			fatal(err)
+		}
+		p.PrintPlan(humanOutput, plan)
+		var pruneCandidates []*project.InventoryEntry
+		if prune {
+			pruneCandidates, err = p.PrunePlan(ctx)
+			if err != nil {
+				fatal(err)
+			}
+			p.PrintPrunePlan(humanOutput, pruneCandidates)
+		}
 		if !yes {
-			utils.Ask("Update all resource?", "yes", "no")
+			utils.Askf(humanOutput, "Update all resource?", "yes", "no")
 			ok, err := utils.ExpectAnswer("yes")
 			if err != nil {
-				utils.Fatal(err)
+				fatal(err)
 			}
 			if !ok {
-				os.Exit(0)
+				fatal(project.ErrAborted{})
 			}
 		}
-		err = p.Update()
+		ctx = lockNamespace(ctx, p, project.OperationUpdate)
+		defer releaseLock()
+		err = p.CheckPlan(ctx, plan, prune, pruneCandidates)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
+		}
+		timings := p.RecordTimings()
+		err = p.Update(ctx)
+		if err == nil && prune {
+			err = p.Prune(ctx, pruneCandidates)
+		}
+		reportTimings(p, timings)
+		if err != nil {
+			fatal(err)
 		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(updateCmd)
+	addOperationOutputFlags(updateCmd)
+	addGroupFlags(updateCmd)
+
+	updateCmd.Flags().BoolVar(&batch, "batch", false, "Apply all resources of a group in a single request")
+	updateCmd.Flags().BoolVar(&forceConflicts, "force-conflicts", false, "With the native backend, take over the fields owned by another field manager instead of failing")
+	updateCmd.Flags().BoolVar(&prune, "prune", false, "Also delete resources applied before which are not in the project file anymore")
 }
@@ -15,8 +15,6 @@
 package cmd
 
 import (
-	"os"
-
 	"github.com/anduintransaction/rivendell/project"
 	"github.com/anduintransaction/rivendell/utils"
 	"github.com/spf13/cobra"
@@ -37,27 +35,60 @@ Unlike update command, non-existed resources will be created.
 	Run: func(cmd *cobra.Command, args []string) {
 		p, err := project.ReadProject(args[0], namespace, context, kubeConfig, variableMap, variableFiles, includeResources, excludeResources)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
+		p.SetParallelism(parallelism).SetBatch(batch).SetKeepGoing(keepGoing).SetResume(resume)
+		setOperationOutput(p)
 		p.PrintCommonInfo()
-		p.PrintUpdatePlan()
+		ctx := interruptContext()
+		plan, err := p.Plan(ctx, project.OperationUpgrade, false)
+		if err != nil {
+			fatal(err)
+		}
+		p.PrintPlan(humanOutput, plan)
+		var pruneCandidates []*project.InventoryEntry
+		if prune {
+			pruneCandidates, err = p.PrunePlan(ctx)
+			if err != nil {
+				fatal(err)
+			}
+			p.PrintPrunePlan(humanOutput, pruneCandidates)
+		}
 		if !yes {
-			utils.Ask("Upgrade all resource?", "yes", "no")
+			utils.Askf(humanOutput, "Upgrade all resource?", "yes", "no")
 			ok, err := utils.ExpectAnswer("yes")
 			if err != nil {
-				utils.Fatal(err)
+				fatal(err)
 			}
 			if !ok {
-				os.Exit(0)
+				fatal(project.ErrAborted{})
 			}
 		}
-		err = p.Upgrade()
+		ctx = lockNamespace(ctx, p, project.OperationUpgrade)
+		defer releaseLock()
+		err = p.CheckPlan(ctx, plan, prune, pruneCandidates)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
+		}
+		timings := p.RecordTimings()
+		err = p.Upgrade(ctx)
+		if err == nil && prune {
+			err = p.Prune(ctx, pruneCandidates)
+		}
+		reportTimings(p, timings)
+		if err != nil {
+			fatal(err)
 		}
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(upgradeCmd)
+	addOperationOutputFlags(upgradeCmd)
+	addGroupFlags(upgradeCmd)
+
+	upgradeCmd.Flags().BoolVar(&batch, "batch", false, "Apply all resources of a group in a single request")
+	upgradeCmd.Flags().BoolVar(&forceConflicts, "force-conflicts", false, "With the native backend, take over the fields owned by another field manager instead of failing")
+	upgradeCmd.Flags().BoolVar(&prune, "prune", false, "Also delete resources applied before which are not in the project file anymore")
+	upgradeCmd.Flags().BoolVar(&resume, "resume", false, "Skip the groups completed by the last failed upgrade which did not change since")
 }
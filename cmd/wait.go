@@ -16,22 +16,24 @@ package cmd
 
 import (
 	"github.com/anduintransaction/rivendell/project"
-	"github.com/anduintransaction/rivendell/utils"
 	"github.com/spf13/cobra"
 )
 
 var waitTimeout int
+var waitFor string
 
 // waitCmd represents the wait command
 var waitCmd = &cobra.Command{
-	Use:   "wait [pod|job|deploy|deployment] [name]",
+	Use:   "wait [kind] [name]",
 	Short: "Wait for a resource to complete",
-	Long:  "Wait for a resource to complete",
-	Args:  cobra.MinimumNArgs(2),
+	Long: `Wait for a resource to complete.
+Without --for, pod, job, deployment, statefulset, daemonset, replicaset, persistentvolumeclaim and customresourcedefinition are supported.
+With --for, any kind can be waited for a status condition or a jsonpath value.`,
+	Args: cobra.MinimumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
-		err := project.Wait(namespace, context, kubeConfig, args[0], args[1], waitTimeout)
+		err := project.WaitFor(interruptContext(), namespace, context, kubeConfig, args[0], args[1], waitFor, waitTimeout)
 		if err != nil {
-			utils.Fatal(err)
+			fatal(err)
 		}
 	},
 }
@@ -40,4 +42,5 @@ func init() {
 	RootCmd.AddCommand(waitCmd)
 
 	waitCmd.Flags().IntVarP(&waitTimeout, "timeout", "t", 300, "timeout in second (value <= 0 for infinity)")
+	waitCmd.Flags().StringVar(&waitFor, "for", "", "condition to wait for: condition=<type>[=<status>] or jsonpath={<path>}[=<value>]")
 }
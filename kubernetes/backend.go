@@ -0,0 +1,106 @@
+package kubernetes
+
+import (
+	gocontext "context"
+	"io"
+	"sort"
+
+	"github.com/palantir/stacktrace"
+)
+
+// Backend is the layer talking to the kubernetes cluster.
+// Objects are exchanged as raw manifests (JSON or YAML), a missing object is reported with ErrNotExist.
+// Every call is aborted when its context is done.
+type Backend interface {
+	// Get returns the manifest of an object. Use an empty namespace for cluster-wide objects
+	Get(ctx gocontext.Context, namespace, kind, name string) ([]byte, error)
+	// List returns the manifests of all objects of a kind matching a label selector
+	List(ctx gocontext.Context, namespace, kind, selector string) ([][]byte, error)
+	// Apply creates or updates all objects in the manifest content
+	Apply(ctx gocontext.Context, namespace string, content []byte) ([]*ApplyResult, error)
+	// Create creates the single object of a manifest and returns it, an existing object is reported with
+	// ErrAlreadyExists
+	Create(ctx gocontext.Context, namespace string, content []byte) ([]byte, error)
+	// Replace updates the single object of a manifest and returns it. When the manifest holds a resourceVersion, an
+	// object modified since that version is reported with ErrConflict
+	Replace(ctx gocontext.Context, namespace string, content []byte) ([]byte, error)
+	// Delete an object
+	Delete(ctx gocontext.Context, namespace, kind, name string) error
+	// Watch an object for changes
+	Watch(ctx gocontext.Context, namespace, kind, name string) (Watcher, error)
+	// Logs streams the log of a container in a pod
+	Logs(ctx gocontext.Context, namespace, name string, opts *LogOptions, stdout, stderr io.Writer) error
+}
+
+// ApplyResult holds the outcome of applying a single object
+type ApplyResult struct {
+	Kind   string
+	Name   string
+	Action string
+}
+
+// LogOptions .
+type LogOptions struct {
+	Container string
+	Follow    bool
+	Tail      int
+}
+
+// WatchEvent .
+type WatchEvent struct {
+	Type   string
+	Object []byte
+}
+
+// Watch event types
+const (
+	WatchEventAdded    = "ADDED"
+	WatchEventModified = "MODIFIED"
+	WatchEventDeleted  = "DELETED"
+)
+
+// Watcher streams events of a watched object until stopped or its context is done
+type Watcher interface {
+	Events() <-chan *WatchEvent
+	Stop()
+}
+
+// BackendFactory creates a backend for a kubernetes context and config file
+type BackendFactory func(context, kubeConfig string) (Backend, error)
+
+// Backend names
+const (
+	BackendKubectl = "kubectl"
+	BackendNative  = "native"
+)
+
+// DefaultBackend is the backend used by NewContext
+var DefaultBackend = BackendKubectl
+
+var backendFactories = map[string]BackendFactory{
+	BackendKubectl: newKubectlBackend,
+	BackendNative:  newNativeBackend,
+}
+
+// RegisterBackend makes a backend available by name
+func RegisterBackend(name string, factory BackendFactory) {
+	backendFactories[name] = factory
+}
+
+// Backends returns all registered backend names
+func Backends() []string {
+	names := []string{}
+	for name := range backendFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newBackend(name, context, kubeConfig string) (Backend, error) {
+	factory, ok := backendFactories[name]
+	if !ok {
+		return nil, stacktrace.Propagate(ErrUnknownBackend{name}, "unknown backend")
+	}
+	return factory(context, kubeConfig)
+}
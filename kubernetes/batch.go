@@ -0,0 +1,171 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/palantir/stacktrace"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// BatchResource is a resource created, updated or upgraded together with others
+type BatchResource struct {
+	Name       string
+	Kind       string
+	RawContent string
+}
+
+// BatchResult is the outcome of a batch operation for a single resource
+type BatchResult struct {
+	Name string
+	Kind string
+	// Exists tells CreateBatch left an existing resource untouched
+	Exists bool
+	// UpdateStatus is set by UpdateBatch and UpgradeBatch
+	UpdateStatus UpdateStatus
+	// Action is created, configured or unchanged for applied resources, empty otherwise
+	Action string
+}
+
+// GetStatuses returns the status of many resources, listing each kind once instead of getting every resource
+func (r *Resource) GetStatuses(resources []*BatchResource) ([]RsStatus, error) {
+	manifests := make(map[string][]byte)
+	listed := make(map[string]bool)
+	statuses := []RsStatus{}
+	for _, resource := range resources {
+		kind := strings.ToLower(resource.Kind)
+		normalizedKind := normalizeKind(kind)
+		if !listed[normalizedKind] {
+			items, err := r.context.backend.List(r.context.ctx, r.context.namespaceFor(kind), kind, "")
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range items {
+				name, err := manifestName(item)
+				if err != nil {
+					return nil, err
+				}
+				manifests[normalizedKind+"/"+name] = item
+			}
+			listed[normalizedKind] = true
+		}
+		status, err := parseStatus(kind, manifests[normalizedKind+"/"+resource.Name])
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// CreateBatch creates all resources which do not exist yet with a single apply
+func (r *Resource) CreateBatch(resources []*BatchResource) ([]*BatchResult, error) {
+	statuses, err := r.GetStatuses(resources)
+	if err != nil {
+		return nil, err
+	}
+	results := []*BatchResult{}
+	for i, resource := range resources {
+		kind := strings.ToLower(resource.Kind)
+		apply, err := r.prepareCreate(resource.Name, kind, statuses[i])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &BatchResult{Name: resource.Name, Kind: kind, Exists: !apply})
+	}
+	return r.applyBatch(resources, results, func(result *BatchResult) bool {
+		return !result.Exists
+	})
+}
+
+// UpdateBatch updates all existing resources with a single apply, pods and jobs are skipped
+func (r *Resource) UpdateBatch(resources []*BatchResource) ([]*BatchResult, error) {
+	candidates := []*BatchResource{}
+	for _, resource := range resources {
+		kind := strings.ToLower(resource.Kind)
+		if kind != "pod" && kind != "job" {
+			candidates = append(candidates, resource)
+		}
+	}
+	statuses, err := r.GetStatuses(candidates)
+	if err != nil {
+		return nil, err
+	}
+	results := []*BatchResult{}
+	for _, resource := range resources {
+		kind := strings.ToLower(resource.Kind)
+		result := &BatchResult{Name: resource.Name, Kind: kind, UpdateStatus: UpdateStatusSkipped}
+		if kind != "pod" && kind != "job" {
+			result.UpdateStatus, err = r.prepareUpdate(resource.Name, kind, statuses[0])
+			if err != nil {
+				return nil, err
+			}
+			statuses = statuses[1:]
+		}
+		results = append(results, result)
+	}
+	return r.applyBatch(resources, results, func(result *BatchResult) bool {
+		return result.UpdateStatus == UpdateStatusExisted
+	})
+}
+
+// UpgradeBatch creates or updates all resources with a single apply, finished pods and jobs are recreated
+func (r *Resource) UpgradeBatch(resources []*BatchResource) ([]*BatchResult, error) {
+	statuses, err := r.GetStatuses(resources)
+	if err != nil {
+		return nil, err
+	}
+	results := []*BatchResult{}
+	for i, resource := range resources {
+		kind := strings.ToLower(resource.Kind)
+		updateStatus, err := r.prepareUpgrade(resource.Name, kind, statuses[i])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &BatchResult{Name: resource.Name, Kind: kind, UpdateStatus: updateStatus})
+	}
+	return r.applyBatch(resources, results, func(result *BatchResult) bool {
+		return result.UpdateStatus != UpdateStatusSkipped
+	})
+}
+
+// applyBatch applies the resources selected by shouldApply in one request and sets the action of their results
+func (r *Resource) applyBatch(resources []*BatchResource, results []*BatchResult, shouldApply func(*BatchResult) bool) ([]*BatchResult, error) {
+	contents := []string{}
+	pending := make(map[string]*BatchResult)
+	for i, resource := range resources {
+		if !shouldApply(results[i]) {
+			continue
+		}
+		contents = append(contents, strings.TrimSpace(resource.RawContent))
+		pending[normalizeKind(results[i].Kind)+"/"+resource.Name] = results[i]
+	}
+	if len(contents) == 0 {
+		return results, nil
+	}
+	applyResults, err := r.context.backend.Apply(r.context.ctx, r.context.namespace, []byte(strings.Join(contents, "\n---\n")))
+	if err != nil {
+		return nil, err
+	}
+	for _, applyResult := range applyResults {
+		if result, ok := pending[normalizeKind(applyResult.Kind)+"/"+applyResult.Name]; ok {
+			result.Action = applyResult.Action
+		}
+	}
+	return results, nil
+}
+
+func manifestName(manifest []byte) (string, error) {
+	info := &batchObjectInfo{}
+	err := yaml.Unmarshal(manifest, info)
+	if err != nil {
+		return "", stacktrace.Propagate(ErrInvalidResponse{err, string(manifest)}, "invalid response")
+	}
+	if info.Metadata == nil {
+		return "", nil
+	}
+	return info.Metadata.Name, nil
+}
+
+type batchObjectInfo struct {
+	Metadata *podMetadata `yaml:"metadata"`
+}
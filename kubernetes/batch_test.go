@@ -0,0 +1,153 @@
+package kubernetes
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type BatchTestSuite struct {
+	suite.Suite
+	resourceRoot string
+	namespace    string
+	backend      *countingBackend
+	kubeContext  *Context
+}
+
+func (s *BatchTestSuite) SetupTest() {
+	s.resourceRoot = "../test-resources"
+	s.namespace = "batch-ns"
+	s.backend = &countingBackend{Backend: NewFakeCluster()}
+	s.kubeContext = NewContextWithBackend(s.namespace, s.backend)
+	_, err := s.kubeContext.Namespace().Create()
+	require.Nil(s.T(), err)
+	s.backend.reset()
+}
+
+func (s *BatchTestSuite) TestCreateBatch() {
+	exists, err := s.kubeContext.Resource().Create("secret", "secret", s.readResource("static", "secret.yml"))
+	require.Nil(s.T(), err)
+	require.False(s.T(), exists)
+	s.backend.reset()
+
+	results, err := s.kubeContext.Resource().CreateBatch([]*BatchResource{
+		s.batchResource("config-map", "ConfigMap", "static", "config-map.yml"),
+		s.batchResource("secret", "Secret", "static", "secret.yml"),
+		s.batchResource("deployment", "Deployment", "pod-based", "deployment.yml"),
+	})
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []*BatchResult{
+		{Name: "config-map", Kind: "configmap", Action: "created"},
+		{Name: "secret", Kind: "secret", Exists: true},
+		{Name: "deployment", Kind: "deployment", Action: "created"},
+	}, results)
+	require.Equal(s.T(), int32(1), atomic.LoadInt32(&s.backend.applies))
+	require.Equal(s.T(), int32(3), atomic.LoadInt32(&s.backend.lists))
+	require.Equal(s.T(), int32(0), atomic.LoadInt32(&s.backend.gets))
+}
+
+func (s *BatchTestSuite) TestUpdateBatch() {
+	_, err := s.kubeContext.Resource().CreateBatch([]*BatchResource{
+		s.batchResource("config-map", "configmap", "static", "config-map.yml"),
+		s.batchResource("service", "service", "static", "service.yml"),
+		s.batchResource("success", "pod", "pod", "success.yml"),
+	})
+	require.Nil(s.T(), err)
+	s.backend.reset()
+
+	results, err := s.kubeContext.Resource().UpdateBatch([]*BatchResource{
+		s.batchResource("config-map", "configmap", "static", "config-map-updated.yml"),
+		s.batchResource("service", "service", "static", "service.yml"),
+		s.batchResource("secret", "secret", "static", "secret.yml"),
+		s.batchResource("success", "pod", "pod", "success-updated.yml"),
+	})
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []*BatchResult{
+		{Name: "config-map", Kind: "configmap", UpdateStatus: UpdateStatusExisted, Action: "configured"},
+		{Name: "service", Kind: "service", UpdateStatus: UpdateStatusExisted, Action: "unchanged"},
+		{Name: "secret", Kind: "secret", UpdateStatus: UpdateStatusNotExist},
+		{Name: "success", Kind: "pod", UpdateStatus: UpdateStatusSkipped},
+	}, results)
+	require.Equal(s.T(), int32(1), atomic.LoadInt32(&s.backend.applies))
+	exists, err := s.kubeContext.Resource().Exists("secret", "secret")
+	require.Nil(s.T(), err)
+	require.False(s.T(), exists)
+}
+
+func (s *BatchTestSuite) TestUpgradeBatch() {
+	_, err := s.kubeContext.Resource().CreateBatch([]*BatchResource{
+		s.batchResource("config-map", "configmap", "static", "config-map.yml"),
+		s.batchResource("success", "pod", "pod", "success.yml"),
+	})
+	require.Nil(s.T(), err)
+	success, err := s.kubeContext.Resource().Wait("success", "pod")
+	require.Nil(s.T(), err)
+	require.True(s.T(), success)
+
+	results, err := s.kubeContext.Resource().UpgradeBatch([]*BatchResource{
+		s.batchResource("config-map", "configmap", "static", "config-map.yml"),
+		s.batchResource("secret", "secret", "static", "secret.yml"),
+		s.batchResource("success", "pod", "pod", "success-updated.yml"),
+	})
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []*BatchResult{
+		{Name: "config-map", Kind: "configmap", UpdateStatus: UpdateStatusExisted, Action: "unchanged"},
+		{Name: "secret", Kind: "secret", UpdateStatus: UpdateStatusNotExist, Action: "created"},
+		{Name: "success", Kind: "pod", UpdateStatus: UpdateStatusExisted, Action: "created"},
+	}, results)
+}
+
+func (s *BatchTestSuite) TestEmptyBatch() {
+	results, err := s.kubeContext.Resource().CreateBatch(nil)
+	require.Nil(s.T(), err)
+	require.Empty(s.T(), results)
+	require.Equal(s.T(), int32(0), atomic.LoadInt32(&s.backend.applies))
+}
+
+func (s *BatchTestSuite) readResource(dir, filename string) string {
+	content, err := ioutil.ReadFile(filepath.Join(s.resourceRoot, "resource-test", dir, filename))
+	require.Nil(s.T(), err)
+	return string(content)
+}
+
+func (s *BatchTestSuite) batchResource(name, kind, dir, filename string) *BatchResource {
+	return &BatchResource{name, kind, s.readResource(dir, filename)}
+}
+
+// countingBackend counts the requests sent to a backend
+type countingBackend struct {
+	Backend
+	gets    int32
+	lists   int32
+	applies int32
+}
+
+func (b *countingBackend) Get(ctx context.Context, namespace, kind, name string) ([]byte, error) {
+	atomic.AddInt32(&b.gets, 1)
+	return b.Backend.Get(ctx, namespace, kind, name)
+}
+
+func (b *countingBackend) List(ctx context.Context, namespace, kind, selector string) ([][]byte, error) {
+	atomic.AddInt32(&b.lists, 1)
+	return b.Backend.List(ctx, namespace, kind, selector)
+}
+
+func (b *countingBackend) Apply(ctx context.Context, namespace string, content []byte) ([]*ApplyResult, error) {
+	atomic.AddInt32(&b.applies, 1)
+	return b.Backend.Apply(ctx, namespace, content)
+}
+
+func (b *countingBackend) reset() {
+	atomic.StoreInt32(&b.gets, 0)
+	atomic.StoreInt32(&b.lists, 0)
+	atomic.StoreInt32(&b.applies, 0)
+}
+
+func TestBatch(t *testing.T) {
+	suite.Run(t, new(BatchTestSuite))
+}
@@ -2,14 +2,7 @@ package kubernetes
 
 import (
 	"os"
-	"time"
-)
-
-const (
-	defaultTerminateInterval   = 3 * time.Second
-	defaultTerminateCheckLimit = 40
-	defaultPendingInterval     = 3 * time.Second
-	defaultPendingCheckLimit   = 40
+	"strings"
 )
 
 func buildTestContext(namespace string) (*Context, error) {
@@ -20,3 +13,42 @@ func buildTestContext(namespace string) (*Context, error) {
 	kubeConfig := os.Getenv("KUBERNETES_TEST_KUBE_CONFIG")
 	return NewContext(namespace, kubeContext, kubeConfig)
 }
+
+var kindAliases = map[string]string{
+	"po":     "pod",
+	"svc":    "service",
+	"deploy": "deployment",
+	"cm":     "configmap",
+	"ns":     "namespace",
+	"pvc":    "persistentvolumeclaim",
+	"pv":     "persistentvolume",
+	"sts":    "statefulset",
+	"ds":     "daemonset",
+	"rs":     "replicaset",
+	"sa":     "serviceaccount",
+	"ing":    "ingress",
+	"cj":     "cronjob",
+	"ep":     "endpoints",
+	"crd":    "customresourcedefinition",
+	"sc":     "storageclass",
+}
+
+// normalizeKind turns kind forms like Deployment, deployments, deploy or deployment.apps into deployment
+func normalizeKind(kind string) string {
+	kind = strings.ToLower(strings.SplitN(kind, ".", 2)[0])
+	if alias, ok := kindAliases[kind]; ok {
+		return alias
+	}
+	if kind == "endpoints" {
+		return kind
+	}
+	switch {
+	case strings.HasSuffix(kind, "sses"), strings.HasSuffix(kind, "ches"):
+		return strings.TrimSuffix(kind, "es")
+	case strings.HasSuffix(kind, "ies"):
+		return strings.TrimSuffix(kind, "ies") + "y"
+	case strings.HasSuffix(kind, "s") && !strings.HasSuffix(kind, "ss"):
+		return strings.TrimSuffix(kind, "s")
+	}
+	return kind
+}
@@ -0,0 +1,132 @@
+package kubernetes
+
+import (
+	"strings"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Details of a live resource, as shown by the project status
+type Details struct {
+	Status RsStatus
+	// Replicas tells if Ready and Desired are known: for workloads, jobs and pods
+	Replicas bool
+	Ready    int32
+	Desired  int32
+	// Restarts is the number of container restarts of the pod, or of the pods of a job or workload
+	Restarts int
+	Created  time.Time
+	Message  string
+}
+
+type detailsResourceInfo struct {
+	Metadata *struct {
+		CreationTimestamp string `yaml:"creationTimestamp"`
+	} `yaml:"metadata"`
+	Spec *struct {
+		Replicas    *int32          `yaml:"replicas"`
+		Completions *int32          `yaml:"completions"`
+		Containers  []*podContainer `yaml:"containers"`
+	} `yaml:"spec"`
+	Status *struct {
+		ReadyReplicas          int32 `yaml:"readyReplicas"`
+		NumberReady            int32 `yaml:"numberReady"`
+		DesiredNumberScheduled int32 `yaml:"desiredNumberScheduled"`
+		Succeeded              int32 `yaml:"succeeded"`
+		ContainerStatuses      []*struct {
+			Ready bool `yaml:"ready"`
+		} `yaml:"containerStatuses"`
+	} `yaml:"status"`
+}
+
+// Details returns the status, replicas, restarts and creation time of a resource
+func (r *Resource) Details(name, kind string) (*Details, error) {
+	kind = strings.ToLower(kind)
+	output, err := r.context.get(name, kind)
+	if err != nil {
+		return nil, err
+	}
+	status, err := r.parseDetailsStatus(name, kind, output)
+	if err != nil {
+		return nil, err
+	}
+	details := &Details{Status: status}
+	if output == nil {
+		return details, nil
+	}
+	info := &detailsResourceInfo{}
+	err = yaml.Unmarshal(output, info)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+	}
+	if info.Metadata != nil && info.Metadata.CreationTimestamp != "" {
+		details.Created, _ = time.Parse(time.RFC3339, info.Metadata.CreationTimestamp)
+	}
+	details.Replicas, details.Ready, details.Desired = info.replicas(normalizeKind(kind))
+	readiness, err := r.parseReadiness(name, kind, output)
+	if err == nil {
+		details.Message = readiness.Message
+	}
+	pods, err := r.context.relatedPods(name, kind, output)
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		if pod.Status == nil {
+			continue
+		}
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			details.Restarts += containerStatus.RestartCount
+		}
+	}
+	return details, nil
+}
+
+// parseDetailsStatus returns the status of a resource from its manifest, like GetStatus
+func (r *Resource) parseDetailsStatus(name, kind string, output []byte) (RsStatus, error) {
+	kind = normalizeKind(kind)
+	switch kind {
+	case "pod", "job":
+		return parseStatus(kind, output)
+	}
+	if _, ok := readinessChecks[kind]; ok && output != nil {
+		readiness, err := r.parseReadiness(name, kind, output)
+		if err != nil {
+			return RsStatusUnknown, err
+		}
+		return readiness.status(), nil
+	}
+	return parseNonPodStatus(kind, output)
+}
+
+func (info *detailsResourceInfo) replicas(kind string) (known bool, ready, desired int32) {
+	if info.Spec == nil || info.Status == nil {
+		return false, 0, 0
+	}
+	switch kind {
+	case "deployment", "statefulset", "replicaset":
+		desired = 1
+		if info.Spec.Replicas != nil {
+			desired = *info.Spec.Replicas
+		}
+		return true, info.Status.ReadyReplicas, desired
+	case "daemonset":
+		return true, info.Status.NumberReady, info.Status.DesiredNumberScheduled
+	case "job":
+		desired = 1
+		if info.Spec.Completions != nil {
+			desired = *info.Spec.Completions
+		}
+		return true, info.Status.Succeeded, desired
+	case "pod":
+		for _, containerStatus := range info.Status.ContainerStatuses {
+			if containerStatus.Ready {
+				ready++
+			}
+		}
+		return true, ready, int32(len(info.Spec.Containers))
+	}
+	return false, 0, 0
+}
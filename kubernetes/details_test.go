@@ -0,0 +1,72 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type DetailsTestSuite struct {
+	suite.Suite
+	namespace   string
+	cluster     *FakeCluster
+	kubeContext *Context
+}
+
+func (s *DetailsTestSuite) SetupTest() {
+	s.namespace = "details-ns"
+	s.cluster = NewFakeCluster()
+	s.kubeContext = NewContextWithBackend(s.namespace, s.cluster)
+	_, err := s.kubeContext.Namespace().Create()
+	require.Nil(s.T(), err)
+}
+
+func (s *DetailsTestSuite) TestDeployment() {
+	s.cluster.SetOutcome(s.namespace, "pod", "web-abcde", FakeOutcomeCrashLoopBackOff)
+	s.create("web", "deployment", `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "web"}, "spec": {"replicas": 3, "selector": {"matchLabels": {"app": "web"}}}}`)
+	s.create("web-abcde", "pod", `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web-abcde", "labels": {"app": "web"}}, "spec": {"containers": [{"name": "web", "image": "nginx"}]}}`)
+	s.create("other", "pod", `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "other", "labels": {"app": "other"}}, "spec": {"containers": [{"name": "other", "image": "nginx"}]}}`)
+	_, err := s.kubeContext.Resource().GetStatus("web-abcde", "pod")
+	require.Nil(s.T(), err)
+	details, err := s.kubeContext.Resource().Details("web", "Deployment")
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), RsStatusActive, details.Status)
+	require.True(s.T(), details.Replicas)
+	require.Equal(s.T(), int32(3), details.Desired)
+	require.Equal(s.T(), int32(3), details.Ready)
+	require.True(s.T(), details.Restarts >= 1)
+	require.WithinDuration(s.T(), time.Now(), details.Created, time.Minute)
+}
+
+func (s *DetailsTestSuite) TestPod() {
+	s.create("multi", "pod", `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "multi"}, "spec": {"containers": [{"name": "a", "image": "nginx"}, {"name": "b", "image": "nginx"}]}}`)
+	details, err := s.kubeContext.Resource().Details("multi", "pod")
+	require.Nil(s.T(), err)
+	require.True(s.T(), details.Replicas)
+	require.Equal(s.T(), int32(2), details.Desired)
+	require.Equal(s.T(), 0, details.Restarts)
+}
+
+func (s *DetailsTestSuite) TestOtherKinds() {
+	s.create("config", "configmap", `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "config"}}`)
+	details, err := s.kubeContext.Resource().Details("config", "configmap")
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), RsStatusActive, details.Status)
+	require.False(s.T(), details.Replicas)
+	details, err = s.kubeContext.Resource().Details("not-exists", "deployment")
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), RsStatusNotExist, details.Status)
+	require.True(s.T(), details.Created.IsZero())
+}
+
+func (s *DetailsTestSuite) create(name, kind, content string) {
+	exists, err := s.kubeContext.Resource().Create(name, kind, content)
+	require.Nil(s.T(), err)
+	require.False(s.T(), exists)
+}
+
+func TestDetails(t *testing.T) {
+	suite.Run(t, new(DetailsTestSuite))
+}
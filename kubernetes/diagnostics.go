@@ -0,0 +1,281 @@
+package kubernetes
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const maxDiagnosticEvents = 50
+
+// Diagnostics holds what is needed to understand why a resource failed, without access to the cluster
+type Diagnostics struct {
+	Name    string
+	Kind    string
+	Summary string
+	Events  []string
+	Logs    []*ContainerLog
+}
+
+// ContainerLog holds the last lines of a container log
+type ContainerLog struct {
+	Pod       string
+	Container string
+	Content   string
+}
+
+// Diagnose collects a summary of a resource, the events related to it and the last log lines of its pods.
+// Errors while collecting a part are reported inside the diagnostics.
+func (c *Context) Diagnose(name, kind string, tailLines int) (*Diagnostics, error) {
+	kind = strings.ToLower(kind)
+	d := &Diagnostics{Name: name, Kind: kind}
+	output, err := c.get(name, kind)
+	if err != nil {
+		return nil, err
+	}
+	if output == nil {
+		d.Summary = fmt.Sprintf("%s %q does not exist\n", kind, name)
+	} else {
+		d.Summary, err = describe(output)
+		if err != nil {
+			return nil, err
+		}
+	}
+	pods, err := c.relatedPods(name, kind, output)
+	if err != nil {
+		d.Summary += fmt.Sprintf("cannot list pods: %s\n", err)
+	}
+	involved := map[string]bool{name: true}
+	for _, pod := range pods {
+		involved[pod.Metadata.Name] = true
+	}
+	d.Events, err = c.events(involved)
+	if err != nil {
+		d.Events = []string{fmt.Sprintf("cannot list events: %s", err)}
+	}
+	for _, pod := range pods {
+		for _, container := range pod.containers() {
+			d.Logs = append(d.Logs, c.containerLog(pod.Metadata.Name, container, tailLines))
+		}
+	}
+	return d, nil
+}
+
+// Write the diagnostics in a human readable form
+func (d *Diagnostics) Write(w io.Writer) error {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "===== %s %q =====\n", d.Kind, d.Name)
+	buf.WriteString(d.Summary)
+	fmt.Fprintf(buf, "----- Events -----\n")
+	if len(d.Events) == 0 {
+		buf.WriteString("<none>\n")
+	}
+	for _, event := range d.Events {
+		buf.WriteString(event + "\n")
+	}
+	for _, log := range d.Logs {
+		fmt.Fprintf(buf, "----- Logs of pod %q container %q -----\n", log.Pod, log.Container)
+		buf.WriteString(log.Content)
+		if log.Content != "" && !strings.HasSuffix(log.Content, "\n") {
+			buf.WriteString("\n")
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	if err != nil {
+		return stacktrace.Propagate(err, "cannot write diagnostics")
+	}
+	return nil
+}
+
+// describe renders a manifest like a short kubectl describe: identity, labels and the full status
+func describe(output []byte) (string, error) {
+	info := &describeInfo{}
+	err := yaml.Unmarshal(output, info)
+	if err != nil {
+		return "", stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "Kind:       %s\n", info.Kind)
+	if info.Metadata != nil {
+		fmt.Fprintf(buf, "Name:       %s\n", info.Metadata.Name)
+		if info.Metadata.Namespace != "" {
+			fmt.Fprintf(buf, "Namespace:  %s\n", info.Metadata.Namespace)
+		}
+		fmt.Fprintf(buf, "Created:    %s\n", info.Metadata.CreationTimestamp)
+		labels := []string{}
+		for k, v := range info.Metadata.Labels {
+			labels = append(labels, k+"="+v)
+		}
+		sort.Strings(labels)
+		fmt.Fprintf(buf, "Labels:     %s\n", strings.Join(labels, ","))
+	}
+	if len(info.Status) == 0 {
+		return buf.String(), nil
+	}
+	status, err := yaml.Marshal(info.Status)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "cannot encode status")
+	}
+	buf.WriteString("Status:\n")
+	for _, line := range strings.Split(strings.TrimRight(string(status), "\n"), "\n") {
+		buf.WriteString("  " + line + "\n")
+	}
+	return buf.String(), nil
+}
+
+// relatedPods returns the pod itself, the pods of a job or the pods selected by a workload
+func (c *Context) relatedPods(name, kind string, output []byte) ([]*podResourceInfo, error) {
+	if output == nil {
+		return nil, nil
+	}
+	selector := ""
+	switch normalizeKind(kind) {
+	case "pod":
+		podInfo := &podResourceInfo{}
+		err := yaml.Unmarshal(output, podInfo)
+		if err != nil {
+			return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+		}
+		if podInfo.Metadata == nil {
+			return nil, nil
+		}
+		return []*podResourceInfo{podInfo}, nil
+	case "job":
+		selector = "job-name=" + name
+	case "deployment", "statefulset", "daemonset", "replicaset":
+		info := &selectorResourceInfo{}
+		err := yaml.Unmarshal(output, info)
+		if err != nil {
+			return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+		}
+		if info.Spec == nil || info.Spec.Selector == nil || len(info.Spec.Selector.MatchLabels) == 0 {
+			return nil, nil
+		}
+		selectors := []string{}
+		for k, v := range info.Spec.Selector.MatchLabels {
+			selectors = append(selectors, k+"="+v)
+		}
+		sort.Strings(selectors)
+		selector = strings.Join(selectors, ",")
+	default:
+		return nil, nil
+	}
+	items, err := c.backend.List(c.ctx, c.namespace, "pod", selector)
+	if err != nil {
+		return nil, err
+	}
+	pods := []*podResourceInfo{}
+	for _, item := range items {
+		podInfo := &podResourceInfo{}
+		err = yaml.Unmarshal(item, podInfo)
+		if err != nil {
+			return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(item)}, "invalid response")
+		}
+		if podInfo.Metadata != nil {
+			pods = append(pods, podInfo)
+		}
+	}
+	return pods, nil
+}
+
+// events returns the latest namespace events of the involved objects, oldest first
+func (c *Context) events(involved map[string]bool) ([]string, error) {
+	items, err := c.backend.List(c.ctx, c.namespace, "event", "")
+	if err != nil {
+		return nil, err
+	}
+	events := []*eventInfo{}
+	for _, item := range items {
+		event := &eventInfo{}
+		err = yaml.Unmarshal(item, event)
+		if err != nil {
+			return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(item)}, "invalid response")
+		}
+		if event.InvolvedObject != nil && involved[event.InvolvedObject.Name] {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].timestamp() < events[j].timestamp()
+	})
+	if len(events) > maxDiagnosticEvents {
+		events = events[len(events)-maxDiagnosticEvents:]
+	}
+	lines := []string{}
+	for _, event := range events {
+		lines = append(lines, fmt.Sprintf("%s %s %s %s/%s: %s", event.timestamp(), event.Type, event.Reason, strings.ToLower(event.InvolvedObject.Kind), event.InvolvedObject.Name, strings.TrimSpace(event.Message)))
+	}
+	return lines, nil
+}
+
+func (c *Context) containerLog(pod, container string, tailLines int) *ContainerLog {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	err := c.backend.Logs(c.ctx, c.namespace, pod, &LogOptions{Container: container, Tail: tailLines}, stdout, stderr)
+	content := stdout.String()
+	if err != nil {
+		content += fmt.Sprintf("cannot get logs: %s %s\n", err, strings.TrimSpace(stderr.String()))
+	}
+	return &ContainerLog{pod, container, content}
+}
+
+func (p *podResourceInfo) containers() []string {
+	containers := []string{}
+	if p.Spec == nil {
+		return containers
+	}
+	for _, container := range p.Spec.InitContainers {
+		containers = append(containers, container.Name)
+	}
+	for _, container := range p.Spec.Containers {
+		containers = append(containers, container.Name)
+	}
+	return containers
+}
+
+type describeInfo struct {
+	Kind     string `yaml:"kind"`
+	Metadata *struct {
+		Name              string            `yaml:"name"`
+		Namespace         string            `yaml:"namespace"`
+		CreationTimestamp string            `yaml:"creationTimestamp"`
+		Labels            map[string]string `yaml:"labels"`
+	} `yaml:"metadata"`
+	Status yaml.MapSlice `yaml:"status"`
+}
+
+type selectorResourceInfo struct {
+	Spec *struct {
+		Selector *struct {
+			MatchLabels map[string]string `yaml:"matchLabels"`
+		} `yaml:"selector"`
+	} `yaml:"spec"`
+}
+
+type eventInfo struct {
+	InvolvedObject *struct {
+		Kind string `yaml:"kind"`
+		Name string `yaml:"name"`
+	} `yaml:"involvedObject"`
+	Type           string `yaml:"type"`
+	Reason         string `yaml:"reason"`
+	Message        string `yaml:"message"`
+	FirstTimestamp string `yaml:"firstTimestamp"`
+	LastTimestamp  string `yaml:"lastTimestamp"`
+	EventTime      string `yaml:"eventTime"`
+}
+
+func (e *eventInfo) timestamp() string {
+	if e.LastTimestamp != "" {
+		return e.LastTimestamp
+	}
+	if e.EventTime != "" {
+		return e.EventTime
+	}
+	return e.FirstTimestamp
+}
@@ -0,0 +1,114 @@
+package kubernetes
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type DiagnosticsTestSuite struct {
+	suite.Suite
+	resourceRoot string
+	namespace    string
+	cluster      *FakeCluster
+	kubeContext  *Context
+}
+
+func (s *DiagnosticsTestSuite) SetupTest() {
+	s.resourceRoot = "../test-resources"
+	s.namespace = "diagnostics-ns"
+	s.cluster = NewFakeCluster()
+	s.kubeContext = NewContextWithBackend(s.namespace, s.cluster)
+	_, err := s.kubeContext.Namespace().Create()
+	require.Nil(s.T(), err)
+}
+
+func (s *DiagnosticsTestSuite) TestPod() {
+	s.cluster.SetOutcome(s.namespace, "pod", "multiple-containers", FakeOutcomeFailed)
+	s.create("multiple-containers", "pod", s.readResource(filepath.Join("pod", "multiple-containers.yml")))
+	s.createEvent("event1", "Pod", "multiple-containers", "Warning", "BackOff", "Back-off restarting failed container", "2023-01-01T00:00:02Z")
+	s.createEvent("event2", "Pod", "multiple-containers", "Normal", "Scheduled", "Successfully assigned", "2023-01-01T00:00:01Z")
+	s.createEvent("event3", "Pod", "other", "Normal", "Scheduled", "Successfully assigned", "2023-01-01T00:00:00Z")
+	s.cluster.SetLogs(s.namespace, "multiple-containers", "container1", "line1\nline2\nline3\n")
+	s.cluster.SetLogs(s.namespace, "multiple-containers", "container2", "error")
+
+	diagnostics, err := s.kubeContext.Diagnose("multiple-containers", "pod", 2)
+	require.Nil(s.T(), err)
+	require.Contains(s.T(), diagnostics.Summary, "Name:       multiple-containers")
+	require.Contains(s.T(), diagnostics.Summary, "phase: Failed")
+	require.Len(s.T(), diagnostics.Events, 2)
+	require.Contains(s.T(), diagnostics.Events[0], "Scheduled pod/multiple-containers")
+	require.Contains(s.T(), diagnostics.Events[1], "BackOff pod/multiple-containers: Back-off restarting failed container")
+	require.Equal(s.T(), []*ContainerLog{
+		{"multiple-containers", "container1", "line2\nline3\n"},
+		{"multiple-containers", "container2", "error"},
+	}, diagnostics.Logs)
+
+	buf := &bytes.Buffer{}
+	err = diagnostics.Write(buf)
+	require.Nil(s.T(), err)
+	require.Contains(s.T(), buf.String(), "===== pod \"multiple-containers\" =====\n")
+	require.Contains(s.T(), buf.String(), "----- Logs of pod \"multiple-containers\" container \"container2\" -----\nerror\n")
+}
+
+func (s *DiagnosticsTestSuite) TestJob() {
+	s.cluster.SetOutcome(s.namespace, "job", "success", FakeOutcomeFailed)
+	s.create("success", "job", s.readResource(filepath.Join("job", "success.yml")))
+	s.create("success-abcde", "pod", `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "success-abcde", "labels": {"job-name": "success"}}, "spec": {"restartPolicy": "Never", "containers": [{"name": "job", "image": "ubuntu:16.04"}]}}`)
+	s.create("other", "pod", s.readResource(filepath.Join("pod", "happy.yml")))
+	s.createEvent("event1", "Job", "success", "Warning", "BackoffLimitExceeded", "Job has reached the specified backoff limit", "2023-01-01T00:00:01Z")
+	s.cluster.SetLogs(s.namespace, "success-abcde", "job", "boom\n")
+
+	diagnostics, err := s.kubeContext.Diagnose("success", "job", 10)
+	require.Nil(s.T(), err)
+	require.Contains(s.T(), diagnostics.Summary, "Kind:       Job")
+	require.Len(s.T(), diagnostics.Events, 1)
+	require.Equal(s.T(), []*ContainerLog{{"success-abcde", "job", "boom\n"}}, diagnostics.Logs)
+}
+
+func (s *DiagnosticsTestSuite) TestDeployment() {
+	s.cluster.SetOutcome(s.namespace, "deployment", "web", FakeOutcomeStuck)
+	s.create("web", "deployment", `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "web"}, "spec": {"replicas": 1, "selector": {"matchLabels": {"app": "web"}}, "template": {"metadata": {"labels": {"app": "web"}}, "spec": {"containers": [{"name": "nginx", "image": "nginx"}]}}}}`)
+	s.create("web-abcde", "pod", `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web-abcde", "labels": {"app": "web"}}, "spec": {"containers": [{"name": "nginx", "image": "nginx"}]}}`)
+	s.create("happy", "pod", s.readResource(filepath.Join("pod", "happy.yml")))
+
+	diagnostics, err := s.kubeContext.Diagnose("web", "deployment", 10)
+	require.Nil(s.T(), err)
+	require.Len(s.T(), diagnostics.Logs, 1)
+	require.Equal(s.T(), "web-abcde", diagnostics.Logs[0].Pod)
+	require.Empty(s.T(), diagnostics.Events)
+}
+
+func (s *DiagnosticsTestSuite) TestNotExists() {
+	diagnostics, err := s.kubeContext.Diagnose("not-exists", "job", 10)
+	require.Nil(s.T(), err)
+	require.Contains(s.T(), diagnostics.Summary, "does not exist")
+	require.Empty(s.T(), diagnostics.Logs)
+}
+
+func (s *DiagnosticsTestSuite) readResource(filename string) string {
+	content, err := ioutil.ReadFile(filepath.Join(s.resourceRoot, "resource-test", filename))
+	require.Nil(s.T(), err)
+	return string(content)
+}
+
+func (s *DiagnosticsTestSuite) create(name, kind, content string) {
+	exists, err := s.kubeContext.Resource().Create(name, kind, content)
+	require.Nil(s.T(), err)
+	require.False(s.T(), exists)
+}
+
+func (s *DiagnosticsTestSuite) createEvent(name, kind, involved, eventType, reason, message, timestamp string) {
+	content := `{"apiVersion": "v1", "kind": "Event", "metadata": {"name": "` + name + `"}, ` +
+		`"involvedObject": {"kind": "` + kind + `", "name": "` + involved + `"}, ` +
+		`"type": "` + eventType + `", "reason": "` + reason + `", "message": "` + message + `", "lastTimestamp": "` + timestamp + `"}`
+	s.create(name, "event", content)
+}
+
+func TestDiagnostics(t *testing.T) {
+	suite.Run(t, new(DiagnosticsTestSuite))
+}
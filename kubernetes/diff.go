@@ -0,0 +1,226 @@
+package kubernetes
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+	yaml "gopkg.in/yaml.v2"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// AnnotationLastApplied is the annotation kubectl stores the last applied manifest in
+const AnnotationLastApplied = "kubectl.kubernetes.io/last-applied-configuration"
+
+// serverMetadataFields are set by the server and never part of a manifest
+var serverMetadataFields = []string{
+	"managedFields",
+	"resourceVersion",
+	"uid",
+	"generation",
+	"selfLink",
+	"creationTimestamp",
+	"deletionTimestamp",
+	"deletionGracePeriodSeconds",
+	"namespace",
+}
+
+// Comparison holds a rendered manifest and its live counterpart, both normalized to YAML
+type Comparison struct {
+	Name    string
+	Kind    string
+	Exists  bool
+	Desired string
+	Live    string
+}
+
+// Changed tells if the live object differs from the rendered manifest
+func (c *Comparison) Changed() bool {
+	return !c.Exists || c.Desired != c.Live
+}
+
+// Compare normalizes a rendered manifest and the live object it applies to. Fields set by the server, and fields
+// defaulted by the server which were never applied, are left out of the live object. Annotations in ignoredAnnotations
+// are left out of both, and the values of secrets are replaced with their hash.
+func (r *Resource) Compare(name, kind, rawContent string, ignoredAnnotations []string) (*Comparison, error) {
+	kind = strings.ToLower(kind)
+	comparison := &Comparison{Name: name, Kind: kind}
+	desired, err := decodeManifest([]byte(rawContent))
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode manifest of %s %q", kind, name)
+	}
+	normalizeDesired(kind, desired)
+	removeAnnotations(desired, ignoredAnnotations)
+	comparison.Desired, err = encodeComparison(kind, desired)
+	if err != nil {
+		return nil, err
+	}
+	output, err := r.context.get(name, kind)
+	if err != nil {
+		return nil, err
+	}
+	if output == nil {
+		return comparison, nil
+	}
+	comparison.Exists = true
+	live, err := decodeManifest(output)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode live %s %q", kind, name)
+	}
+	applied := lastApplied(live)
+	removeServerFields(live)
+	removeAnnotations(live, ignoredAnnotations)
+	live = pruneDefaulted(live, desired, applied).(map[string]interface{})
+	comparison.Live, err = encodeComparison(kind, live)
+	if err != nil {
+		return nil, err
+	}
+	return comparison, nil
+}
+
+func decodeManifest(content []byte) (map[string]interface{}, error) {
+	jsonContent, err := k8syaml.ToJSON(content)
+	if err != nil {
+		return nil, err
+	}
+	manifest := make(map[string]interface{})
+	err = json.Unmarshal(jsonContent, &manifest)
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// normalizeDesired turns a manifest into what the server stores
+func normalizeDesired(kind string, manifest map[string]interface{}) {
+	if metadata, ok := manifest["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "namespace")
+	}
+	if kind != "secret" {
+		return
+	}
+	stringData, ok := manifest["stringData"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	data, ok := manifest["data"].(map[string]interface{})
+	if !ok {
+		data = make(map[string]interface{})
+	}
+	for key, value := range stringData {
+		if s, ok := value.(string); ok {
+			data[key] = base64.StdEncoding.EncodeToString([]byte(s))
+		}
+	}
+	manifest["data"] = data
+	delete(manifest, "stringData")
+}
+
+func removeServerFields(manifest map[string]interface{}) {
+	delete(manifest, "status")
+	metadata, ok := manifest["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, field := range serverMetadataFields {
+		delete(metadata, field)
+	}
+	removeAnnotations(manifest, []string{AnnotationLastApplied})
+}
+
+func removeAnnotations(manifest map[string]interface{}, annotations []string) {
+	metadata, ok := manifest["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	values, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, annotation := range annotations {
+		delete(values, annotation)
+	}
+	if len(values) == 0 {
+		delete(metadata, "annotations")
+	}
+}
+
+// lastApplied returns the manifest recorded by kubectl apply on a live object, nil if there is none
+func lastApplied(manifest map[string]interface{}) map[string]interface{} {
+	metadata, _ := manifest["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	content, ok := annotations[AnnotationLastApplied].(string)
+	if !ok {
+		return nil
+	}
+	applied := make(map[string]interface{})
+	if json.Unmarshal([]byte(content), &applied) != nil {
+		return nil
+	}
+	return applied
+}
+
+// pruneDefaulted keeps the fields of a live value which are in the rendered manifest or were applied before.
+// Other fields were defaulted by the server.
+func pruneDefaulted(live, desired, applied interface{}) interface{} {
+	switch liveValue := live.(type) {
+	case map[string]interface{}:
+		desiredMap, _ := desired.(map[string]interface{})
+		appliedMap, _ := applied.(map[string]interface{})
+		pruned := make(map[string]interface{})
+		for key, value := range liveValue {
+			desiredValue, inDesired := desiredMap[key]
+			appliedValue, inApplied := appliedMap[key]
+			if !inDesired && !inApplied {
+				continue
+			}
+			pruned[key] = pruneDefaulted(value, desiredValue, appliedValue)
+		}
+		return pruned
+	case []interface{}:
+		desiredList, _ := desired.([]interface{})
+		appliedList, _ := applied.([]interface{})
+		pruned := make([]interface{}, len(liveValue))
+		for i, value := range liveValue {
+			var desiredValue, appliedValue interface{}
+			if i < len(desiredList) {
+				desiredValue = desiredList[i]
+			}
+			if i < len(appliedList) {
+				appliedValue = appliedList[i]
+			}
+			if desiredValue == nil && appliedValue == nil {
+				pruned[i] = value
+				continue
+			}
+			pruned[i] = pruneDefaulted(value, desiredValue, appliedValue)
+		}
+		return pruned
+	default:
+		return live
+	}
+}
+
+func encodeComparison(kind string, manifest map[string]interface{}) (string, error) {
+	if kind == "secret" {
+		if data, ok := manifest["data"].(map[string]interface{}); ok {
+			for key, value := range data {
+				data[key] = redact(value)
+			}
+		}
+	}
+	content, err := yaml.Marshal(manifest)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "cannot encode manifest")
+	}
+	return string(content), nil
+}
+
+func redact(value interface{}) string {
+	content, _ := json.Marshal(value)
+	hash := sha256.Sum256(content)
+	return "(redacted sha256:" + hex.EncodeToString(hash[:])[:12] + ")"
+}
@@ -0,0 +1,116 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type DiffTestSuite struct {
+	suite.Suite
+	resourceRoot string
+	kubeContext  *Context
+}
+
+func (s *DiffTestSuite) SetupTest() {
+	s.resourceRoot = "../test-resources"
+	s.kubeContext = NewContextWithBackend("diff-ns", NewFakeCluster())
+	_, err := s.kubeContext.Namespace().Create()
+	require.Nil(s.T(), err)
+}
+
+func (s *DiffTestSuite) TestCompareMissing() {
+	comparison, err := s.kubeContext.Resource().Compare("config-map", "ConfigMap", s.readResource("config-map.yml"), nil)
+	require.Nil(s.T(), err)
+	require.False(s.T(), comparison.Exists)
+	require.True(s.T(), comparison.Changed())
+	require.Empty(s.T(), comparison.Live)
+	require.Contains(s.T(), comparison.Desired, "foo: bar")
+}
+
+func (s *DiffTestSuite) TestCompareUnchanged() {
+	_, err := s.kubeContext.Resource().Create("config-map", "configmap", s.readResource("config-map.yml"))
+	require.Nil(s.T(), err)
+	comparison, err := s.kubeContext.Resource().Compare("config-map", "configmap", s.readResource("config-map.yml"), nil)
+	require.Nil(s.T(), err)
+	require.True(s.T(), comparison.Exists)
+	require.False(s.T(), comparison.Changed(), "server fields are ignored")
+}
+
+func (s *DiffTestSuite) TestCompareChanged() {
+	_, err := s.kubeContext.Resource().Create("config-map", "configmap", s.readResource("config-map.yml"))
+	require.Nil(s.T(), err)
+	comparison, err := s.kubeContext.Resource().Compare("config-map", "configmap", s.readResource("config-map-updated.yml"), nil)
+	require.Nil(s.T(), err)
+	require.True(s.T(), comparison.Changed())
+	require.Contains(s.T(), comparison.Live, "foo: bar\n")
+	require.Contains(s.T(), comparison.Desired, "foo: barbar\n")
+}
+
+func (s *DiffTestSuite) TestCompareIgnoredAnnotations() {
+	applied := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: annotated\n  annotations:\n    run: one\n"
+	_, err := s.kubeContext.Resource().Create("annotated", "configmap", applied)
+	require.Nil(s.T(), err)
+	rendered := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: annotated\n  annotations:\n    run: two\n"
+	comparison, err := s.kubeContext.Resource().Compare("annotated", "configmap", rendered, []string{"run"})
+	require.Nil(s.T(), err)
+	require.False(s.T(), comparison.Changed())
+}
+
+func (s *DiffTestSuite) TestCompareSecret() {
+	_, err := s.kubeContext.Resource().Create("secret", "secret", s.readResource("secret.yml"))
+	require.Nil(s.T(), err)
+	rendered := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: secret\nstringData:\n  value: |\n    test\n"
+	comparison, err := s.kubeContext.Resource().Compare("secret", "secret", rendered, nil)
+	require.Nil(s.T(), err)
+	require.False(s.T(), comparison.Changed(), "string data is compared encoded")
+	require.NotContains(s.T(), comparison.Live, "dGVzdAo=", "secret values are redacted")
+}
+
+func (s *DiffTestSuite) TestPruneDefaulted() {
+	live := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas":         float64(1),
+			"progressDeadline": float64(600),
+			"removed":          "value",
+			"ports": []interface{}{
+				map[string]interface{}{"port": float64(80), "protocol": "TCP"},
+			},
+		},
+	}
+	desired := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": float64(2),
+			"ports": []interface{}{
+				map[string]interface{}{"port": float64(80)},
+			},
+		},
+	}
+	applied := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"removed": "value",
+		},
+	}
+	require.Equal(s.T(), map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": float64(1),
+			"removed":  "value",
+			"ports": []interface{}{
+				map[string]interface{}{"port": float64(80)},
+			},
+		},
+	}, pruneDefaulted(live, desired, applied))
+}
+
+func (s *DiffTestSuite) readResource(filename string) string {
+	content, err := ioutil.ReadFile(filepath.Join(s.resourceRoot, "resource-test", "static", filename))
+	require.Nil(s.T(), err)
+	return string(content)
+}
+
+func TestDiff(t *testing.T) {
+	suite.Run(t, new(DiffTestSuite))
+}
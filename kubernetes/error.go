@@ -2,6 +2,10 @@ package kubernetes
 
 import (
 	"fmt"
+	"net"
+
+	"github.com/palantir/stacktrace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 // ErrMissingCommand .
@@ -13,10 +17,31 @@ func (err ErrMissingCommand) Error() string {
 	return fmt.Sprintf("missing %q", err.Command)
 }
 
+// CommandFailure is what a failed kubectl command ran into, read from its output when it exits
+type CommandFailure string
+
+// CommandFailure values
+const (
+	CommandFailureUnknown         CommandFailure = ""
+	CommandFailureUnreachable     CommandFailure = "unreachable"
+	CommandFailureNotFound        CommandFailure = "not-found"
+	CommandFailureAlreadyExists   CommandFailure = "already-exists"
+	CommandFailureConflict        CommandFailure = "conflict"
+	CommandFailureForbidden       CommandFailure = "forbidden"
+	CommandFailureUnsupportedKind CommandFailure = "unsupported-kind"
+	CommandFailureRejected        CommandFailure = "rejected"
+)
+
+// Rejected reports whether the cluster or kubectl refused the request
+func (failure CommandFailure) Rejected() bool {
+	return failure != CommandFailureUnknown && failure != CommandFailureUnreachable
+}
+
 // ErrCommandExecute .
 type ErrCommandExecute struct {
 	ExitCode int
 	Output   string
+	Failure  CommandFailure
 }
 
 func (err ErrCommandExecute) Error() string {
@@ -26,6 +51,7 @@ func (err ErrCommandExecute) Error() string {
 // ErrCommandExitCode .
 type ErrCommandExitCode struct {
 	ExitCode int
+	Failure  CommandFailure
 }
 
 func (err ErrCommandExitCode) Error() string {
@@ -79,3 +105,165 @@ type ErrNotExist struct {
 func (err ErrNotExist) Error() string {
 	return fmt.Sprintf("not exist: %s %q", err.Kind, err.Name)
 }
+
+// ErrAlreadyExists .
+type ErrAlreadyExists struct {
+	Name string
+	Kind string
+}
+
+func (err ErrAlreadyExists) Error() string {
+	return fmt.Sprintf("already exists: %s %q", err.Kind, err.Name)
+}
+
+// ErrConflict .
+type ErrConflict struct {
+	Name string
+	Kind string
+}
+
+func (err ErrConflict) Error() string {
+	return fmt.Sprintf("%s %q was modified since it was read", err.Kind, err.Name)
+}
+
+// ErrApplyConflict .
+type ErrApplyConflict struct {
+	Name    string
+	Kind    string
+	Message string
+}
+
+func (err ErrApplyConflict) Error() string {
+	return fmt.Sprintf("cannot apply %s %q, fields are owned by another manager (use --force-conflicts to take them over): %s", err.Kind, err.Name, err.Message)
+}
+
+// ErrUnknownBackend .
+type ErrUnknownBackend struct {
+	Name string
+}
+
+func (err ErrUnknownBackend) Error() string {
+	return fmt.Sprintf("unknown backend: %s", err.Name)
+}
+
+// ErrInvalidWaitCondition .
+type ErrInvalidWaitCondition struct {
+	Condition string
+}
+
+func (err ErrInvalidWaitCondition) Error() string {
+	return fmt.Sprintf("invalid wait condition %q, expected condition=<type>[=<status>] or jsonpath={<path>}[=<value>]", err.Condition)
+}
+
+// ErrPodFailure .
+type ErrPodFailure struct {
+	Pod          string
+	Container    string
+	Reason       string
+	Message      string
+	RestartCount int
+}
+
+func (err ErrPodFailure) Error() string {
+	msg := fmt.Sprintf("pod %q cannot run: %s", err.Pod, err.Reason)
+	if err.Container != "" {
+		msg = fmt.Sprintf("pod %q cannot run: container %q is %s, restarted %d times", err.Pod, err.Container, err.Reason, err.RestartCount)
+	}
+	if err.Message != "" {
+		msg += ": " + err.Message
+	}
+	return msg
+}
+
+// IsNotExist reports whether an error is caused by a missing object
+func IsNotExist(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := stacktrace.RootCause(err).(ErrNotExist)
+	return ok
+}
+
+// IsAlreadyExists reports whether an error is caused by creating an object which exists
+func IsAlreadyExists(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := stacktrace.RootCause(err).(ErrAlreadyExists)
+	return ok
+}
+
+// IsConflict reports whether an error is caused by replacing an object modified since it was read
+func IsConflict(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := stacktrace.RootCause(err).(ErrConflict)
+	return ok
+}
+
+// IsUnsupportedKind reports whether an error is caused by a kind the cluster does not serve
+func IsUnsupportedKind(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch cause := stacktrace.RootCause(err).(type) {
+	case ErrUnsupportedKind:
+		return true
+	case ErrCommandExecute:
+		return cause.Failure == CommandFailureUnsupportedKind
+	default:
+		return false
+	}
+}
+
+// IsForbidden reports whether an error is caused by a request the user is not allowed to make
+func IsForbidden(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch cause := stacktrace.RootCause(err).(type) {
+	case ErrCommandExecute:
+		return cause.Failure == CommandFailureForbidden
+	default:
+		return apierrors.IsForbidden(cause)
+	}
+}
+
+// IsUnreachable reports whether an error is caused by a cluster which cannot be reached, or a missing kubectl
+func IsUnreachable(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch cause := stacktrace.RootCause(err).(type) {
+	case ErrMissingCommand:
+		return true
+	case ErrCommandExecute:
+		return cause.Failure == CommandFailureUnreachable
+	case ErrCommandExitCode:
+		return cause.Failure == CommandFailureUnreachable
+	case net.Error:
+		return true
+	default:
+		return apierrors.IsUnauthorized(cause) || apierrors.IsServiceUnavailable(cause) || apierrors.IsServerTimeout(cause) || apierrors.IsTimeout(cause) || apierrors.IsTooManyRequests(cause)
+	}
+}
+
+// IsRejected reports whether an error is a request refused by the cluster, like an invalid or forbidden manifest
+func IsRejected(err error) bool {
+	if err == nil || IsUnreachable(err) || IsNotExist(err) {
+		return false
+	}
+	switch cause := stacktrace.RootCause(err).(type) {
+	case ErrCommandExecute:
+		return cause.Failure.Rejected()
+	case ErrCommandExitCode:
+		return cause.Failure.Rejected()
+	case ErrUnsupportedKind, ErrApplyConflict, ErrAlreadyExists, ErrConflict:
+		return true
+	case apierrors.APIStatus:
+		return cause.Status().Code != 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,816 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	gocontext "context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// BackendFake is the name to register a FakeCluster with
+const BackendFake = "fake"
+
+// FakeOutcome decides how a simulated workload ends
+type FakeOutcome int
+
+// FakeOutcome values
+const (
+	// FakeOutcomeSucceeded: pods and jobs complete, workloads become available
+	FakeOutcomeSucceeded FakeOutcome = iota
+	// FakeOutcomeFailed: pods and jobs fail, deployments exceed their progress deadline
+	FakeOutcomeFailed
+	// FakeOutcomeStuck: objects never reach their final phase
+	FakeOutcomeStuck
+	// FakeOutcomeImagePullBackOff: pods stay pending, their containers cannot pull images
+	FakeOutcomeImagePullBackOff
+	// FakeOutcomeCrashLoopBackOff: pods run, their containers restart on every read
+	FakeOutcomeCrashLoopBackOff
+	// FakeOutcomeUnschedulable: pods stay pending, no node can run them
+	FakeOutcomeUnschedulable
+)
+
+// FakeCluster is an in-memory Backend for hermetic tests.
+// It stores applied objects, simulates pod, job and workload phase transitions and terminating deletion.
+type FakeCluster struct {
+	// TransitionReads is the number of reads an object stays in a phase before moving to the next one.
+	// With 0, objects reach their final phase on the first read.
+	TransitionReads int
+
+	mu              sync.Mutex
+	objects         map[string]*fakeObject
+	outcomes        map[string]FakeOutcome
+	logs            map[string]string
+	watchers        map[*fakeWatcher]struct{}
+	resourceVersion int
+}
+
+// NewFakeCluster .
+func NewFakeCluster() *FakeCluster {
+	f := &FakeCluster{
+		objects:  make(map[string]*fakeObject),
+		outcomes: make(map[string]FakeOutcome),
+		logs:     make(map[string]string),
+		watchers: make(map[*fakeWatcher]struct{}),
+	}
+	_, _ = f.Apply(gocontext.Background(), "", []byte("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: default\n"))
+	return f
+}
+
+// Factory returns the cluster itself for any context, to be used with RegisterBackend
+func (f *FakeCluster) Factory(context, kubeConfig string) (Backend, error) {
+	return f, nil
+}
+
+// SetOutcome decides how a pod, job or workload will end
+func (f *FakeCluster) SetOutcome(namespace, kind, name string, outcome FakeOutcome) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kind = normalizeKind(kind)
+	f.outcomes[fakeObjectKey(fakeNamespace(namespace, kind), kind, name)] = outcome
+}
+
+// SetLogs sets the log content of a container
+func (f *FakeCluster) SetLogs(namespace, pod, container, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logs[fakeObjectKey(fakeNamespace(namespace, "pod"), pod, container)] = content
+}
+
+// Get .
+func (f *FakeCluster) Get(ctx gocontext.Context, namespace, kind, name string) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fakeCancelled(ctx)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kind = normalizeKind(kind)
+	o, ok := f.objects[fakeObjectKey(fakeNamespace(namespace, kind), kind, name)]
+	if !ok {
+		return nil, stacktrace.Propagate(ErrNotExist{name, kind}, "not exist")
+	}
+	f.progress(o)
+	if o.removed {
+		return nil, stacktrace.Propagate(ErrNotExist{name, kind}, "not exist")
+	}
+	return o.obj.MarshalJSON()
+}
+
+// List .
+func (f *FakeCluster) List(ctx gocontext.Context, namespace, kind, selector string) ([][]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fakeCancelled(ctx)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kind = normalizeKind(kind)
+	namespace = fakeNamespace(namespace, kind)
+	labelSelector, err := labels.Parse(selector)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "invalid selector %q", selector)
+	}
+	keys := []string{}
+	for key, o := range f.objects {
+		if o.kind == kind && o.namespace == namespace {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	items := [][]byte{}
+	for _, key := range keys {
+		o := f.objects[key]
+		if !labelSelector.Matches(labels.Set(o.obj.GetLabels())) {
+			continue
+		}
+		f.progress(o)
+		if o.removed {
+			continue
+		}
+		item, err := o.obj.MarshalJSON()
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot encode %s", kind)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// Apply .
+func (f *FakeCluster) Apply(ctx gocontext.Context, namespace string, content []byte) ([]*ApplyResult, error) {
+	if ctx.Err() != nil {
+		return nil, fakeCancelled(ctx)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	results := []*ApplyResult{}
+	reader := k8syaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(content)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot read manifest")
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		jsonDoc, err := k8syaml.ToJSON(doc)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot decode manifest")
+		}
+		obj := &unstructured.Unstructured{}
+		err = obj.UnmarshalJSON(jsonDoc)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot decode manifest")
+		}
+		result, err := f.applyObject(namespace, obj)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Create .
+func (f *FakeCluster) Create(ctx gocontext.Context, namespace string, content []byte) ([]byte, error) {
+	return f.write(ctx, namespace, content, false)
+}
+
+// Replace .
+func (f *FakeCluster) Replace(ctx gocontext.Context, namespace string, content []byte) ([]byte, error) {
+	return f.write(ctx, namespace, content, true)
+}
+
+// write creates or replaces the object of a manifest, checking its existence and resource version like the api server
+func (f *FakeCluster) write(ctx gocontext.Context, namespace string, content []byte, replace bool) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fakeCancelled(ctx)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	jsonDoc, err := k8syaml.ToJSON(content)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode manifest")
+	}
+	obj := &unstructured.Unstructured{}
+	err = obj.UnmarshalJSON(jsonDoc)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode manifest")
+	}
+	kind := normalizeKind(obj.GetKind())
+	objNamespace := obj.GetNamespace()
+	if objNamespace == "" {
+		objNamespace = namespace
+	}
+	key := fakeObjectKey(fakeNamespace(objNamespace, kind), kind, obj.GetName())
+	o, exists := f.objects[key]
+	switch {
+	case !replace && exists:
+		return nil, stacktrace.Propagate(ErrAlreadyExists{obj.GetName(), kind}, "already exists")
+	case replace && !exists:
+		return nil, stacktrace.Propagate(ErrNotExist{obj.GetName(), kind}, "not exist")
+	case replace && obj.GetResourceVersion() != "" && obj.GetResourceVersion() != o.obj.GetResourceVersion():
+		return nil, stacktrace.Propagate(ErrConflict{obj.GetName(), kind}, "conflict")
+	}
+	obj.SetResourceVersion("")
+	_, err = f.applyObject(namespace, obj)
+	if err != nil {
+		return nil, err
+	}
+	return f.objects[key].obj.MarshalJSON()
+}
+
+// Delete .
+func (f *FakeCluster) Delete(ctx gocontext.Context, namespace, kind, name string) error {
+	if ctx.Err() != nil {
+		return fakeCancelled(ctx)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kind = normalizeKind(kind)
+	o, ok := f.objects[fakeObjectKey(fakeNamespace(namespace, kind), kind, name)]
+	if !ok {
+		return stacktrace.Propagate(ErrNotExist{name, kind}, "not exist")
+	}
+	if o.terminating {
+		return nil
+	}
+	if f.TransitionReads <= 0 || (kind != "pod" && kind != "namespace") {
+		f.remove(o)
+		return nil
+	}
+	o.terminating = true
+	o.reads = 0
+	o.obj.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+	f.render(o)
+	f.touch(o, WatchEventModified)
+	return nil
+}
+
+// Watch .
+func (f *FakeCluster) Watch(ctx gocontext.Context, namespace, kind, name string) (Watcher, error) {
+	if ctx.Err() != nil {
+		return nil, fakeCancelled(ctx)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kind = normalizeKind(kind)
+	key := fakeObjectKey(fakeNamespace(namespace, kind), kind, name)
+	w := &fakeWatcher{
+		key:     key,
+		signal:  make(chan struct{}, 1),
+		events:  make(chan *WatchEvent),
+		done:    make(chan struct{}),
+		cluster: f,
+	}
+	f.watchers[w] = struct{}{}
+	if o, ok := f.objects[key]; ok {
+		f.progress(o)
+		if !o.removed {
+			content, _ := o.obj.MarshalJSON()
+			w.push(&WatchEvent{WatchEventAdded, content})
+		}
+	}
+	go w.pump()
+	go func() {
+		select {
+		case <-ctx.Done():
+			w.Stop()
+		case <-w.done:
+		}
+	}()
+	return w, nil
+}
+
+// Logs .
+func (f *FakeCluster) Logs(ctx gocontext.Context, namespace, name string, opts *LogOptions, stdout, stderr io.Writer) error {
+	if ctx.Err() != nil {
+		return fakeCancelled(ctx)
+	}
+	f.mu.Lock()
+	o, ok := f.objects[fakeObjectKey(fakeNamespace(namespace, "pod"), "pod", name)]
+	if !ok {
+		f.mu.Unlock()
+		return stacktrace.Propagate(ErrNotExist{name, "pod"}, "not exist")
+	}
+	container := opts.Container
+	if container == "" {
+		containers, _, _ := unstructured.NestedSlice(o.obj.Object, "spec", "containers")
+		if len(containers) > 0 {
+			if c, ok := containers[0].(map[string]interface{}); ok {
+				container, _ = c["name"].(string)
+			}
+		}
+	}
+	content := f.logs[fakeObjectKey(fakeNamespace(namespace, "pod"), name, container)]
+	f.mu.Unlock()
+	if opts.Tail > 0 {
+		lines := strings.SplitAfter(content, "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		if len(lines) > opts.Tail {
+			lines = lines[len(lines)-opts.Tail:]
+		}
+		content = strings.Join(lines, "")
+	}
+	_, err := io.WriteString(stdout, content)
+	return err
+}
+
+func (f *FakeCluster) applyObject(namespace string, obj *unstructured.Unstructured) (*ApplyResult, error) {
+	kind := normalizeKind(obj.GetKind())
+	if kind == "" || obj.GetName() == "" {
+		return nil, stacktrace.NewError("manifest must have a kind and a name")
+	}
+	if obj.GetNamespace() != "" {
+		namespace = obj.GetNamespace()
+	}
+	namespace = fakeNamespace(namespace, kind)
+	if namespace != "" {
+		ns, ok := f.objects[fakeObjectKey("", "namespace", namespace)]
+		if !ok || ns.removed {
+			return nil, stacktrace.Propagate(ErrNotExist{namespace, "namespace"}, "not exist")
+		}
+		obj.SetNamespace(namespace)
+	}
+	result := &ApplyResult{Kind: kind, Name: obj.GetName()}
+	key := fakeObjectKey(namespace, kind, obj.GetName())
+	applied := obj.DeepCopy()
+	o, ok := f.objects[key]
+	if !ok {
+		f.resourceVersion++
+		obj.SetResourceVersion(strconv.Itoa(f.resourceVersion))
+		obj.SetGeneration(1)
+		obj.SetCreationTimestamp(metav1.Time{Time: time.Now()})
+		o = &fakeObject{
+			namespace: namespace,
+			kind:      kind,
+			applied:   applied,
+			obj:       obj,
+		}
+		f.objects[key] = o
+		f.render(o)
+		f.touch(o, WatchEventAdded)
+		result.Action = "created"
+		return result, nil
+	}
+	if reflect.DeepEqual(o.applied.Object, applied.Object) {
+		result.Action = "unchanged"
+		return result, nil
+	}
+	generation := o.obj.GetGeneration()
+	if !reflect.DeepEqual(o.applied.Object["spec"], applied.Object["spec"]) {
+		generation++
+		// a new spec starts a new rollout
+		o.stage = 0
+		o.reads = 0
+	}
+	obj.SetResourceVersion(o.obj.GetResourceVersion())
+	obj.SetGeneration(generation)
+	obj.SetCreationTimestamp(o.obj.GetCreationTimestamp())
+	obj.SetDeletionTimestamp(o.obj.GetDeletionTimestamp())
+	o.applied = applied
+	o.obj = obj
+	f.render(o)
+	f.touch(o, WatchEventModified)
+	result.Action = "configured"
+	return result, nil
+}
+
+// progress moves an object through its lifecycle, one phase per TransitionReads reads
+func (f *FakeCluster) progress(o *fakeObject) {
+	if o.removed {
+		return
+	}
+	defer f.crash(o)
+	if f.TransitionReads <= 0 {
+		changed := false
+		for f.advance(o) {
+			changed = true
+		}
+		if changed {
+			f.commit(o)
+		}
+		return
+	}
+	o.reads++
+	if o.reads <= f.TransitionReads {
+		return
+	}
+	// the read moving the object counts as the first read of the new phase
+	o.reads = 1
+	if f.advance(o) {
+		f.commit(o)
+	}
+}
+
+// crash restarts the containers of a running crash looping pod
+func (f *FakeCluster) crash(o *fakeObject) {
+	if o.removed || o.kind != "pod" || o.stage != 1 || f.outcome(o) != FakeOutcomeCrashLoopBackOff {
+		return
+	}
+	o.restarts++
+	f.commit(o)
+}
+
+func (f *FakeCluster) commit(o *fakeObject) {
+	if o.removed {
+		f.remove(o)
+		return
+	}
+	f.render(o)
+	f.touch(o, WatchEventModified)
+}
+
+// advance moves an object to its next phase, returns false if the object is in its final phase
+func (f *FakeCluster) advance(o *fakeObject) bool {
+	if o.removed {
+		return false
+	}
+	if o.terminating {
+		o.removed = true
+		return true
+	}
+	if o.stage >= f.lastStage(o) {
+		return false
+	}
+	o.stage++
+	return true
+}
+
+// lastStage is the last phase an object can reach with its outcome
+func (f *FakeCluster) lastStage(o *fakeObject) int {
+	final := len(fakeLifecycles[o.kind]) - 1
+	if final <= 0 {
+		return 0
+	}
+	outcome := f.outcome(o)
+	switch {
+	case o.kind == "pod" && (outcome == FakeOutcomeImagePullBackOff || outcome == FakeOutcomeUnschedulable):
+		return 0
+	case o.kind == "pod" && (outcome == FakeOutcomeStuck || outcome == FakeOutcomeCrashLoopBackOff || !fakePodTerminates(o)):
+		return final - 1
+	case o.kind == "pod":
+		return final
+	case o.kind == "job":
+		if outcome == FakeOutcomeStuck {
+			return final - 1
+		}
+		return final
+	case outcome == FakeOutcomeSucceeded:
+		return final
+	default:
+		return final - 1
+	}
+}
+
+func (f *FakeCluster) outcome(o *fakeObject) FakeOutcome {
+	return f.outcomes[fakeObjectKey(o.namespace, o.kind, o.obj.GetName())]
+}
+
+func (f *FakeCluster) remove(o *fakeObject) {
+	o.removed = true
+	key := fakeObjectKey(o.namespace, o.kind, o.obj.GetName())
+	delete(f.objects, key)
+	content, _ := o.obj.MarshalJSON()
+	f.notify(key, &WatchEvent{WatchEventDeleted, content})
+	if o.kind == "namespace" {
+		for _, child := range f.objects {
+			if child.namespace == o.obj.GetName() {
+				f.remove(child)
+			}
+		}
+	}
+}
+
+func (f *FakeCluster) touch(o *fakeObject, eventType string) {
+	f.resourceVersion++
+	o.obj.SetResourceVersion(strconv.Itoa(f.resourceVersion))
+	content, _ := o.obj.MarshalJSON()
+	f.notify(fakeObjectKey(o.namespace, o.kind, o.obj.GetName()), &WatchEvent{eventType, content})
+}
+
+func (f *FakeCluster) notify(key string, event *WatchEvent) {
+	for w := range f.watchers {
+		if w.key == key {
+			w.push(event)
+		}
+	}
+}
+
+// render writes the status of the current phase into the object
+func (f *FakeCluster) render(o *fakeObject) {
+	lifecycle, ok := fakeLifecycles[o.kind]
+	if !ok {
+		// static resources like configmaps have no status
+		return
+	}
+	phase := lifecycle[o.stage]
+	if phase == fakePhaseFinal {
+		if f.outcome(o) == FakeOutcomeFailed {
+			phase = "Failed"
+		} else {
+			phase = "Succeeded"
+		}
+	}
+	status := map[string]interface{}{}
+	generation := o.obj.GetGeneration()
+	replicas, ok, _ := unstructured.NestedInt64(o.obj.Object, "spec", "replicas")
+	if !ok {
+		replicas = 1
+	}
+	ready := int64(0)
+	if phase == "Available" {
+		ready = replicas
+	}
+	switch o.kind {
+	case "pod":
+		status["phase"] = phase
+		f.renderPodStatus(o, status)
+	case "customresourcedefinition":
+		established := "False"
+		if phase == "Established" {
+			established = "True"
+		}
+		status["conditions"] = []interface{}{
+			fakeCondition("NamesAccepted", "True", "NoConflicts"),
+			fakeCondition("Established", established, ""),
+		}
+	case "namespace":
+		status["phase"] = phase
+		if o.terminating {
+			status["phase"] = "Terminating"
+		}
+	case "persistentvolumeclaim":
+		status["phase"] = phase
+	case "job":
+		switch phase {
+		case "Active":
+			status["active"] = int64(1)
+		case "Succeeded":
+			status["succeeded"] = int64(1)
+			status["conditions"] = []interface{}{fakeCondition("Complete", "True", "")}
+		case "Failed":
+			status["failed"] = int64(1)
+			status["conditions"] = []interface{}{fakeCondition("Failed", "True", "BackoffLimitExceeded")}
+		}
+	case "deployment":
+		status["observedGeneration"] = generation
+		status["replicas"] = replicas
+		status["updatedReplicas"] = ready
+		status["readyReplicas"] = ready
+		status["availableReplicas"] = ready
+		if phase == "Available" {
+			status["conditions"] = []interface{}{
+				fakeCondition("Available", "True", "MinimumReplicasAvailable"),
+				fakeCondition("Progressing", "True", "NewReplicaSetAvailable"),
+			}
+		} else if f.outcome(o) == FakeOutcomeFailed {
+			status["conditions"] = []interface{}{
+				fakeCondition("Progressing", "False", "ProgressDeadlineExceeded"),
+			}
+		}
+	case "statefulset":
+		status["observedGeneration"] = generation
+		status["replicas"] = replicas
+		status["readyReplicas"] = ready
+		status["currentReplicas"] = ready
+		status["updatedReplicas"] = ready
+		status["availableReplicas"] = ready
+		status["currentRevision"] = fmt.Sprintf("%s-%d", o.obj.GetName(), generation)
+		status["updateRevision"] = fmt.Sprintf("%s-%d", o.obj.GetName(), generation)
+	case "daemonset":
+		status["observedGeneration"] = generation
+		scheduled := int64(0)
+		if phase == "Available" {
+			scheduled = 1
+		}
+		status["desiredNumberScheduled"] = int64(1)
+		status["currentNumberScheduled"] = int64(1)
+		status["updatedNumberScheduled"] = scheduled
+		status["numberReady"] = scheduled
+		status["numberAvailable"] = scheduled
+	case "service":
+		if _, ok, _ := unstructured.NestedString(o.obj.Object, "spec", "clusterIP"); !ok {
+			_ = unstructured.SetNestedField(o.obj.Object, "10.96.0.10", "spec", "clusterIP")
+		}
+		loadBalancer := map[string]interface{}{}
+		serviceType, _, _ := unstructured.NestedString(o.obj.Object, "spec", "type")
+		if serviceType == "LoadBalancer" && phase == "Available" {
+			loadBalancer["ingress"] = []interface{}{map[string]interface{}{"ip": "127.0.0.1"}}
+		}
+		status["loadBalancer"] = loadBalancer
+	case "replicaset":
+		status["observedGeneration"] = generation
+		status["replicas"] = replicas
+		status["readyReplicas"] = ready
+		status["availableReplicas"] = ready
+	default:
+		return
+	}
+	o.obj.Object["status"] = status
+}
+
+func (f *FakeCluster) renderPodStatus(o *fakeObject, status map[string]interface{}) {
+	outcome := f.outcome(o)
+	podReady := "False"
+	if status["phase"] == "Running" && outcome != FakeOutcomeCrashLoopBackOff {
+		podReady = "True"
+	}
+	conditions := []interface{}{}
+	if outcome == FakeOutcomeUnschedulable {
+		condition := fakeCondition("PodScheduled", "False", "Unschedulable")
+		condition["message"] = "0/1 nodes are available: 1 Insufficient cpu."
+		condition["lastTransitionTime"] = o.obj.GetCreationTimestamp().UTC().Format(time.RFC3339)
+		conditions = append(conditions, condition)
+	} else {
+		conditions = append(conditions, fakeCondition("PodScheduled", "True", ""))
+	}
+	conditions = append(conditions, fakeCondition("Ready", podReady, ""))
+	status["conditions"] = conditions
+	if outcome == FakeOutcomeUnschedulable {
+		return
+	}
+	containers, _, _ := unstructured.NestedSlice(o.obj.Object, "spec", "containers")
+	containerStatuses := []interface{}{}
+	for _, container := range containers {
+		name, _, _ := unstructured.NestedString(container.(map[string]interface{}), "name")
+		image, _, _ := unstructured.NestedString(container.(map[string]interface{}), "image")
+		var state map[string]interface{}
+		switch {
+		case outcome == FakeOutcomeImagePullBackOff:
+			state = map[string]interface{}{"waiting": map[string]interface{}{
+				"reason":  "ImagePullBackOff",
+				"message": fmt.Sprintf("Back-off pulling image %q", image),
+			}}
+		case outcome == FakeOutcomeCrashLoopBackOff && o.restarts > 0:
+			state = map[string]interface{}{"waiting": map[string]interface{}{
+				"reason":  "CrashLoopBackOff",
+				"message": fmt.Sprintf("back-off restarting failed container %s", name),
+			}}
+		case status["phase"] == "Pending":
+			state = map[string]interface{}{"waiting": map[string]interface{}{"reason": "ContainerCreating"}}
+		case status["phase"] == "Running":
+			state = map[string]interface{}{"running": map[string]interface{}{}}
+		default:
+			exitCode := int64(0)
+			if status["phase"] == "Failed" {
+				exitCode = 1
+			}
+			state = map[string]interface{}{"terminated": map[string]interface{}{"exitCode": exitCode}}
+		}
+		containerStatuses = append(containerStatuses, map[string]interface{}{
+			"name":         name,
+			"image":        image,
+			"ready":        podReady == "True",
+			"restartCount": int64(o.restarts),
+			"state":        state,
+		})
+	}
+	status["containerStatuses"] = containerStatuses
+}
+
+type fakeObject struct {
+	namespace   string
+	kind        string
+	applied     *unstructured.Unstructured
+	obj         *unstructured.Unstructured
+	stage       int
+	reads       int
+	restarts    int
+	terminating bool
+	removed     bool
+}
+
+// fakePhaseFinal is resolved to Succeeded or Failed by the object outcome
+const fakePhaseFinal = "<final>"
+
+var fakeLifecycles = map[string][]string{
+	"pod":                      {"Pending", "Running", fakePhaseFinal},
+	"job":                      {"Active", fakePhaseFinal},
+	"deployment":               {"Progressing", "Available"},
+	"statefulset":              {"Progressing", "Available"},
+	"daemonset":                {"Progressing", "Available"},
+	"replicaset":               {"Progressing", "Available"},
+	"persistentvolumeclaim":    {"Pending", "Bound"},
+	"customresourcedefinition": {"Pending", "Established"},
+	"service":                  {"Pending", "Available"},
+	"namespace":                {"Active"},
+}
+
+var fakeClusterScopedKinds = map[string]bool{
+	"namespace":                true,
+	"node":                     true,
+	"persistentvolume":         true,
+	"clusterrole":              true,
+	"clusterrolebinding":       true,
+	"customresourcedefinition": true,
+	"storageclass":             true,
+}
+
+func fakeNamespace(namespace, kind string) string {
+	if fakeClusterScopedKinds[kind] {
+		return ""
+	}
+	if namespace == "" {
+		return "default"
+	}
+	return namespace
+}
+
+func fakeCancelled(ctx gocontext.Context) error {
+	return stacktrace.Propagate(ctx.Err(), "cancelled")
+}
+
+func fakeObjectKey(namespace, kind, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+func fakePodTerminates(o *fakeObject) bool {
+	restartPolicy, _, _ := unstructured.NestedString(o.obj.Object, "spec", "restartPolicy")
+	return restartPolicy == "Never" || restartPolicy == "OnFailure"
+}
+
+func fakeCondition(conditionType, status, reason string) map[string]interface{} {
+	condition := map[string]interface{}{
+		"type":   conditionType,
+		"status": status,
+	}
+	if reason != "" {
+		condition["reason"] = reason
+	}
+	return condition
+}
+
+type fakeWatcher struct {
+	key     string
+	mu      sync.Mutex
+	queue   []*WatchEvent
+	signal  chan struct{}
+	events  chan *WatchEvent
+	done    chan struct{}
+	cluster *FakeCluster
+}
+
+func (w *fakeWatcher) Events() <-chan *WatchEvent {
+	return w.events
+}
+
+func (w *fakeWatcher) Stop() {
+	w.cluster.mu.Lock()
+	defer w.cluster.mu.Unlock()
+	if _, ok := w.cluster.watchers[w]; !ok {
+		return
+	}
+	delete(w.cluster.watchers, w)
+	close(w.done)
+}
+
+func (w *fakeWatcher) push(event *WatchEvent) {
+	w.mu.Lock()
+	w.queue = append(w.queue, event)
+	w.mu.Unlock()
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (w *fakeWatcher) pump() {
+	defer close(w.events)
+	for {
+		w.mu.Lock()
+		if len(w.queue) == 0 {
+			w.mu.Unlock()
+			select {
+			case <-w.signal:
+				continue
+			case <-w.done:
+				return
+			}
+		}
+		event := w.queue[0]
+		w.queue = w.queue[1:]
+		w.mu.Unlock()
+		select {
+		case w.events <- event:
+		case <-w.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type FakeClusterTestSuite struct {
+	suite.Suite
+	resourceRoot string
+	namespace    string
+	cluster      *FakeCluster
+	kubeContext  *Context
+}
+
+func (s *FakeClusterTestSuite) SetupTest() {
+	s.resourceRoot = "../test-resources"
+	s.namespace = "fake-ns"
+	s.cluster = NewFakeCluster()
+	s.kubeContext = NewContextWithBackend(s.namespace, s.cluster)
+	exists, err := s.kubeContext.Namespace().Create()
+	require.Nil(s.T(), err)
+	require.False(s.T(), exists)
+}
+
+func (s *FakeClusterTestSuite) TestNamespace() {
+	exists, err := s.kubeContext.Namespace().Create()
+	require.Nil(s.T(), err)
+	require.True(s.T(), exists)
+	s.createResource("config-map", "configmap", filepath.Join("static", "config-map.yml"))
+	s.cluster.TransitionReads = 1
+	exists, err = s.kubeContext.Namespace().Delete()
+	require.Nil(s.T(), err)
+	require.True(s.T(), exists)
+	status, err := s.kubeContext.Namespace().getStatus()
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), RsStatusTerminating, status)
+	status, err = s.kubeContext.Namespace().getStatus()
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), RsStatusNotExist, status)
+	_, err = s.cluster.Get(context.Background(), s.namespace, "configmap", "config-map")
+	require.True(s.T(), IsNotExist(err), "objects are removed with their namespace")
+}
+
+func (s *FakeClusterTestSuite) TestApplyIntoMissingNamespace() {
+	kubeContext := NewContextWithBackend("missing", s.cluster)
+	_, err := kubeContext.Resource().Create("config-map", "configmap", s.readResource(filepath.Join("static", "config-map.yml")))
+	require.NotNil(s.T(), err)
+}
+
+func (s *FakeClusterTestSuite) TestCreateAndReplace() {
+	manifest := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: owned\n%sdata:\n  key: %s\n"
+	created, err := s.kubeContext.Resource().PutNew(fmt.Sprintf(manifest, "", "first"))
+	require.Nil(s.T(), err)
+	_, err = s.kubeContext.Resource().PutNew(fmt.Sprintf(manifest, "", "second"))
+	require.True(s.T(), IsAlreadyExists(err), "%v", err)
+	obj := &unstructured.Unstructured{}
+	require.Nil(s.T(), obj.UnmarshalJSON(created))
+	version := "  resourceVersion: \"" + obj.GetResourceVersion() + "\"\n"
+	_, err = s.kubeContext.Resource().Replace(fmt.Sprintf(manifest, version, "second"))
+	require.Nil(s.T(), err)
+	_, err = s.kubeContext.Resource().Replace(fmt.Sprintf(manifest, version, "third"))
+	require.True(s.T(), IsConflict(err), "%v", err)
+	_, err = s.kubeContext.Resource().Replace(strings.Replace(fmt.Sprintf(manifest, "", "third"), "owned", "missing", 1))
+	require.True(s.T(), IsNotExist(err), "%v", err)
+}
+
+func (s *FakeClusterTestSuite) TestStaticResource() {
+	s.createResource("config-map", "ConfigMap", filepath.Join("static", "config-map.yml"))
+	exists, err := s.kubeContext.Resource().Create("config-map", "configmap", s.readResource(filepath.Join("static", "config-map.yml")))
+	require.Nil(s.T(), err)
+	require.True(s.T(), exists)
+	results, err := s.cluster.Apply(context.Background(), s.namespace, []byte(s.readResource(filepath.Join("static", "config-map.yml"))))
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []*ApplyResult{{"configmap", "config-map", "unchanged"}}, results)
+	updateStatus, err := s.kubeContext.Resource().Update("config-map", "cm", s.readResource(filepath.Join("static", "config-map-updated.yml")))
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), UpdateStatusExisted, updateStatus)
+	s.deleteResource("config-map", "configmap")
+	updateStatus, err = s.kubeContext.Resource().Update("config-map", "configmap", s.readResource(filepath.Join("static", "config-map-updated.yml")))
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), UpdateStatusNotExist, updateStatus)
+	exists, err = s.kubeContext.Resource().Delete("config-map", "configmap")
+	require.Nil(s.T(), err)
+	require.False(s.T(), exists)
+}
+
+func (s *FakeClusterTestSuite) TestPodLifecycle() {
+	s.cluster.TransitionReads = 1
+	s.createResource("completed", "pod", filepath.Join("pod", "completed.yml"))
+	s.verifyStatus("completed", "pod", RsStatusPending)
+	s.verifyStatus("completed", "pod", RsStatusActive)
+	s.verifyStatus("completed", "pod", RsStatusSucceeded)
+	s.verifyStatus("completed", "pod", RsStatusSucceeded)
+	s.createResource("happy", "pod", filepath.Join("pod", "happy.yml"))
+	s.verifyStatus("happy", "pod", RsStatusPending)
+	s.verifyStatus("happy", "pod", RsStatusActive)
+	s.verifyStatus("happy", "pod", RsStatusActive)
+	exists, err := s.kubeContext.Resource().Delete("happy", "pod")
+	require.Nil(s.T(), err)
+	require.True(s.T(), exists)
+	s.verifyStatus("happy", "pod", RsStatusTerminating)
+	s.verifyStatus("happy", "pod", RsStatusNotExist)
+}
+
+func (s *FakeClusterTestSuite) TestPodWait() {
+	s.createResource("success", "pod", filepath.Join("pod", "success.yml"))
+	s.wait("success", "pod", true)
+	s.cluster.SetOutcome(s.namespace, "pod", "error", FakeOutcomeFailed)
+	s.createResource("error", "pod", filepath.Join("pod", "error.yml"))
+	s.wait("error", "pod", false)
+	_, err := s.kubeContext.Resource().Wait("not-exists", "pod")
+	require.True(s.T(), IsNotExist(err))
+}
+
+func (s *FakeClusterTestSuite) TestJobWait() {
+	s.createResource("success", "job", filepath.Join("job", "success.yml"))
+	s.wait("success", "job", true)
+	s.cluster.SetOutcome(s.namespace, "job", "error", FakeOutcomeFailed)
+	s.createResource("error", "job", filepath.Join("job", "error.yml"))
+	s.wait("error", "job", false)
+}
+
+func (s *FakeClusterTestSuite) TestDeploymentWait() {
+	s.createResource("deployment", "deployment", filepath.Join("pod-based", "deployment.yml"))
+	s.wait("deployment", "deploy", true)
+	s.cluster.SetOutcome(s.namespace, "deployment", "deployment", FakeOutcomeFailed)
+	updateStatus, err := s.kubeContext.Resource().Update("deployment", "deployment", s.readResource(filepath.Join("pod-based", "deployment-updated.yml")))
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), UpdateStatusExisted, updateStatus)
+	s.wait("deployment", "deployment", false)
+}
+
+func (s *FakeClusterTestSuite) TestUpgrade() {
+	s.createResource("completed", "pod", filepath.Join("pod", "completed.yml"))
+	s.verifyStatus("completed", "pod", RsStatusSucceeded)
+	updateStatus, err := s.kubeContext.Resource().Upgrade("completed", "pod", s.readResource(filepath.Join("pod", "completed-updated.yml")))
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), UpdateStatusExisted, updateStatus)
+	s.cluster.SetOutcome(s.namespace, "pod", "happy", FakeOutcomeStuck)
+	s.createResource("happy", "pod", filepath.Join("pod", "happy.yml"))
+	s.verifyStatus("happy", "pod", RsStatusActive)
+	updateStatus, err = s.kubeContext.Resource().Upgrade("happy", "pod", s.readResource(filepath.Join("pod", "happy.yml")))
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), UpdateStatusSkipped, updateStatus)
+}
+
+func (s *FakeClusterTestSuite) TestLogs() {
+	s.createResource("logs", "pod", filepath.Join("pod", "logs.yml"))
+	s.cluster.SetLogs(s.namespace, "logs", "logs", "line1\nline2\ntest\n")
+	stdout := &bytes.Buffer{}
+	err := s.kubeContext.Resource().Logs("logs", "", stdout, &bytes.Buffer{})
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), "line1\nline2\ntest\n", stdout.String())
+	stdout = &bytes.Buffer{}
+	err = s.cluster.Logs(context.Background(), s.namespace, "logs", &LogOptions{Tail: 1}, stdout, &bytes.Buffer{})
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), "test\n", stdout.String())
+	s.deleteResource("logs", "pod")
+	err = s.kubeContext.Resource().Logs("logs", "", stdout, &bytes.Buffer{})
+	require.True(s.T(), IsNotExist(err))
+}
+
+func (s *FakeClusterTestSuite) TestServicePods() {
+	s.createResource("service", "service", filepath.Join("static", "service.yml"))
+	pods, err := s.kubeContext.Service().ListPods("service")
+	require.Nil(s.T(), err)
+	require.Empty(s.T(), pods)
+	pods, err = s.kubeContext.Service().ListPods("not-exists")
+	require.Nil(s.T(), err)
+	require.Empty(s.T(), pods)
+}
+
+func (s *FakeClusterTestSuite) TestWatch() {
+	watcher, err := s.cluster.Watch(context.Background(), s.namespace, "configmap", "config-map")
+	require.Nil(s.T(), err)
+	defer watcher.Stop()
+	s.createResource("config-map", "configmap", filepath.Join("static", "config-map.yml"))
+	event := <-watcher.Events()
+	require.Equal(s.T(), WatchEventAdded, event.Type)
+	s.deleteResource("config-map", "configmap")
+	event = <-watcher.Events()
+	require.Equal(s.T(), WatchEventDeleted, event.Type)
+}
+
+func (s *FakeClusterTestSuite) TestNormalizeKind() {
+	require.Equal(s.T(), "deployment", normalizeKind("Deployment"))
+	require.Equal(s.T(), "deployment", normalizeKind("deployments.apps"))
+	require.Equal(s.T(), "deployment", normalizeKind("deploy"))
+	require.Equal(s.T(), "ingress", normalizeKind("ingresses"))
+	require.Equal(s.T(), "networkpolicy", normalizeKind("networkpolicies"))
+	require.Equal(s.T(), "storageclass", normalizeKind("storageclasses"))
+	require.Equal(s.T(), "endpoints", normalizeKind("endpoints"))
+}
+
+func (s *FakeClusterTestSuite) readResource(filename string) string {
+	content, err := ioutil.ReadFile(filepath.Join(s.resourceRoot, "resource-test", filename))
+	require.Nil(s.T(), err)
+	return string(content)
+}
+
+func (s *FakeClusterTestSuite) createResource(name, kind, filename string) {
+	exists, err := s.kubeContext.Resource().Create(name, kind, s.readResource(filename))
+	require.Nil(s.T(), err)
+	require.False(s.T(), exists)
+}
+
+func (s *FakeClusterTestSuite) deleteResource(name, kind string) {
+	exists, err := s.kubeContext.Resource().Delete(name, kind)
+	require.Nil(s.T(), err)
+	require.True(s.T(), exists)
+}
+
+func (s *FakeClusterTestSuite) verifyStatus(name, kind string, expected RsStatus) {
+	status, err := s.kubeContext.Resource().GetStatus(name, kind)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), expected, status)
+}
+
+func (s *FakeClusterTestSuite) wait(name, kind string, expectedSuccess bool) {
+	success, err := s.kubeContext.Resource().Wait(name, kind)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), expectedSuccess, success)
+}
+
+func TestFakeCluster(t *testing.T) {
+	suite.Run(t, new(FakeClusterTestSuite))
+}
@@ -0,0 +1,343 @@
+package kubernetes
+
+import (
+	"bufio"
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// kubectlBackend talks to the cluster by running kubectl
+type kubectlBackend struct {
+	context    string
+	kubeConfig string
+}
+
+func newKubectlBackend(context, kubeConfig string) (Backend, error) {
+	b := &kubectlBackend{context, kubeConfig}
+	err := b.checkDeps()
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *kubectlBackend) Get(ctx gocontext.Context, namespace, kind, name string) ([]byte, error) {
+	args := b.completeArgs(namespace, []string{"get", kind, name, "-o", "json"})
+	cmdResult, err := utils.NewCommandContext(ctx, "kubectl", args...).Run()
+	if err != nil {
+		return nil, err
+	}
+	if cmdResult.ExitCode != 0 {
+		return nil, b.commandError(cmdResult, name, kind)
+	}
+	output, err := ioutil.ReadAll(cmdResult.Stdout)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot read stdout")
+	}
+	return output, nil
+}
+
+func (b *kubectlBackend) List(ctx gocontext.Context, namespace, kind, selector string) ([][]byte, error) {
+	args := []string{"get", kind, "-o", "json"}
+	if selector != "" {
+		args = append(args, "-l", selector)
+	}
+	cmdResult, err := utils.NewCommandContext(ctx, "kubectl", b.completeArgs(namespace, args)...).Run()
+	if err != nil {
+		return nil, err
+	}
+	if cmdResult.ExitCode != 0 {
+		return nil, b.commandError(cmdResult, "", kind)
+	}
+	output, err := ioutil.ReadAll(cmdResult.Stdout)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot read stdout")
+	}
+	list := &kubectlList{}
+	err = json.Unmarshal(output, list)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+	}
+	items := [][]byte{}
+	for _, item := range list.Items {
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (b *kubectlBackend) Apply(ctx gocontext.Context, namespace string, content []byte) ([]*ApplyResult, error) {
+	args := b.completeArgs(namespace, []string{"apply", "-f", "-"})
+	cmd := utils.NewCommandContext(ctx, "kubectl", args...)
+	cmd.SetStdin(content)
+	cmdResult, err := cmd.Run()
+	if err != nil {
+		return nil, err
+	}
+	if cmdResult.ExitCode != 0 {
+		return nil, b.commandError(cmdResult, "", "")
+	}
+	results := []*ApplyResult{}
+	scanner := bufio.NewScanner(cmdResult.Stdout)
+	for scanner.Scan() {
+		result := parseKubectlApplyLine(scanner.Text())
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func (b *kubectlBackend) Create(ctx gocontext.Context, namespace string, content []byte) ([]byte, error) {
+	return b.write(ctx, namespace, "create", content)
+}
+
+func (b *kubectlBackend) Replace(ctx gocontext.Context, namespace string, content []byte) ([]byte, error) {
+	return b.write(ctx, namespace, "replace", content)
+}
+
+// write runs kubectl create or replace with a manifest, it returns the written object
+func (b *kubectlBackend) write(ctx gocontext.Context, namespace, verb string, content []byte) ([]byte, error) {
+	jsonContent, err := k8syaml.ToJSON(content)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode manifest")
+	}
+	obj := &unstructured.Unstructured{}
+	err = obj.UnmarshalJSON(jsonContent)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode manifest")
+	}
+	args := b.completeArgs(namespace, []string{verb, "-f", "-", "-o", "json"})
+	cmd := utils.NewCommandContext(ctx, "kubectl", args...)
+	cmd.SetStdin(content)
+	cmdResult, err := cmd.Run()
+	if err != nil {
+		return nil, err
+	}
+	if cmdResult.ExitCode != 0 {
+		return nil, b.commandError(cmdResult, obj.GetName(), strings.ToLower(obj.GetKind()))
+	}
+	output, err := ioutil.ReadAll(cmdResult.Stdout)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot read stdout")
+	}
+	return output, nil
+}
+
+func (b *kubectlBackend) Delete(ctx gocontext.Context, namespace, kind, name string) error {
+	args := b.completeArgs(namespace, []string{"delete", kind, name})
+	cmdResult, err := utils.NewCommandContext(ctx, "kubectl", args...).Run()
+	if err != nil {
+		return err
+	}
+	if cmdResult.ExitCode != 0 {
+		return b.commandError(cmdResult, name, kind)
+	}
+	return nil
+}
+
+func (b *kubectlBackend) Watch(ctx gocontext.Context, namespace, kind, name string) (Watcher, error) {
+	args := b.completeArgs(namespace, []string{"get", kind, name, "-w", "-o", "json", "--output-watch-events"})
+	cmd := utils.NewCommandContext(ctx, "kubectl", args...)
+	r, w := io.Pipe()
+	cmd.SetStdout(w)
+	err := cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+	watcher := &kubectlWatcher{
+		cmd:    cmd,
+		events: make(chan *WatchEvent),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		_, _ = cmd.Wait()
+		w.Close()
+	}()
+	go watcher.decode(r)
+	return watcher, nil
+}
+
+func (b *kubectlBackend) Logs(ctx gocontext.Context, namespace, name string, opts *LogOptions, stdout, stderr io.Writer) error {
+	args := []string{"logs"}
+	if opts.Follow {
+		args = append(args, "-f")
+	}
+	if opts.Container != "" {
+		args = append(args, "-c", opts.Container)
+	}
+	if opts.Tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(opts.Tail))
+	}
+	args = append(args, name)
+	cmd := utils.NewCommandContext(ctx, "kubectl", b.completeArgs(namespace, args)...)
+	errOutput := &bytes.Buffer{}
+	cmd.SetStdout(stdout)
+	cmd.SetStderr(io.MultiWriter(stderr, errOutput))
+	cmdResult, err := cmd.Run()
+	if err != nil {
+		return err
+	}
+	if cmdResult.ExitCode != 0 {
+		return stacktrace.Propagate(ErrCommandExitCode{cmdResult.ExitCode, parseCommandFailure(errOutput.String())}, "command execute error")
+	}
+	return nil
+}
+
+func (b *kubectlBackend) checkDeps() error {
+	status, err := utils.ExecuteCommandSilently("which", "kubectl")
+	if err != nil {
+		return nil
+	}
+	if status.ExitCode != 0 {
+		return stacktrace.Propagate(ErrMissingCommand{"kubectl"}, "missing command %q", "kubectl")
+	}
+	return nil
+}
+
+func (b *kubectlBackend) completeArgs(namespace string, args []string) []string {
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	if b.context != "" {
+		args = append(args, "--context", b.context)
+	}
+	if b.kubeConfig != "" {
+		args = append(args, "--kubeconfig", b.kubeConfig)
+	}
+	return args
+}
+
+func (b *kubectlBackend) commandError(cmdResult *utils.CommandStatus, name, kind string) error {
+	output, _ := ioutil.ReadAll(cmdResult.Stderr)
+	errOutput := string(output)
+	failure := parseCommandFailure(errOutput)
+	switch {
+	case name != "" && failure == CommandFailureNotFound:
+		return stacktrace.Propagate(ErrNotExist{name, kind}, "not exist")
+	case name != "" && failure == CommandFailureAlreadyExists:
+		return stacktrace.Propagate(ErrAlreadyExists{name, kind}, "already exists")
+	case name != "" && failure == CommandFailureConflict:
+		return stacktrace.Propagate(ErrConflict{name, kind}, "conflict")
+	}
+	return stacktrace.Propagate(ErrCommandExecute{cmdResult.ExitCode, errOutput, failure}, "error execute command")
+}
+
+var (
+	// kubectlStatusPattern matches the status reason kubectl prints for the errors returned by the api server
+	kubectlStatusPattern = regexp.MustCompile(`(?m)^(?:Error from server|error: You must be logged in to the server) \((\w+)\)`)
+	// kubectlUnreachablePattern matches the errors of kubectl failing to connect to the api server
+	kubectlUnreachablePattern = regexp.MustCompile(`(?m)^(?:Unable to connect to the server: |The connection to the server .* was refused)`)
+	// kubectlInvalidPattern matches the errors of manifests refused by the api server or by kubectl validation
+	kubectlInvalidPattern = regexp.MustCompile(`(?m)^(?:The \S+ "[^"]*" is invalid: |error: error validating |error: error parsing |error: unable to decode )`)
+	// kubectlUnsupportedKindPattern matches the errors of kinds the cluster does not serve
+	kubectlUnsupportedKindPattern = regexp.MustCompile(`(?m)^error: (?:the server doesn't have a resource type |resource mapping not found )`)
+)
+
+// parseCommandFailure reads what a failed kubectl command ran into from its error output
+func parseCommandFailure(output string) CommandFailure {
+	if match := kubectlStatusPattern.FindStringSubmatch(output); match != nil {
+		return statusReasonFailure(metav1.StatusReason(match[1]))
+	}
+	switch {
+	case kubectlUnreachablePattern.MatchString(output):
+		return CommandFailureUnreachable
+	case kubectlUnsupportedKindPattern.MatchString(output):
+		return CommandFailureUnsupportedKind
+	case kubectlInvalidPattern.MatchString(output):
+		return CommandFailureRejected
+	default:
+		return CommandFailureUnknown
+	}
+}
+
+// statusReasonFailure maps the reason of an api error to a failure
+func statusReasonFailure(reason metav1.StatusReason) CommandFailure {
+	switch reason {
+	case metav1.StatusReasonUnauthorized, metav1.StatusReasonServiceUnavailable, metav1.StatusReasonServerTimeout, metav1.StatusReasonTimeout, metav1.StatusReasonTooManyRequests:
+		return CommandFailureUnreachable
+	case metav1.StatusReasonNotFound:
+		return CommandFailureNotFound
+	case metav1.StatusReasonAlreadyExists:
+		return CommandFailureAlreadyExists
+	case metav1.StatusReasonConflict:
+		return CommandFailureConflict
+	case metav1.StatusReasonForbidden:
+		return CommandFailureForbidden
+	default:
+		return CommandFailureRejected
+	}
+}
+
+// parseKubectlApplyLine parses lines like "deployment.apps/nginx configured"
+func parseKubectlApplyLine(line string) *ApplyResult {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil
+	}
+	segments := strings.SplitN(fields[0], "/", 2)
+	if len(segments) != 2 {
+		return nil
+	}
+	return &ApplyResult{
+		Kind:   strings.SplitN(segments[0], ".", 2)[0],
+		Name:   segments[1],
+		Action: fields[1],
+	}
+}
+
+type kubectlList struct {
+	Items []json.RawMessage `json:"items"`
+}
+
+type kubectlWatchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+type kubectlWatcher struct {
+	cmd    *utils.Command
+	events chan *WatchEvent
+	done   chan struct{}
+}
+
+func (w *kubectlWatcher) Events() <-chan *WatchEvent {
+	return w.events
+}
+
+func (w *kubectlWatcher) Stop() {
+	select {
+	case <-w.done:
+	default:
+		close(w.done)
+		_ = w.cmd.Kill()
+	}
+}
+
+func (w *kubectlWatcher) decode(r io.Reader) {
+	defer close(w.events)
+	decoder := json.NewDecoder(r)
+	for {
+		event := &kubectlWatchEvent{}
+		err := decoder.Decode(event)
+		if err != nil {
+			return
+		}
+		select {
+		case w.events <- &WatchEvent{event.Type, event.Object}:
+		case <-w.done:
+			return
+		}
+	}
+}
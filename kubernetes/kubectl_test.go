@@ -0,0 +1,45 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type KubectlTestSuite struct {
+	suite.Suite
+}
+
+func (s *KubectlTestSuite) TestParseApplyLine() {
+	require.Equal(s.T(), &ApplyResult{"deployment", "nginx", "configured"}, parseKubectlApplyLine("deployment.apps/nginx configured"))
+	require.Equal(s.T(), &ApplyResult{"configmap", "config", "created"}, parseKubectlApplyLine("configmap/config created"))
+	require.Equal(s.T(), &ApplyResult{"pod", "happy", "unchanged"}, parseKubectlApplyLine("pod/happy unchanged"))
+	require.Nil(s.T(), parseKubectlApplyLine("Warning: resource is missing annotation"))
+	require.Nil(s.T(), parseKubectlApplyLine(""))
+}
+
+func (s *KubectlTestSuite) TestParseCommandFailure() {
+	failures := map[string]CommandFailure{
+		"Unable to connect to the server: dial tcp 10.0.0.1:443: i/o timeout\n":                                                   CommandFailureUnreachable,
+		"The connection to the server localhost:8080 was refused - did you specify the right host or port?\n":                     CommandFailureUnreachable,
+		"error: You must be logged in to the server (Unauthorized)\n":                                                             CommandFailureUnreachable,
+		"Error from server (ServiceUnavailable): the server is currently unable to handle the request\n":                          CommandFailureUnreachable,
+		`Error from server (NotFound): pods "nginx" not found` + "\n":                                                             CommandFailureNotFound,
+		"Error from server (Forbidden): storageclasses.storage.k8s.io is forbidden: User \"ci\" cannot list resource\n":           CommandFailureForbidden,
+		`Error from server (AlreadyExists): namespaces "shop" already exists` + "\n":                                              CommandFailureAlreadyExists,
+		`The Deployment "nginx" is invalid: spec.template.metadata.labels: Invalid value` + "\n":                                  CommandFailureRejected,
+		"error: error validating \"deployment.yml\": error validating data\n":                                                     CommandFailureRejected,
+		`error: the server doesn't have a resource type "widgets"` + "\n":                                                         CommandFailureUnsupportedKind,
+		"Warning: deprecated\nError from server (Invalid): error when creating \"STDIN\": Deployment.apps \"nginx\" is invalid\n": CommandFailureRejected,
+		"signal: killed\n": CommandFailureUnknown,
+		"":                 CommandFailureUnknown,
+	}
+	for output, failure := range failures {
+		require.Equal(s.T(), failure, parseCommandFailure(output), output)
+	}
+}
+
+func TestKubectl(t *testing.T) {
+	suite.Run(t, new(KubectlTestSuite))
+}
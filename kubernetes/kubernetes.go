@@ -1,30 +1,116 @@
 package kubernetes
 
 import (
-	"io/ioutil"
-	"strings"
+	gocontext "context"
+	"io"
+	"os"
+	"sync"
 	"time"
 
-	"github.com/anduintransaction/rivendell/utils"
 	"github.com/palantir/stacktrace"
 	yaml "gopkg.in/yaml.v2"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 // Context .
 type Context struct {
-	namespace  string
-	context    string
-	kubeConfig string
+	ctx                      gocontext.Context
+	namespace                string
+	backend                  Backend
+	restartThreshold         int
+	unschedulableGracePeriod time.Duration
+	waitOptions              *WaitOptions
+	out                      io.Writer
+	warnOnce                 *sync.Once
 }
 
-// NewContext .
+// NewContext creates a context using the default backend
 func NewContext(namespace, context, kubeConfig string) (*Context, error) {
-	c := &Context{namespace, context, kubeConfig}
-	err := c.checkDeps()
+	return NewContextWithBackendName(namespace, context, kubeConfig, DefaultBackend)
+}
+
+// NewContextWithBackendName creates a context using a registered backend
+func NewContextWithBackendName(namespace, context, kubeConfig, backendName string) (*Context, error) {
+	backend, err := newBackend(backendName, context, kubeConfig)
 	if err != nil {
 		return nil, err
 	}
-	return c, nil
+	return NewContextWithBackend(namespace, backend), nil
+}
+
+// NewContextWithBackend .
+func NewContextWithBackend(namespace string, backend Backend) *Context {
+	return &Context{
+		ctx:                      gocontext.Background(),
+		namespace:                namespace,
+		backend:                  backend,
+		restartThreshold:         DefaultRestartThreshold,
+		unschedulableGracePeriod: DefaultUnschedulableGracePeriod,
+		waitOptions:              DefaultWaitOptions,
+		out:                      os.Stdout,
+		warnOnce:                 &sync.Once{},
+	}
+}
+
+// ResolveContextName returns the name of the kubernetes context used for a context flag and a config file:
+// the flag itself, or the current context of the config when the flag is empty
+func ResolveContextName(context, kubeConfig string) (string, error) {
+	if context != "" {
+		return context, nil
+	}
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeConfig
+	config, err := loadingRules.Load()
+	if err != nil {
+		return "", stacktrace.Propagate(err, "cannot load kubernetes config")
+	}
+	return config.CurrentContext, nil
+}
+
+// WithContext returns a copy of the context whose operations are aborted when ctx is done
+func (c *Context) WithContext(ctx gocontext.Context) *Context {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// WithRestartThreshold returns a copy of the context failing pod and job waits after a number of container restarts
+func (c *Context) WithRestartThreshold(restartThreshold int) *Context {
+	clone := *c
+	clone.restartThreshold = restartThreshold
+	return &clone
+}
+
+// WithUnschedulableGracePeriod returns a copy of the context failing pod and job waits once a pod stayed unschedulable
+// for a grace period
+func (c *Context) WithUnschedulableGracePeriod(gracePeriod time.Duration) *Context {
+	clone := *c
+	clone.unschedulableGracePeriod = gracePeriod
+	return &clone
+}
+
+// WithWaitOptions returns a copy of the context waiting with other intervals and timeouts
+func (c *Context) WithWaitOptions(waitOptions *WaitOptions) *Context {
+	clone := *c
+	clone.waitOptions = waitOptions
+	return &clone
+}
+
+// WithOutput returns a copy of the context printing the results of its operations to out
+func (c *Context) WithOutput(out io.Writer) *Context {
+	clone := *c
+	clone.out = out
+	return &clone
+}
+
+// Output .
+func (c *Context) Output() io.Writer {
+	return c.out
+}
+
+// Backend .
+func (c *Context) Backend() Backend {
+	return c.backend
 }
 
 // Namespace .
@@ -42,59 +128,49 @@ func (c *Context) Service() *Service {
 	return &Service{c}
 }
 
-func (c *Context) checkDeps() error {
-	status, err := utils.ExecuteCommandSilently("which", "kubectl")
-	if err != nil {
-		return nil
-	}
-	if status.ExitCode != 0 {
-		return stacktrace.Propagate(ErrMissingCommand{"kubectl"}, "missing command %q", "kubectl")
+func (c *Context) namespaceFor(kind string) string {
+	if kind == "namespace" || kind == "ns" {
+		return ""
 	}
-	return nil
+	return c.namespace
 }
 
-func (c *Context) completeArgsWithoutNamespace(args []string) []string {
-	if c.context != "" {
-		args = append(args, "--context", c.context)
-	}
-	if c.kubeConfig != "" {
-		args = append(args, "--kubeconfig", c.kubeConfig)
+// get returns the manifest of an object, or nil if the object does not exist
+func (c *Context) get(name, kind string) ([]byte, error) {
+	output, err := c.backend.Get(c.ctx, c.namespaceFor(kind), kind, name)
+	if IsNotExist(err) {
+		return nil, nil
 	}
-	return args
-}
-
-func (c *Context) completeArgs(args []string) []string {
-	if c.namespace != "" {
-		args = append(args, "-n", c.namespace)
+	if err != nil {
+		return nil, err
 	}
-	return c.completeArgsWithoutNamespace(args)
+	return output, nil
 }
 
 func (c *Context) getNonPodStatus(name, kind string) (RsStatus, error) {
-	var args []string
-	if kind == "namespace" {
-		args = c.completeArgsWithoutNamespace([]string{"get", kind, name, "-o", "yaml"})
-	} else {
-		args = c.completeArgs([]string{"get", kind, name, "-o", "yaml"})
-	}
-	cmdResult, err := utils.NewCommand("kubectl", args...).Run()
+	output, err := c.get(name, kind)
 	if err != nil {
 		return RsStatusUnknown, err
 	}
-	if cmdResult.ExitCode != 0 {
-		output, _ := ioutil.ReadAll(cmdResult.Stderr)
-		errOutput := string(output)
-		if strings.Contains(errOutput, "(NotFound)") {
-			return RsStatusNotExist, nil
-		}
-		return RsStatusUnknown, stacktrace.Propagate(ErrCommandExecute{cmdResult.ExitCode, errOutput}, "error execute command")
+	return parseNonPodStatus(kind, output)
+}
+
+func parseNonPodStatus(kind string, output []byte) (RsStatus, error) {
+	if output == nil {
+		return RsStatusNotExist, nil
 	}
-	output, _ := ioutil.ReadAll(cmdResult.Stdout)
 	rsInfo := &kubernetesResourceInfo{}
-	err = yaml.Unmarshal(output, rsInfo)
+	err := yaml.Unmarshal(output, rsInfo)
 	if err != nil {
 		return RsStatusUnknown, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
 	}
+	if check, ok := readinessChecks[normalizeKind(kind)]; ok {
+		readiness, err := check(output)
+		if err != nil {
+			return RsStatusUnknown, err
+		}
+		return readiness.status(), nil
+	}
 	if rsInfo.Status == nil {
 		// static resources like configmaps
 		return RsStatusActive, nil
@@ -106,31 +182,15 @@ func (c *Context) getNonPodStatus(name, kind string) (RsStatus, error) {
 		return RsStatusPending, nil
 	case "Terminating":
 		return RsStatusTerminating, nil
+	case "Lost":
+		return RsStatusFailed, nil
 	default:
 		return RsStatusUnknown, nil
 	}
 }
 
 func (c *Context) waitForNonPodTerminate(name, kind string) error {
-	check := 0
-	for {
-		status, err := c.getNonPodStatus(name, kind)
-		if err != nil {
-			return err
-		}
-		switch status {
-		case RsStatusTerminating:
-			time.Sleep(defaultTerminateInterval)
-			check++
-			if check > defaultTerminateCheckLimit {
-				return stacktrace.Propagate(ErrTimeout{}, "timeout waiting for terminating %s %q", kind, name)
-			}
-		case RsStatusUnknown:
-			return stacktrace.Propagate(ErrUnknownStatus{name, kind, status}, "unknown status")
-		default:
-			return nil
-		}
-	}
+	return c.waitWhile(name, kind, RsStatusTerminating)
 }
 
 type kubernetesResourceInfo struct {
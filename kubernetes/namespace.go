@@ -1,7 +1,8 @@
 package kubernetes
 
 import (
-	"github.com/anduintransaction/rivendell/utils"
+	"fmt"
+
 	"github.com/palantir/stacktrace"
 )
 
@@ -10,6 +11,11 @@ type Namespace struct {
 	context *Context
 }
 
+// Name of the namespace
+func (n *Namespace) Name() string {
+	return n.context.namespace
+}
+
 // Create namespace in context
 func (n *Namespace) Create() (exists bool, err error) {
 	status, err := n.getStatus()
@@ -30,14 +36,12 @@ func (n *Namespace) Create() (exists bool, err error) {
 		}
 	}
 	exists = false
-	args := n.context.completeArgsWithoutNamespace([]string{"create", "ns", n.context.namespace})
-	cmdResult, err := utils.ExecuteCommand("kubectl", args...)
+	manifest := fmt.Sprintf("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: %s\n", n.context.namespace)
+	_, err = n.context.backend.Apply(n.context.ctx, "", []byte(manifest))
 	if err != nil {
 		return
 	}
-	if cmdResult.ExitCode != 0 {
-		return false, stacktrace.Propagate(ErrCommandExitCode{cmdResult.ExitCode}, "command execute error")
-	}
+	fmt.Fprintf(n.context.out, "namespace/%s created\n", n.context.namespace)
 	return
 }
 
@@ -76,14 +80,14 @@ func (n *Namespace) Delete() (exists bool, err error) {
 		return
 	}
 	exists = true
-	args := n.context.completeArgsWithoutNamespace([]string{"delete", "ns", n.context.namespace})
-	cmdResult, err := utils.ExecuteCommand("kubectl", args...)
+	err = n.context.backend.Delete(n.context.ctx, "", "namespace", n.context.namespace)
+	if IsNotExist(err) {
+		return false, nil
+	}
 	if err != nil {
 		return
 	}
-	if cmdResult.ExitCode != 0 {
-		return false, stacktrace.Propagate(ErrCommandExitCode{cmdResult.ExitCode}, "command execute error")
-	}
+	fmt.Fprintf(n.context.out, "namespace %q deleted\n", n.context.namespace)
 	return
 }
 
@@ -0,0 +1,418 @@
+package kubernetes
+
+import (
+	"bytes"
+	gocontext "context"
+	"io"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const nativeFieldManager = "rivendell"
+
+// ForceConflicts makes the native backend take over the fields owned by another field manager when applying,
+// instead of failing with ErrApplyConflict
+var ForceConflicts = false
+
+// nativeBackend talks to the cluster with client-go, no kubectl binary is required
+type nativeBackend struct {
+	defaultNamespace string
+	mapper           meta.RESTMapper
+	dynamicClient    dynamic.Interface
+	restClient       rest.Interface
+	clientset        clientset.Interface
+}
+
+func newNativeBackend(kubeContext, kubeConfig string) (Backend, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeConfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot load kubernetes config")
+	}
+	defaultNamespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot resolve default namespace")
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot create discovery client")
+	}
+	cachedDiscovery := memory.NewMemCacheClient(discoveryClient)
+	mapper := restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(cachedDiscovery), cachedDiscovery)
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot create dynamic client")
+	}
+	// the dynamic client does not tell whether an apply created its object, applies go through a plain rest client
+	applyConfig := dynamic.ConfigFor(restConfig)
+	applyConfig.GroupVersion = &schema.GroupVersion{}
+	restClient, err := rest.RESTClientFor(applyConfig)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot create rest client")
+	}
+	cs, err := clientset.NewForConfig(restConfig)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot create kubernetes client")
+	}
+	return &nativeBackend{
+		defaultNamespace: defaultNamespace,
+		mapper:           mapper,
+		dynamicClient:    dynamicClient,
+		restClient:       restClient,
+		clientset:        cs,
+	}, nil
+}
+
+func (b *nativeBackend) Get(ctx gocontext.Context, namespace, kind, name string) ([]byte, error) {
+	client, err := b.resourceClient(namespace, kind)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, b.apiError(err, name, kind)
+	}
+	return obj.MarshalJSON()
+}
+
+func (b *nativeBackend) List(ctx gocontext.Context, namespace, kind, selector string) ([][]byte, error) {
+	client, err := b.resourceClient(namespace, kind)
+	if err != nil {
+		return nil, err
+	}
+	list, err := client.List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, b.apiError(err, "", kind)
+	}
+	items := [][]byte{}
+	for i := range list.Items {
+		item, err := list.Items[i].MarshalJSON()
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot encode %s", kind)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (b *nativeBackend) Apply(ctx gocontext.Context, namespace string, content []byte) ([]*ApplyResult, error) {
+	results := []*ApplyResult{}
+	decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot decode manifest")
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		result, err := b.applyObject(ctx, namespace, obj)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (b *nativeBackend) Create(ctx gocontext.Context, namespace string, content []byte) ([]byte, error) {
+	obj, client, err := b.objectClient(namespace, content)
+	if err != nil {
+		return nil, err
+	}
+	created, err := client.Create(ctx, obj, metav1.CreateOptions{FieldManager: nativeFieldManager})
+	if err != nil {
+		return nil, b.writeError(err, obj.GetName(), strings.ToLower(obj.GetKind()))
+	}
+	return created.MarshalJSON()
+}
+
+func (b *nativeBackend) Replace(ctx gocontext.Context, namespace string, content []byte) ([]byte, error) {
+	obj, client, err := b.objectClient(namespace, content)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := client.Update(ctx, obj, metav1.UpdateOptions{FieldManager: nativeFieldManager})
+	if err != nil {
+		return nil, b.writeError(err, obj.GetName(), strings.ToLower(obj.GetKind()))
+	}
+	return updated.MarshalJSON()
+}
+
+func (b *nativeBackend) Delete(ctx gocontext.Context, namespace, kind, name string) error {
+	client, err := b.resourceClient(namespace, kind)
+	if err != nil {
+		return err
+	}
+	propagation := metav1.DeletePropagationBackground
+	err = client.Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil {
+		return b.apiError(err, name, kind)
+	}
+	return nil
+}
+
+func (b *nativeBackend) Watch(ctx gocontext.Context, namespace, kind, name string) (Watcher, error) {
+	client, err := b.resourceClient(namespace, kind)
+	if err != nil {
+		return nil, err
+	}
+	w, err := client.Watch(ctx, metav1.ListOptions{FieldSelector: "metadata.name=" + name})
+	if err != nil {
+		return nil, b.apiError(err, name, kind)
+	}
+	watcher := &nativeWatcher{
+		watch:  w,
+		events: make(chan *WatchEvent),
+		done:   make(chan struct{}),
+	}
+	go watcher.convert()
+	return watcher, nil
+}
+
+func (b *nativeBackend) Logs(ctx gocontext.Context, namespace, name string, opts *LogOptions, stdout, stderr io.Writer) error {
+	podLogOptions := &corev1.PodLogOptions{
+		Container: opts.Container,
+		Follow:    opts.Follow,
+	}
+	if opts.Tail > 0 {
+		tail := int64(opts.Tail)
+		podLogOptions.TailLines = &tail
+	}
+	stream, err := b.clientset.CoreV1().Pods(b.namespace(namespace)).GetLogs(name, podLogOptions).Stream(ctx)
+	if err != nil {
+		return b.apiError(err, name, "pod")
+	}
+	defer stream.Close()
+	_, err = io.Copy(stdout, stream)
+	if err != nil {
+		return stacktrace.Propagate(err, "cannot read log of pod %q", name)
+	}
+	return nil
+}
+
+// applyObject applies an object with server-side apply. The object is first applied with a dry run: an unchanged
+// object keeps the resource version returned by the dry run, a created one has none.
+func (b *nativeBackend) applyObject(ctx gocontext.Context, namespace string, obj *unstructured.Unstructured) (*ApplyResult, error) {
+	gvk := obj.GroupVersionKind()
+	mapping, err := b.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrUnsupportedKind{gvk.Kind}, "cannot map kind: %s", err)
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && obj.GetNamespace() == "" {
+		obj.SetNamespace(b.namespace(namespace))
+	}
+	kind := strings.ToLower(gvk.Kind)
+	body, err := obj.MarshalJSON()
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot encode %s %q", kind, obj.GetName())
+	}
+	dryRun, _, err := b.applyRequest(ctx, mapping, obj, body, true)
+	if err != nil {
+		return nil, b.applyError(err, obj.GetName(), kind)
+	}
+	applied, created, err := b.applyRequest(ctx, mapping, obj, body, false)
+	if err != nil {
+		return nil, b.applyError(err, obj.GetName(), kind)
+	}
+	result := &ApplyResult{Kind: kind, Name: obj.GetName()}
+	switch {
+	case created:
+		result.Action = "created"
+	case dryRun.GetResourceVersion() == applied.GetResourceVersion():
+		result.Action = "unchanged"
+	default:
+		result.Action = "configured"
+	}
+	return result, nil
+}
+
+// applyRequest sends an apply patch, it returns the applied object and whether the apply created it
+func (b *nativeBackend) applyRequest(ctx gocontext.Context, mapping *meta.RESTMapping, obj *unstructured.Unstructured, body []byte, dryRun bool) (*unstructured.Unstructured, bool, error) {
+	segments := []string{"api"}
+	if mapping.Resource.Group != "" {
+		segments = []string{"apis", mapping.Resource.Group}
+	}
+	segments = append(segments, mapping.Resource.Version)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		segments = append(segments, "namespaces", obj.GetNamespace())
+	}
+	segments = append(segments, mapping.Resource.Resource, obj.GetName())
+	request := b.restClient.Patch(types.ApplyPatchType).
+		AbsPath(segments...).
+		Param("fieldManager", nativeFieldManager).
+		Body(body)
+	if ForceConflicts {
+		request = request.Param("force", "true")
+	}
+	if dryRun {
+		request = request.Param("dryRun", metav1.DryRunAll)
+	}
+	created := false
+	response, err := request.Do(ctx).WasCreated(&created).Raw()
+	if err != nil {
+		return nil, false, err
+	}
+	applied := &unstructured.Unstructured{}
+	err = applied.UnmarshalJSON(response)
+	if err != nil {
+		return nil, false, stacktrace.Propagate(err, "cannot decode applied %s %q", mapping.GroupVersionKind.Kind, obj.GetName())
+	}
+	return applied, created, nil
+}
+
+// objectClient decodes the single object of a manifest and returns the client of its resource
+func (b *nativeBackend) objectClient(namespace string, content []byte) (*unstructured.Unstructured, dynamic.ResourceInterface, error) {
+	obj := &unstructured.Unstructured{}
+	err := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 4096).Decode(&obj.Object)
+	if err != nil {
+		return nil, nil, stacktrace.Propagate(err, "cannot decode manifest")
+	}
+	gvk := obj.GroupVersionKind()
+	mapping, err := b.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, nil, stacktrace.Propagate(ErrUnsupportedKind{gvk.Kind}, "cannot map kind: %s", err)
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return obj, b.dynamicClient.Resource(mapping.Resource), nil
+	}
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(b.namespace(namespace))
+	}
+	return obj, b.dynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace()), nil
+}
+
+func (b *nativeBackend) resourceClient(namespace, kind string) (dynamic.ResourceInterface, error) {
+	gvr, err := b.resolveResource(kind)
+	if err != nil {
+		return nil, err
+	}
+	gvk, err := b.mapper.KindFor(gvr)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrUnsupportedKind{kind}, "cannot map kind: %s", err)
+	}
+	mapping, err := b.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrUnsupportedKind{kind}, "cannot map kind: %s", err)
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return b.dynamicClient.Resource(mapping.Resource), nil
+	}
+	return b.dynamicClient.Resource(mapping.Resource).Namespace(b.namespace(namespace)), nil
+}
+
+// resolveResource accepts the same kind forms as kubectl: deployment, deployments, deploy or deployment.apps
+func (b *nativeBackend) resolveResource(kind string) (schema.GroupVersionResource, error) {
+	fullySpecified, groupResource := schema.ParseResourceArg(strings.ToLower(kind))
+	if fullySpecified != nil {
+		if gvr, err := b.mapper.ResourceFor(*fullySpecified); err == nil {
+			return gvr, nil
+		}
+	}
+	gvr, err := b.mapper.ResourceFor(groupResource.WithVersion(""))
+	if err != nil {
+		return schema.GroupVersionResource{}, stacktrace.Propagate(ErrUnsupportedKind{kind}, "cannot map kind: %s", err)
+	}
+	return gvr, nil
+}
+
+func (b *nativeBackend) namespace(namespace string) string {
+	if namespace == "" {
+		return b.defaultNamespace
+	}
+	return namespace
+}
+
+// applyError reports the fields owned by another field manager as ErrApplyConflict
+func (b *nativeBackend) applyError(err error, name, kind string) error {
+	if apierrors.IsConflict(err) {
+		return stacktrace.Propagate(ErrApplyConflict{name, kind, err.Error()}, "apply conflict")
+	}
+	return b.apiError(err, name, kind)
+}
+
+// writeError reports existing objects as ErrAlreadyExists and objects modified since their version as ErrConflict
+func (b *nativeBackend) writeError(err error, name, kind string) error {
+	switch {
+	case apierrors.IsAlreadyExists(err):
+		return stacktrace.Propagate(ErrAlreadyExists{name, kind}, "already exists")
+	case apierrors.IsConflict(err):
+		return stacktrace.Propagate(ErrConflict{name, kind}, "conflict")
+	default:
+		return b.apiError(err, name, kind)
+	}
+}
+
+func (b *nativeBackend) apiError(err error, name, kind string) error {
+	if name != "" && apierrors.IsNotFound(err) {
+		return stacktrace.Propagate(ErrNotExist{name, kind}, "not exist")
+	}
+	return stacktrace.Propagate(err, "kubernetes api error")
+}
+
+type nativeWatcher struct {
+	watch  watch.Interface
+	events chan *WatchEvent
+	done   chan struct{}
+}
+
+func (w *nativeWatcher) Events() <-chan *WatchEvent {
+	return w.events
+}
+
+func (w *nativeWatcher) Stop() {
+	select {
+	case <-w.done:
+	default:
+		close(w.done)
+		w.watch.Stop()
+	}
+}
+
+func (w *nativeWatcher) convert() {
+	defer close(w.events)
+	for event := range w.watch.ResultChan() {
+		switch event.Type {
+		case watch.Added, watch.Modified, watch.Deleted:
+		default:
+			continue
+		}
+		obj, ok := event.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		content, err := obj.MarshalJSON()
+		if err != nil {
+			continue
+		}
+		select {
+		case w.events <- &WatchEvent{string(event.Type), content}:
+		case <-w.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package kubernetes
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// applyServer serves server-side applies of config maps, fields applied by another manager conflict unless forced
+type applyServer struct {
+	sync.Mutex
+	objects  map[string]*appliedConfigMap
+	requests []string
+}
+
+type appliedConfigMap struct {
+	data            map[string]string
+	manager         string
+	resourceVersion int
+}
+
+func (s *applyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	s.requests = append(s.requests, r.URL.RawQuery)
+	query := r.URL.Query()
+	segments := strings.Split(r.URL.Path, "/")
+	name := segments[len(segments)-1]
+	body, _ := ioutil.ReadAll(r.Body)
+	applied := struct {
+		Data map[string]string `json:"data"`
+	}{}
+	json.Unmarshal(body, &applied)
+	w.Header().Set("Content-Type", "application/json")
+	existing, ok := s.objects[name]
+	status := http.StatusOK
+	object := &appliedConfigMap{data: applied.Data, manager: query.Get("fieldManager")}
+	switch {
+	case !ok:
+		status = http.StatusCreated
+		object.resourceVersion = 1
+	case reflect.DeepEqual(existing.data, applied.Data):
+		object = existing
+	case existing.manager != query.Get("fieldManager") && query.Get("force") != "true":
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Conflict","code":409,"message":"Apply failed with 1 conflict: conflict with \"kubectl-edit\""}`))
+		return
+	default:
+		object.resourceVersion = existing.resourceVersion + 1
+	}
+	resourceVersion := ""
+	if query.Get("dryRun") == "" {
+		s.objects[name] = object
+		resourceVersion = strconv.Itoa(object.resourceVersion)
+	} else if ok {
+		resourceVersion = strconv.Itoa(existing.resourceVersion)
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "native-ns", "resourceVersion": resourceVersion},
+		"data":       object.data,
+	})
+}
+
+type NativeTestSuite struct {
+	suite.Suite
+	server  *applyServer
+	http    *httptest.Server
+	backend *nativeBackend
+}
+
+func (s *NativeTestSuite) SetupTest() {
+	s.server = &applyServer{objects: map[string]*appliedConfigMap{}}
+	s.http = httptest.NewServer(s.server)
+	config := dynamic.ConfigFor(&rest.Config{Host: s.http.URL})
+	config.GroupVersion = &schema.GroupVersion{}
+	restClient, err := rest.RESTClientFor(config)
+	require.Nil(s.T(), err)
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	s.backend = &nativeBackend{defaultNamespace: "native-ns", mapper: mapper, restClient: restClient}
+}
+
+func (s *NativeTestSuite) TearDownTest() {
+	s.http.Close()
+	ForceConflicts = false
+}
+
+func (s *NativeTestSuite) TestApplyActions() {
+	require.Equal(s.T(), "created", s.apply("value-1"))
+	require.Equal(s.T(), "unchanged", s.apply("value-1"))
+	require.Equal(s.T(), "configured", s.apply("value-2"))
+	require.Equal(s.T(), 2, s.server.objects["config"].resourceVersion)
+	for _, query := range s.server.requests {
+		require.Contains(s.T(), query, "fieldManager=rivendell")
+		require.NotContains(s.T(), query, "force")
+	}
+}
+
+func (s *NativeTestSuite) TestApplyConflict() {
+	s.server.objects["config"] = &appliedConfigMap{data: map[string]string{"key": "edited"}, manager: "kubectl-edit", resourceVersion: 7}
+	_, err := s.backend.Apply(gocontext.Background(), "", s.manifest("value-1"))
+	require.NotNil(s.T(), err)
+	conflict, ok := stacktrace.RootCause(err).(ErrApplyConflict)
+	require.True(s.T(), ok, err.Error())
+	require.Equal(s.T(), "config", conflict.Name)
+	require.True(s.T(), IsRejected(err))
+	require.Equal(s.T(), "edited", s.server.objects["config"].data["key"])
+
+	ForceConflicts = true
+	require.Equal(s.T(), "configured", s.apply("value-1"))
+	require.Equal(s.T(), "rivendell", s.server.objects["config"].manager)
+}
+
+func (s *NativeTestSuite) apply(value string) string {
+	results, err := s.backend.Apply(gocontext.Background(), "", s.manifest(value))
+	require.Nil(s.T(), err)
+	require.Len(s.T(), results, 1)
+	require.Equal(s.T(), "configmap", results[0].Kind)
+	require.Equal(s.T(), "config", results[0].Name)
+	return results[0].Action
+}
+
+func (s *NativeTestSuite) manifest(value string) []byte {
+	return []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  key: " + value + "\n")
+}
+
+func TestNative(t *testing.T) {
+	suite.Run(t, new(NativeTestSuite))
+}
@@ -0,0 +1,133 @@
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/palantir/stacktrace"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultRestartThreshold is the number of container restarts after which waiting for a pod or a job fails.
+// A value <= 0 disables the restart check.
+var DefaultRestartThreshold = 5
+
+// DefaultUnschedulableGracePeriod is how long a pod may stay unschedulable before waiting for it fails, leaving time
+// to a cluster autoscaler to add a node. A value <= 0 fails as soon as the pod is unschedulable.
+var DefaultUnschedulableGracePeriod = 2 * time.Minute
+
+// fatalWaitingReasons keep a container from starting until its spec or the cluster is changed
+var fatalWaitingReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"ErrImageNeverPull":          true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+}
+
+// checkPodFailures returns an ErrPodFailure if a pod, or a pod of a job, cannot run
+func (r *Resource) checkPodFailures(name, kind string, output []byte) error {
+	switch kind {
+	case "pod":
+		return r.checkPodFailure(output)
+	case "job":
+		items, err := r.context.backend.List(r.context.ctx, r.context.namespace, "pod", "job-name="+name)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			err = r.checkPodFailure(item)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Resource) checkPodFailure(output []byte) error {
+	podInfo := &podResourceInfo{}
+	err := yaml.Unmarshal(output, podInfo)
+	if err != nil {
+		return stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+	}
+	failure := podInfo.failure(r.context.restartThreshold, r.context.unschedulableGracePeriod, time.Now())
+	if failure != nil {
+		return stacktrace.Propagate(*failure, "pod cannot run")
+	}
+	return nil
+}
+
+func (p *podResourceInfo) failure(restartThreshold int, unschedulableGracePeriod time.Duration, now time.Time) *ErrPodFailure {
+	if p.Metadata == nil || p.Status == nil {
+		return nil
+	}
+	for _, condition := range p.Status.Conditions {
+		if condition.Type == "PodScheduled" && condition.Status == "False" && condition.Reason == "Unschedulable" && condition.unschedulableFor(now) >= unschedulableGracePeriod {
+			return &ErrPodFailure{
+				Pod:     p.Metadata.Name,
+				Reason:  condition.Reason,
+				Message: condition.Message,
+			}
+		}
+	}
+	containerStatuses := append(append([]*podContainerStatus{}, p.Status.InitContainerStatuses...), p.Status.ContainerStatuses...)
+	for _, containerStatus := range containerStatuses {
+		failure := &ErrPodFailure{
+			Pod:          p.Metadata.Name,
+			Container:    containerStatus.Name,
+			RestartCount: containerStatus.RestartCount,
+		}
+		if containerStatus.State != nil && containerStatus.State.Waiting != nil {
+			failure.Reason = containerStatus.State.Waiting.Reason
+			failure.Message = containerStatus.State.Waiting.Message
+			if fatalWaitingReasons[failure.Reason] {
+				return failure
+			}
+		}
+		if restartThreshold > 0 && containerStatus.RestartCount >= restartThreshold {
+			if failure.Reason == "" {
+				failure.Reason = "RestartThresholdExceeded"
+			}
+			if containerStatus.LastState != nil && containerStatus.LastState.Terminated != nil && failure.Message == "" {
+				failure.Message = containerStatus.LastState.Terminated.Reason
+			}
+			return failure
+		}
+	}
+	return nil
+}
+
+type podCondition struct {
+	Type               string `yaml:"type"`
+	Status             string `yaml:"status"`
+	Reason             string `yaml:"reason"`
+	Message            string `yaml:"message"`
+	LastTransitionTime string `yaml:"lastTransitionTime"`
+}
+
+// unschedulableFor is the time since the condition changed, zero if unknown
+func (c *podCondition) unschedulableFor(now time.Time) time.Duration {
+	since, err := time.Parse(time.RFC3339, c.LastTransitionTime)
+	if err != nil {
+		return 0
+	}
+	return now.Sub(since)
+}
+
+type podContainerStatus struct {
+	Name         string             `yaml:"name"`
+	RestartCount int                `yaml:"restartCount"`
+	State        *podContainerState `yaml:"state"`
+	LastState    *podContainerState `yaml:"lastState"`
+}
+
+type podContainerState struct {
+	Waiting *struct {
+		Reason  string `yaml:"reason"`
+		Message string `yaml:"message"`
+	} `yaml:"waiting"`
+	Terminated *struct {
+		Reason   string `yaml:"reason"`
+		ExitCode int    `yaml:"exitCode"`
+	} `yaml:"terminated"`
+}
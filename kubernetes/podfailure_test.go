@@ -0,0 +1,134 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type PodFailureTestSuite struct {
+	suite.Suite
+	resourceRoot string
+	namespace    string
+	cluster      *FakeCluster
+	kubeContext  *Context
+}
+
+func (s *PodFailureTestSuite) SetupTest() {
+	s.resourceRoot = "../test-resources"
+	s.namespace = "pod-failure-ns"
+	s.cluster = NewFakeCluster()
+	s.kubeContext = NewContextWithBackend(s.namespace, s.cluster)
+	_, err := s.kubeContext.Namespace().Create()
+	require.Nil(s.T(), err)
+}
+
+func (s *PodFailureTestSuite) TestImagePullBackOff() {
+	s.cluster.SetOutcome(s.namespace, "pod", "success", FakeOutcomeImagePullBackOff)
+	s.create("success", "pod", s.readResource(filepath.Join("pod", "success.yml")))
+	failure := s.waitFailure(s.kubeContext, "success", "pod")
+	require.Equal(s.T(), "success", failure.Pod)
+	require.Equal(s.T(), "ImagePullBackOff", failure.Reason)
+	require.NotEmpty(s.T(), failure.Container)
+	require.Contains(s.T(), failure.Message, "Back-off pulling image")
+}
+
+func (s *PodFailureTestSuite) TestUnschedulable() {
+	s.cluster.SetOutcome(s.namespace, "pod", "success", FakeOutcomeUnschedulable)
+	s.create("success", "pod", s.readResource(filepath.Join("pod", "success.yml")))
+	failure := s.waitFailure(s.kubeContext.WithUnschedulableGracePeriod(0), "success", "pod")
+	require.Equal(s.T(), "Unschedulable", failure.Reason)
+	require.Empty(s.T(), failure.Container)
+}
+
+func (s *PodFailureTestSuite) TestUnschedulableGracePeriod() {
+	now := time.Now()
+	podInfo := s.parsePod(`{"metadata": {"name": "pod"}, "status": {"phase": "Pending", "conditions": [{"type": "PodScheduled", "status": "False", "reason": "Unschedulable", "message": "0/1 nodes are available: 1 Insufficient cpu.", "lastTransitionTime": "` + now.Add(-time.Minute).UTC().Format(time.RFC3339) + `"}]}}`)
+	require.Nil(s.T(), podInfo.failure(5, 2*time.Minute, now), "a node may still be added by an autoscaler")
+	failure := podInfo.failure(5, 2*time.Minute, now.Add(2*time.Minute))
+	require.NotNil(s.T(), failure)
+	require.Equal(s.T(), "Unschedulable", failure.Reason)
+	require.Contains(s.T(), failure.Message, "Insufficient cpu")
+	require.NotNil(s.T(), podInfo.failure(5, 0, now))
+
+	podInfo = s.parsePod(`{"metadata": {"name": "pod"}, "status": {"phase": "Pending", "conditions": [{"type": "PodScheduled", "status": "False", "reason": "Unschedulable"}]}}`)
+	require.Nil(s.T(), podInfo.failure(5, time.Minute, now))
+	require.NotNil(s.T(), podInfo.failure(5, 0, now))
+}
+
+func (s *PodFailureTestSuite) TestCrashLoopBackOff() {
+	s.cluster.SetOutcome(s.namespace, "pod", "happy", FakeOutcomeCrashLoopBackOff)
+	s.create("happy", "pod", s.readResource(filepath.Join("pod", "happy.yml")))
+	failure := s.waitFailure(s.kubeContext.WithRestartThreshold(2), "happy", "pod")
+	require.Equal(s.T(), "CrashLoopBackOff", failure.Reason)
+	require.True(s.T(), failure.RestartCount >= 2)
+}
+
+func (s *PodFailureTestSuite) TestJobPods() {
+	s.cluster.SetOutcome(s.namespace, "job", "success", FakeOutcomeStuck)
+	s.cluster.SetOutcome(s.namespace, "pod", "success-abcde", FakeOutcomeImagePullBackOff)
+	s.create("success", "job", s.readResource(filepath.Join("job", "success.yml")))
+	s.create("success-abcde", "pod", `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "success-abcde", "labels": {"job-name": "success"}}, "spec": {"restartPolicy": "Never", "containers": [{"name": "success", "image": "ubuntu:not-exists"}]}}`)
+	failure := s.waitFailure(s.kubeContext, "success", "job")
+	require.Equal(s.T(), "success-abcde", failure.Pod)
+	require.Equal(s.T(), "ImagePullBackOff", failure.Reason)
+}
+
+func (s *PodFailureTestSuite) TestFailure() {
+	podInfo := s.parsePod(`{"metadata": {"name": "pod"}, "status": {"phase": "Pending", "initContainerStatuses": [{"name": "init", "restartCount": 0, "state": {"waiting": {"reason": "CreateContainerConfigError", "message": "configmap \"config\" not found"}}}]}}`)
+	failure := podInfo.failure(5, time.Minute, time.Now())
+	require.NotNil(s.T(), failure)
+	require.Equal(s.T(), "init", failure.Container)
+	require.Equal(s.T(), "CreateContainerConfigError", failure.Reason)
+
+	podInfo = s.parsePod(`{"metadata": {"name": "pod"}, "status": {"phase": "Running", "containerStatuses": [{"name": "app", "restartCount": 3, "state": {"running": {}}, "lastState": {"terminated": {"reason": "OOMKilled", "exitCode": 137}}}]}}`)
+	require.Nil(s.T(), podInfo.failure(5, time.Minute, time.Now()))
+	require.Nil(s.T(), podInfo.failure(0, time.Minute, time.Now()))
+	failure = podInfo.failure(3, time.Minute, time.Now())
+	require.NotNil(s.T(), failure)
+	require.Equal(s.T(), "RestartThresholdExceeded", failure.Reason)
+	require.Equal(s.T(), "OOMKilled", failure.Message)
+
+	podInfo = s.parsePod(`{"metadata": {"name": "pod"}, "status": {"phase": "Pending", "containerStatuses": [{"name": "app", "restartCount": 0, "state": {"waiting": {"reason": "ErrImagePull"}}}]}}`)
+	failure = podInfo.failure(5, time.Minute, time.Now())
+	require.NotNil(s.T(), failure)
+	require.Equal(s.T(), "app", failure.Container)
+	require.Equal(s.T(), "ErrImagePull", failure.Reason)
+}
+
+func (s *PodFailureTestSuite) readResource(filename string) string {
+	content, err := ioutil.ReadFile(filepath.Join(s.resourceRoot, "resource-test", filename))
+	require.Nil(s.T(), err)
+	return string(content)
+}
+
+func (s *PodFailureTestSuite) create(name, kind, content string) {
+	exists, err := s.kubeContext.Resource().Create(name, kind, content)
+	require.Nil(s.T(), err)
+	require.False(s.T(), exists)
+}
+
+func (s *PodFailureTestSuite) waitFailure(kubeContext *Context, name, kind string) ErrPodFailure {
+	_, err := kubeContext.Resource().Wait(name, kind)
+	require.NotNil(s.T(), err)
+	failure, ok := stacktrace.RootCause(err).(ErrPodFailure)
+	require.True(s.T(), ok, err.Error())
+	return failure
+}
+
+func (s *PodFailureTestSuite) parsePod(content string) *podResourceInfo {
+	podInfo := &podResourceInfo{}
+	err := yaml.Unmarshal([]byte(content), podInfo)
+	require.Nil(s.T(), err)
+	return podInfo
+}
+
+func TestPodFailure(t *testing.T) {
+	suite.Run(t, new(PodFailureTestSuite))
+}
@@ -0,0 +1,391 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Readiness tells if a resource can be used by the resources depending on it
+type Readiness struct {
+	Ready   bool
+	Failed  bool
+	Message string
+}
+
+func (r *Readiness) status() RsStatus {
+	switch {
+	case r.Failed:
+		return RsStatusFailed
+	case r.Ready:
+		return RsStatusActive
+	default:
+		return RsStatusPending
+	}
+}
+
+// readinessChecks judge kinds which are not ready as soon as they exist
+var readinessChecks = map[string]waitCheck{
+	"deployment":  deploymentReadiness,
+	"statefulset": statefulSetReadiness,
+	"daemonset":   daemonSetReadiness,
+	"replicaset":  replicaSetReadiness,
+	"service":     serviceReadiness,
+}
+
+// Readiness of a resource. Workloads are ready when their rollout is complete, persistent volume claims when bound
+// and load balancer services when an ingress point is assigned. Other kinds are ready as soon as they exist.
+func (r *Resource) Readiness(name, kind string) (*Readiness, error) {
+	kind = strings.ToLower(kind)
+	output, err := r.context.get(name, kind)
+	if err != nil {
+		return nil, err
+	}
+	return r.parseReadiness(name, kind, output)
+}
+
+// WaitReady waits until a resource is ready or failed, calling progress every time its readiness changes.
+// A timeout <= 0 waits forever.
+func (r *Resource) WaitReady(name, kind string, timeout time.Duration, progress func(*Readiness)) (*Readiness, error) {
+	kind = strings.ToLower(kind)
+	var last *Readiness
+	err := r.context.waitUntil(name, kind, timeout, func(output []byte) (bool, error) {
+		readiness, err := r.parseReadiness(name, kind, output)
+		if err != nil {
+			return false, err
+		}
+		if last == nil || *readiness != *last {
+			progress(readiness)
+		}
+		last = readiness
+		return readiness.Ready || readiness.Failed, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+func (r *Resource) parseReadiness(name, kind string, output []byte) (*Readiness, error) {
+	if normalizeKind(kind) == "persistentvolumeclaim" {
+		return r.pvcReadiness(name, kind, output)
+	}
+	check, ok := readinessChecks[normalizeKind(kind)]
+	if !ok {
+		status, err := parseStatus(kind, output)
+		if err != nil {
+			return nil, err
+		}
+		switch status {
+		case RsStatusActive, RsStatusPending, RsStatusSucceeded, RsStatusFailed:
+			return &Readiness{Ready: true}, nil
+		case RsStatusNotExist, RsStatusTerminating:
+			return notExistReadiness(name, kind), nil
+		default:
+			return nil, stacktrace.Propagate(ErrUnknownStatus{name, kind, status}, "unknown status")
+		}
+	}
+	if output == nil {
+		return notExistReadiness(name, kind), nil
+	}
+	return check(output)
+}
+
+// pvcReadiness is the readiness of a bound claim, see pvcBoundCheck. A pending claim of a storage class binding volumes
+// on first consumer is ready too.
+func (r *Resource) pvcReadiness(name, kind string, output []byte) (*Readiness, error) {
+	if output == nil {
+		return notExistReadiness(name, kind), nil
+	}
+	pvcInfo, err := parsePVCInfo(output)
+	if err != nil {
+		return nil, err
+	}
+	readiness := pvcInfo.boundReadiness()
+	if readiness.Ready || readiness.Failed {
+		return readiness, nil
+	}
+	storageClass := ""
+	if pvcInfo.Spec != nil && pvcInfo.Spec.StorageClassName != nil {
+		storageClass = *pvcInfo.Spec.StorageClassName
+	}
+	bindingMode, err := r.context.volumeBindingMode(storageClass)
+	if err != nil {
+		return nil, err
+	}
+	if bindingMode == "WaitForFirstConsumer" {
+		// the claim stays pending until a pod uses it, which is likely to be in a depending group
+		return &Readiness{Ready: true}, nil
+	}
+	return readiness, nil
+}
+
+// volumeBindingMode of a storage class, or of the default storage class if the name is empty.
+// Storage classes are cluster scoped: when they cannot be read, the binding mode is assumed to be Immediate.
+func (c *Context) volumeBindingMode(storageClass string) (string, error) {
+	bindingMode, err := c.readVolumeBindingMode(storageClass)
+	if IsForbidden(err) || IsUnsupportedKind(err) {
+		c.warnOnce.Do(func() {
+			utils.Warnf(c.out, "Cannot read storage classes, assuming persistent volume claims are bound immediately: %s", stacktrace.RootCause(err))
+		})
+		return "", nil
+	}
+	return bindingMode, err
+}
+
+func (c *Context) readVolumeBindingMode(storageClass string) (string, error) {
+	if storageClass == "" {
+		items, err := c.backend.List(c.ctx, "", "storageclass", "")
+		if err != nil {
+			return "", err
+		}
+		for _, item := range items {
+			classInfo := &storageClassInfo{}
+			err = yaml.Unmarshal(item, classInfo)
+			if err != nil {
+				return "", stacktrace.Propagate(ErrInvalidResponse{err, string(item)}, "invalid response")
+			}
+			if classInfo.isDefault() {
+				return classInfo.VolumeBindingMode, nil
+			}
+		}
+		return "", nil
+	}
+	output, err := c.get(storageClass, "storageclass")
+	if err != nil || output == nil {
+		return "", err
+	}
+	classInfo := &storageClassInfo{}
+	err = yaml.Unmarshal(output, classInfo)
+	if err != nil {
+		return "", stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+	}
+	return classInfo.VolumeBindingMode, nil
+}
+
+func notExistReadiness(name, kind string) *Readiness {
+	return &Readiness{Message: fmt.Sprintf("Waiting for %s %q to be created...", kind, name)}
+}
+
+func deploymentReadiness(output []byte) (*Readiness, error) {
+	deploymentInfo := &deploymentResourceInfo{}
+	err := yaml.Unmarshal(output, deploymentInfo)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+	}
+	done, failed, message := deploymentInfo.rolloutStatus()
+	return &Readiness{done, failed, message}, nil
+}
+
+// statefulSetReadiness follows the same rules as kubectl rollout status
+func statefulSetReadiness(output []byte) (*Readiness, error) {
+	info := &statefulSetResourceInfo{}
+	err := yaml.Unmarshal(output, info)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+	}
+	if info.Metadata == nil || info.Status == nil || info.Status.ObservedGeneration == 0 || info.Metadata.Generation > info.Status.ObservedGeneration {
+		return &Readiness{Message: "Waiting for statefulset spec update to be observed..."}, nil
+	}
+	replicas := int32(1)
+	if info.Spec != nil && info.Spec.Replicas != nil {
+		replicas = *info.Spec.Replicas
+	}
+	if info.Status.ReadyReplicas < replicas {
+		return &Readiness{Message: fmt.Sprintf("Waiting for statefulset %q: %d of %d pods are ready...", info.Metadata.Name, info.Status.ReadyReplicas, replicas)}, nil
+	}
+	if info.Spec != nil && info.Spec.UpdateStrategy != nil {
+		strategy := info.Spec.UpdateStrategy
+		if strategy.Type == "OnDelete" {
+			return &Readiness{Ready: true, Message: fmt.Sprintf("statefulset %q is ready", info.Metadata.Name)}, nil
+		}
+		if strategy.RollingUpdate != nil && strategy.RollingUpdate.Partition != nil && *strategy.RollingUpdate.Partition > 0 {
+			expected := replicas - *strategy.RollingUpdate.Partition
+			if info.Status.UpdatedReplicas < expected {
+				return &Readiness{Message: fmt.Sprintf("Waiting for partitioned roll out of statefulset %q to finish: %d of %d new pods have been updated...", info.Metadata.Name, info.Status.UpdatedReplicas, expected)}, nil
+			}
+			return &Readiness{Ready: true, Message: fmt.Sprintf("partitioned roll out of statefulset %q complete", info.Metadata.Name)}, nil
+		}
+	}
+	if info.Status.UpdateRevision != info.Status.CurrentRevision {
+		return &Readiness{Message: fmt.Sprintf("Waiting for statefulset %q rolling update to complete: %d pods at revision %s...", info.Metadata.Name, info.Status.UpdatedReplicas, info.Status.UpdateRevision)}, nil
+	}
+	return &Readiness{Ready: true, Message: fmt.Sprintf("statefulset %q rolling update complete", info.Metadata.Name)}, nil
+}
+
+// daemonSetReadiness follows the same rules as kubectl rollout status
+func daemonSetReadiness(output []byte) (*Readiness, error) {
+	info := &daemonSetResourceInfo{}
+	err := yaml.Unmarshal(output, info)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+	}
+	if info.Metadata == nil || info.Status == nil || info.Metadata.Generation > info.Status.ObservedGeneration {
+		return &Readiness{Message: "Waiting for daemonset spec update to be observed..."}, nil
+	}
+	if info.Spec != nil && info.Spec.UpdateStrategy != nil && info.Spec.UpdateStrategy.Type == "OnDelete" {
+		return &Readiness{Ready: true, Message: fmt.Sprintf("daemonset %q is ready", info.Metadata.Name)}, nil
+	}
+	desired := info.Status.DesiredNumberScheduled
+	if info.Status.UpdatedNumberScheduled < desired {
+		return &Readiness{Message: fmt.Sprintf("Waiting for daemonset %q rollout to finish: %d out of %d new pods have been updated...", info.Metadata.Name, info.Status.UpdatedNumberScheduled, desired)}, nil
+	}
+	if info.Status.NumberAvailable < desired {
+		return &Readiness{Message: fmt.Sprintf("Waiting for daemonset %q rollout to finish: %d of %d updated pods are available...", info.Metadata.Name, info.Status.NumberAvailable, desired)}, nil
+	}
+	return &Readiness{Ready: true, Message: fmt.Sprintf("daemonset %q successfully rolled out", info.Metadata.Name)}, nil
+}
+
+func replicaSetReadiness(output []byte) (*Readiness, error) {
+	info := &replicaSetResourceInfo{}
+	err := yaml.Unmarshal(output, info)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+	}
+	if info.Metadata == nil || info.Status == nil || info.Metadata.Generation > info.Status.ObservedGeneration {
+		return &Readiness{Message: "Waiting for replicaset spec update to be observed..."}, nil
+	}
+	replicas := int32(1)
+	if info.Spec != nil && info.Spec.Replicas != nil {
+		replicas = *info.Spec.Replicas
+	}
+	if info.Status.AvailableReplicas < replicas {
+		return &Readiness{Message: fmt.Sprintf("Waiting for replicaset %q: %d of %d replicas are available...", info.Metadata.Name, info.Status.AvailableReplicas, replicas)}, nil
+	}
+	return &Readiness{Ready: true, Message: fmt.Sprintf("replicaset %q is ready", info.Metadata.Name)}, nil
+}
+
+// serviceReadiness waits for load balancers to get an ingress point, other services are ready right away
+func serviceReadiness(output []byte) (*Readiness, error) {
+	info := &serviceResourceInfo{}
+	err := yaml.Unmarshal(output, info)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+	}
+	if info.Metadata == nil || info.Spec == nil || info.Spec.Type != "LoadBalancer" {
+		return &Readiness{Ready: true}, nil
+	}
+	if info.Status == nil || info.Status.LoadBalancer == nil || len(info.Status.LoadBalancer.Ingress) == 0 {
+		return &Readiness{Message: fmt.Sprintf("Waiting for load balancer of service %q to be provisioned...", info.Metadata.Name)}, nil
+	}
+	return &Readiness{Ready: true, Message: fmt.Sprintf("load balancer of service %q is ready", info.Metadata.Name)}, nil
+}
+
+type workloadMetadata struct {
+	Name       string `yaml:"name"`
+	Generation int64  `yaml:"generation"`
+}
+
+type statefulSetResourceInfo struct {
+	Metadata *workloadMetadata  `yaml:"metadata"`
+	Spec     *statefulSetSpec   `yaml:"spec"`
+	Status   *statefulSetStatus `yaml:"status"`
+}
+
+type statefulSetSpec struct {
+	Replicas       *int32               `yaml:"replicas"`
+	UpdateStrategy *statefulSetStrategy `yaml:"updateStrategy"`
+}
+
+type statefulSetStrategy struct {
+	Type          string `yaml:"type"`
+	RollingUpdate *struct {
+		Partition *int32 `yaml:"partition"`
+	} `yaml:"rollingUpdate"`
+}
+
+type statefulSetStatus struct {
+	ObservedGeneration int64  `yaml:"observedGeneration"`
+	ReadyReplicas      int32  `yaml:"readyReplicas"`
+	UpdatedReplicas    int32  `yaml:"updatedReplicas"`
+	CurrentRevision    string `yaml:"currentRevision"`
+	UpdateRevision     string `yaml:"updateRevision"`
+}
+
+type daemonSetResourceInfo struct {
+	Metadata *workloadMetadata `yaml:"metadata"`
+	Spec     *daemonSetSpec    `yaml:"spec"`
+	Status   *daemonSetStatus  `yaml:"status"`
+}
+
+type daemonSetSpec struct {
+	UpdateStrategy *struct {
+		Type string `yaml:"type"`
+	} `yaml:"updateStrategy"`
+}
+
+type daemonSetStatus struct {
+	ObservedGeneration     int64 `yaml:"observedGeneration"`
+	DesiredNumberScheduled int32 `yaml:"desiredNumberScheduled"`
+	UpdatedNumberScheduled int32 `yaml:"updatedNumberScheduled"`
+	NumberAvailable        int32 `yaml:"numberAvailable"`
+}
+
+type replicaSetResourceInfo struct {
+	Metadata *workloadMetadata `yaml:"metadata"`
+	Spec     *deploymentSpec   `yaml:"spec"`
+	Status   *replicaSetStatus `yaml:"status"`
+}
+
+type replicaSetStatus struct {
+	ObservedGeneration int64 `yaml:"observedGeneration"`
+	AvailableReplicas  int32 `yaml:"availableReplicas"`
+}
+
+type pvcResourceInfo struct {
+	Metadata *struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec *struct {
+		StorageClassName *string `yaml:"storageClassName"`
+	} `yaml:"spec"`
+	Status *kubernetesResourceStatus `yaml:"status"`
+}
+
+func parsePVCInfo(output []byte) (*pvcResourceInfo, error) {
+	info := &pvcResourceInfo{}
+	err := yaml.Unmarshal(output, info)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+	}
+	return info, nil
+}
+
+// boundReadiness is ready once the claim is bound and failed once it lost its volume
+func (p *pvcResourceInfo) boundReadiness() *Readiness {
+	name := ""
+	if p.Metadata != nil {
+		name = p.Metadata.Name
+	}
+	phase := ""
+	if p.Status != nil {
+		phase = p.Status.Phase
+	}
+	switch phase {
+	case "Bound":
+		return &Readiness{Ready: true, Message: fmt.Sprintf("persistent volume claim %q is bound", name)}
+	case "Lost":
+		return &Readiness{Failed: true, Message: fmt.Sprintf("persistent volume claim %q lost its volume", name)}
+	default:
+		return &Readiness{Message: fmt.Sprintf("Waiting for persistent volume claim %q to be bound...", name)}
+	}
+}
+
+type storageClassInfo struct {
+	Metadata *struct {
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+	VolumeBindingMode string `yaml:"volumeBindingMode"`
+}
+
+func (s *storageClassInfo) isDefault() bool {
+	if s.Metadata == nil {
+		return false
+	}
+	return s.Metadata.Annotations["storageclass.kubernetes.io/is-default-class"] == "true" ||
+		s.Metadata.Annotations["storageclass.beta.kubernetes.io/is-default-class"] == "true"
+}
@@ -0,0 +1,164 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type ReadinessTestSuite struct {
+	suite.Suite
+	resourceRoot string
+	namespace    string
+	cluster      *FakeCluster
+	kubeContext  *Context
+}
+
+func (s *ReadinessTestSuite) SetupTest() {
+	s.resourceRoot = "../test-resources"
+	s.namespace = "readiness-ns"
+	s.cluster = NewFakeCluster()
+	s.kubeContext = NewContextWithBackend(s.namespace, s.cluster)
+	_, err := s.kubeContext.Namespace().Create()
+	require.Nil(s.T(), err)
+}
+
+func (s *ReadinessTestSuite) TestDeployment() {
+	s.cluster.TransitionReads = 1
+	s.create("deployment", "deployment", s.readResource("deployment.yml"))
+	s.verifyReadiness("deployment", "deployment", false, false)
+	s.verifyReadiness("deployment", "deploy", true, false)
+	s.cluster.SetOutcome(s.namespace, "deployment", "failed", FakeOutcomeFailed)
+	s.create("failed", "deployment", `{"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "failed"}, "spec": {"replicas": 2}}`)
+	s.verifyReadiness("failed", "deployment", false, true)
+	status, err := s.kubeContext.Resource().GetStatus("failed", "deployment")
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), RsStatusFailed, status)
+}
+
+func (s *ReadinessTestSuite) TestWorkloads() {
+	s.cluster.TransitionReads = 1
+	s.create("statefulset", "statefulset", s.readResource("statefulset.yml"))
+	s.create("daemonset", "daemonset", s.readResource("daemonset.yml"))
+	s.verifyStatus("statefulset", "statefulset", RsStatusPending)
+	s.verifyStatus("daemonset", "ds", RsStatusPending)
+	s.verifyReadiness("statefulset", "statefulsets", true, false)
+	s.verifyReadiness("daemonset", "daemonsets.apps", true, false)
+	s.verifyStatus("statefulset", "sts", RsStatusActive)
+}
+
+func (s *ReadinessTestSuite) TestStatefulSetPartition() {
+	readiness, err := statefulSetReadiness([]byte(`{"metadata": {"name": "sts", "generation": 2}, "spec": {"replicas": 3, "updateStrategy": {"type": "RollingUpdate", "rollingUpdate": {"partition": 1}}}, "status": {"observedGeneration": 2, "readyReplicas": 3, "updatedReplicas": 1, "currentRevision": "a", "updateRevision": "b"}}`))
+	require.Nil(s.T(), err)
+	require.False(s.T(), readiness.Ready)
+	readiness, err = statefulSetReadiness([]byte(`{"metadata": {"name": "sts", "generation": 2}, "spec": {"replicas": 3, "updateStrategy": {"type": "RollingUpdate", "rollingUpdate": {"partition": 1}}}, "status": {"observedGeneration": 2, "readyReplicas": 3, "updatedReplicas": 2, "currentRevision": "a", "updateRevision": "b"}}`))
+	require.Nil(s.T(), err)
+	require.True(s.T(), readiness.Ready)
+}
+
+func (s *ReadinessTestSuite) TestPersistentVolumeClaim() {
+	s.cluster.SetOutcome(s.namespace, "pvc", "immediate", FakeOutcomeStuck)
+	s.cluster.SetOutcome(s.namespace, "pvc", "first-consumer", FakeOutcomeStuck)
+	_, err := s.cluster.Apply(context.Background(), "", []byte(`{"apiVersion": "storage.k8s.io/v1", "kind": "StorageClass", "metadata": {"name": "local"}, "volumeBindingMode": "WaitForFirstConsumer"}`))
+	require.Nil(s.T(), err)
+	s.create("immediate", "pvc", `{"apiVersion": "v1", "kind": "PersistentVolumeClaim", "metadata": {"name": "immediate"}, "spec": {"accessModes": ["ReadWriteOnce"]}}`)
+	s.create("first-consumer", "pvc", `{"apiVersion": "v1", "kind": "PersistentVolumeClaim", "metadata": {"name": "first-consumer"}, "spec": {"storageClassName": "local"}}`)
+	s.create("bound", "pvc", `{"apiVersion": "v1", "kind": "PersistentVolumeClaim", "metadata": {"name": "bound"}, "spec": {"storageClassName": "fast"}}`)
+	s.verifyReadiness("immediate", "pvc", false, false)
+	s.verifyReadiness("first-consumer", "persistentvolumeclaim", true, false)
+	s.verifyReadiness("bound", "pvc", true, false)
+	s.verifyStatus("first-consumer", "pvc", RsStatusPending)
+	_, err = s.cluster.Apply(context.Background(), "", []byte(`{"apiVersion": "storage.k8s.io/v1", "kind": "StorageClass", "metadata": {"name": "default", "annotations": {"storageclass.kubernetes.io/is-default-class": "true"}}, "volumeBindingMode": "WaitForFirstConsumer"}`))
+	require.Nil(s.T(), err)
+	s.verifyReadiness("immediate", "pvc", true, false)
+}
+
+func (s *ReadinessTestSuite) TestPersistentVolumeClaimWithoutStorageClassAccess() {
+	s.cluster.SetOutcome(s.namespace, "pvc", "immediate", FakeOutcomeStuck)
+	s.cluster.SetOutcome(s.namespace, "pvc", "first-consumer", FakeOutcomeStuck)
+	_, err := s.cluster.Apply(context.Background(), "", []byte(`{"apiVersion": "storage.k8s.io/v1", "kind": "StorageClass", "metadata": {"name": "local"}, "volumeBindingMode": "WaitForFirstConsumer"}`))
+	require.Nil(s.T(), err)
+	s.create("immediate", "pvc", `{"apiVersion": "v1", "kind": "PersistentVolumeClaim", "metadata": {"name": "immediate"}}`)
+	s.create("first-consumer", "pvc", `{"apiVersion": "v1", "kind": "PersistentVolumeClaim", "metadata": {"name": "first-consumer"}, "spec": {"storageClassName": "local"}}`)
+	out := &bytes.Buffer{}
+	s.kubeContext = NewContextWithBackend(s.namespace, &storageClassForbiddenBackend{s.cluster}).WithOutput(out)
+	s.verifyReadiness("immediate", "pvc", false, false)
+	s.verifyReadiness("first-consumer", "pvc", false, false)
+	require.Equal(s.T(), 1, strings.Count(out.String(), "Cannot read storage classes"), out.String())
+	require.Contains(s.T(), out.String(), "forbidden")
+}
+
+func (s *ReadinessTestSuite) TestService() {
+	s.cluster.SetOutcome(s.namespace, "service", "pending-lb", FakeOutcomeStuck)
+	s.create("service", "service", `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "service"}, "spec": {"ports": [{"port": 80}]}}`)
+	s.create("lb", "service", `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "lb"}, "spec": {"type": "LoadBalancer", "ports": [{"port": 80}]}}`)
+	s.create("pending-lb", "service", `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "pending-lb"}, "spec": {"type": "LoadBalancer", "ports": [{"port": 80}]}}`)
+	s.verifyReadiness("service", "svc", true, false)
+	s.verifyReadiness("lb", "service", true, false)
+	s.verifyReadiness("pending-lb", "service", false, false)
+	s.verifyStatus("pending-lb", "service", RsStatusPending)
+}
+
+func (s *ReadinessTestSuite) TestOtherKinds() {
+	s.verifyReadiness("not-exists", "configmap", false, false)
+	s.verifyReadiness("not-exists", "deployment", false, false)
+	s.create("config-map", "configmap", `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "config-map"}}`)
+	s.verifyReadiness("config-map", "configmap", true, false)
+}
+
+func (s *ReadinessTestSuite) readResource(filename string) string {
+	content, err := ioutil.ReadFile(filepath.Join(s.resourceRoot, "resource-test", "pod-based", filename))
+	require.Nil(s.T(), err)
+	return string(content)
+}
+
+func (s *ReadinessTestSuite) create(name, kind, content string) {
+	exists, err := s.kubeContext.Resource().Create(name, kind, content)
+	require.Nil(s.T(), err)
+	require.False(s.T(), exists)
+}
+
+func (s *ReadinessTestSuite) verifyStatus(name, kind string, expected RsStatus) {
+	status, err := s.kubeContext.Resource().GetStatus(name, kind)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), expected, status)
+}
+
+func (s *ReadinessTestSuite) verifyReadiness(name, kind string, ready, failed bool) {
+	readiness, err := s.kubeContext.Resource().Readiness(name, kind)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), ready, readiness.Ready, readiness.Message)
+	require.Equal(s.T(), failed, readiness.Failed, readiness.Message)
+}
+
+// storageClassForbiddenBackend refuses to read storage classes, like a namespaced service account
+type storageClassForbiddenBackend struct {
+	*FakeCluster
+}
+
+func (b *storageClassForbiddenBackend) Get(ctx context.Context, namespace, kind, name string) ([]byte, error) {
+	if kind == "storageclass" {
+		return nil, stacktrace.Propagate(apierrors.NewForbidden(schema.GroupResource{Group: "storage.k8s.io", Resource: "storageclasses"}, name, nil), "kubernetes api error")
+	}
+	return b.FakeCluster.Get(ctx, namespace, kind, name)
+}
+
+func (b *storageClassForbiddenBackend) List(ctx context.Context, namespace, kind, selector string) ([][]byte, error) {
+	if kind == "storageclass" {
+		return nil, stacktrace.Propagate(apierrors.NewForbidden(schema.GroupResource{Group: "storage.k8s.io", Resource: "storageclasses"}, "", nil), "kubernetes api error")
+	}
+	return b.FakeCluster.List(ctx, namespace, kind, selector)
+}
+
+func TestReadiness(t *testing.T) {
+	suite.Run(t, new(ReadinessTestSuite))
+}
@@ -1,12 +1,11 @@
 package kubernetes
 
 import (
+	"fmt"
 	"io"
-	"io/ioutil"
 	"strings"
 	"time"
 
-	"github.com/anduintransaction/rivendell/utils"
 	"github.com/palantir/stacktrace"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -23,12 +22,25 @@ func (r *Resource) Create(name, kind, rawContent string) (exists bool, err error
 	if err != nil {
 		return false, err
 	}
+	apply, err := r.prepareCreate(name, kind, status)
+	if err != nil {
+		return false, err
+	}
+	if !apply {
+		return true, nil
+	}
+	err = r.apply(rawContent)
+	return false, err
+}
+
+// prepareCreate tells if a resource with the given status should be applied to be created,
+// waiting for a terminating resource to be gone
+func (r *Resource) prepareCreate(name, kind string, status RsStatus) (apply bool, err error) {
 	if status == RsStatusUnknown {
 		return false, stacktrace.Propagate(ErrUnknownStatus{name, kind, status}, "unknown status")
 	}
 	if status == RsStatusActive || status == RsStatusPending || status == RsStatusSucceeded || status == RsStatusFailed {
-		exists = true
-		return
+		return false, nil
 	}
 	if status == RsStatusTerminating {
 		err = r.waitForTerminating(name, kind)
@@ -36,20 +48,36 @@ func (r *Resource) Create(name, kind, rawContent string) (exists bool, err error
 			return false, err
 		}
 	}
-	exists = false
-	args := r.context.completeArgs([]string{"apply", "-f", "-"})
-	cmd := utils.NewCommand("kubectl", args...)
-	cmd.RedirectToStandard()
-	cmd.SetStdin([]byte(rawContent))
-	cmdResult, err := cmd.Run()
-	if err != nil {
-		return
-	}
-	if cmdResult.ExitCode != 0 {
-		err = ErrCommandExitCode{cmdResult.ExitCode}
-		return
-	}
-	return
+	return true, nil
+}
+
+// Manifest returns the live manifest of a resource, nil if it does not exist
+func (r *Resource) Manifest(name, kind string) ([]byte, error) {
+	return r.context.get(name, strings.ToLower(kind))
+}
+
+// List returns the live manifests of all resources of a kind matching a label selector
+func (r *Resource) List(kind, selector string) ([][]byte, error) {
+	kind = strings.ToLower(kind)
+	return r.context.backend.List(r.context.ctx, r.context.namespaceFor(kind), kind, selector)
+}
+
+// Put creates or updates the objects of a manifest without printing anything, for objects rivendell manages itself
+func (r *Resource) Put(rawContent string) error {
+	_, err := r.context.backend.Apply(r.context.ctx, r.context.namespace, []byte(rawContent))
+	return err
+}
+
+// PutNew creates the object of a manifest without printing anything and returns it, an existing object is reported with
+// ErrAlreadyExists
+func (r *Resource) PutNew(rawContent string) ([]byte, error) {
+	return r.context.backend.Create(r.context.ctx, r.context.namespace, []byte(rawContent))
+}
+
+// Replace updates the object of a manifest without printing anything and returns it. When the manifest holds a
+// resourceVersion, an object modified since that version is reported with ErrConflict
+func (r *Resource) Replace(rawContent string) ([]byte, error) {
+	return r.context.backend.Replace(r.context.ctx, r.context.namespace, []byte(rawContent))
 }
 
 // Exists check
@@ -84,18 +112,36 @@ func (r *Resource) Delete(name, kind string) (exists bool, err error) {
 		return
 	}
 	exists = true
-	args := r.context.completeArgs([]string{"delete", kind, name})
-	cmdResult, err := utils.ExecuteCommand("kubectl", args...)
-	if err != nil {
-		return
+	err = r.context.backend.Delete(r.context.ctx, r.context.namespaceFor(kind), kind, name)
+	if IsNotExist(err) {
+		return false, nil
 	}
-	if cmdResult.ExitCode != 0 {
-		err = ErrCommandExitCode{cmdResult.ExitCode}
+	if err != nil {
 		return
 	}
+	fmt.Fprintf(r.context.out, "%s %q deleted\n", kind, name)
 	return
 }
 
+// WaitDeleted waits until a resource does not exist anymore or is terminating. A timeout <= 0 waits forever.
+func (r *Resource) WaitDeleted(name, kind string, timeout time.Duration) error {
+	kind = strings.ToLower(kind)
+	return r.context.waitUntil(name, kind, timeout, func(output []byte) (bool, error) {
+		status, err := parseStatus(kind, output)
+		if err != nil {
+			return false, err
+		}
+		switch status {
+		case RsStatusNotExist, RsStatusTerminating:
+			return true, nil
+		case RsStatusUnknown:
+			return false, stacktrace.Propagate(ErrUnknownStatus{name, kind, status}, "unknown status")
+		default:
+			return false, nil
+		}
+	})
+}
+
 // UpdateStatus .
 type UpdateStatus int
 
@@ -116,26 +162,23 @@ func (r *Resource) Update(name, kind, rawContent string) (updateStatus UpdateSta
 	if err != nil {
 		return UpdateStatusNotExist, err
 	}
+	updateStatus, err = r.prepareUpdate(name, kind, status)
+	if err != nil || updateStatus != UpdateStatusExisted {
+		return updateStatus, err
+	}
+	err = r.apply(rawContent)
+	return
+}
+
+// prepareUpdate tells what updating a resource with the given status does, only existing resources are applied
+func (r *Resource) prepareUpdate(name, kind string, status RsStatus) (UpdateStatus, error) {
 	if status == RsStatusUnknown {
 		return UpdateStatusNotExist, stacktrace.Propagate(ErrUnknownStatus{name, kind, status}, "unknown status")
 	}
 	if status == RsStatusNotExist || status == RsStatusTerminating {
 		return UpdateStatusNotExist, nil
 	}
-	updateStatus = UpdateStatusExisted
-	args := r.context.completeArgs([]string{"apply", "-f", "-"})
-	cmd := utils.NewCommand("kubectl", args...)
-	cmd.RedirectToStandard()
-	cmd.SetStdin([]byte(rawContent))
-	cmdResult, err := cmd.Run()
-	if err != nil {
-		return
-	}
-	if cmdResult.ExitCode != 0 {
-		err = ErrCommandExitCode{cmdResult.ExitCode}
-		return
-	}
-	return
+	return UpdateStatusExisted, nil
 }
 
 // Upgrade .
@@ -145,73 +188,88 @@ func (r *Resource) Upgrade(name, kind, rawContent string) (updateStatus UpdateSt
 	if err != nil {
 		return UpdateStatusNotExist, err
 	}
+	updateStatus, err = r.prepareUpgrade(name, kind, status)
+	if err != nil || updateStatus == UpdateStatusSkipped {
+		return updateStatus, err
+	}
+	err = r.apply(rawContent)
+	return
+}
+
+// prepareUpgrade tells what upgrading a resource with the given status does, every resource but running pods and jobs
+// is applied. Finished pods and jobs are deleted first to be recreated.
+func (r *Resource) prepareUpgrade(name, kind string, status RsStatus) (UpdateStatus, error) {
 	if status == RsStatusUnknown {
 		return UpdateStatusNotExist, stacktrace.Propagate(ErrUnknownStatus{name, kind, status}, "unknown status")
 	}
 	if (kind == "pod" || kind == "job") && (status == RsStatusActive || status == RsStatusPending) {
 		return UpdateStatusSkipped, nil
 	}
+	updateStatus := UpdateStatusExisted
 	if status == RsStatusNotExist || status == RsStatusTerminating {
 		updateStatus = UpdateStatusNotExist
-	} else {
-		updateStatus = UpdateStatusExisted
 	}
 	if kind == "pod" || kind == "job" {
-		_, err = r.Delete(name, kind)
+		_, err := r.Delete(name, kind)
 		if err != nil {
 			return UpdateStatusNotExist, err
 		}
 	}
-	args := r.context.completeArgs([]string{"apply", "-f", "-"})
-	cmd := utils.NewCommand("kubectl", args...)
-	cmd.RedirectToStandard()
-	cmd.SetStdin([]byte(rawContent))
-	cmdResult, err := cmd.Run()
-	if err != nil {
-		return
-	}
-	if cmdResult.ExitCode != 0 {
-		err = ErrCommandExitCode{cmdResult.ExitCode}
-		return
-	}
-	return
+	return updateStatus, nil
 }
 
-// Wait .
+// Wait for a resource to complete: pods and jobs to succeed, workloads to roll out,
+// persistent volume claims to be bound and custom resource definitions to be established
 func (r *Resource) Wait(name, kind string) (success bool, err error) {
+	return r.WaitFor(name, kind, "")
+}
+
+// WaitFor waits for a resource to reach a condition, see parseWaitCondition for the syntax.
+// An empty condition waits like Wait.
+func (r *Resource) WaitFor(name, kind, condition string) (success bool, err error) {
 	kind = strings.ToLower(kind)
-	switch kind {
-	case "pod":
-		fallthrough
-	case "job":
-		return r.waitByObjStatus(name, kind)
-	case "deploy":
-		fallthrough
-	case "deployment":
-		return r.waitByRolloutStatus(name, kind)
-	default:
+	if condition != "" {
+		check, err := parseWaitCondition(condition)
+		if err != nil {
+			return false, err
+		}
+		return r.waitByCheck(name, kind, check)
+	}
+	normalizedKind := normalizeKind(kind)
+	switch normalizedKind {
+	case "pod", "job":
+		return r.waitByObjStatus(name, normalizedKind)
+	}
+	check, ok := waitChecks[normalizedKind]
+	if !ok {
 		return false, stacktrace.Propagate(ErrUnsupportedKind{kind}, "unsupported kind")
 	}
+	return r.waitByCheck(name, kind, check)
 }
 
-func (r *Resource) waitByRolloutStatus(name, kind string) (bool, error) {
-	args := r.context.completeArgs([]string{"rollout", "status", kind, name})
-	cmd := utils.NewCommand("kubectl", args...)
-	cmd.RedirectToStandard()
-	cmdResult, err := cmd.Run()
-	if err != nil {
-		return false, err
-	}
-	if cmdResult.ExitCode != 0 {
-		return false, ErrCommandExitCode{cmdResult.ExitCode}
-	}
-	return true, nil
+func (r *Resource) waitByCheck(name, kind string, check waitCheck) (success bool, err error) {
+	lastMessage := ""
+	err = r.context.waitUntil(name, kind, 0, func(output []byte) (bool, error) {
+		if output == nil {
+			return false, stacktrace.Propagate(ErrNotExist{name, kind}, "not exist")
+		}
+		readiness, err := check(output)
+		if err != nil {
+			return false, err
+		}
+		if readiness.Message != lastMessage {
+			fmt.Fprintln(r.context.out, readiness.Message)
+			lastMessage = readiness.Message
+		}
+		success = readiness.Ready
+		return readiness.Ready || readiness.Failed, nil
+	})
+	return
 }
 
-func (r *Resource) waitByObjStatus(name, kind string) (bool, error) {
-	waitDelay := 5 * time.Second
-	for {
-		status, err := r.GetStatus(name, kind)
+func (r *Resource) waitByObjStatus(name, kind string) (success bool, err error) {
+	err = r.context.waitUntil(name, kind, 0, func(output []byte) (bool, error) {
+		status, err := parseStatus(kind, output)
 		if err != nil {
 			return false, err
 		}
@@ -219,15 +277,17 @@ func (r *Resource) waitByObjStatus(name, kind string) (bool, error) {
 		case RsStatusNotExist:
 			return false, stacktrace.Propagate(ErrNotExist{name, kind}, "not exist")
 		case RsStatusActive, RsStatusPending, RsStatusTerminating:
-			time.Sleep(waitDelay)
+			return false, r.checkPodFailures(name, kind, output)
 		case RsStatusSucceeded:
+			success = true
 			return true, nil
 		case RsStatusFailed:
-			return false, nil
+			return true, nil
 		default:
 			return false, stacktrace.Propagate(ErrUnknownStatus{name, kind, status}, "unknown status")
 		}
-	}
+	})
+	return
 }
 
 // Logs .
@@ -254,64 +314,51 @@ func (r *Resource) Logs(name, containerName string, stdout io.Writer, stderr io.
 			return err
 		}
 	}
-	firstRun := true
+	opts := &LogOptions{
+		Container: containerName,
+		Follow:    true,
+	}
 	for {
-		var args []string
-		if firstRun {
-			args = r.context.completeArgs([]string{"logs", "-f", "-c", containerName, name})
-			firstRun = false
-		} else {
-			args = r.context.completeArgs([]string{"logs", "-f", "-c", containerName, "--tail", "10", name})
+		err := r.context.backend.Logs(r.context.ctx, r.context.namespace, name, opts, stdout, stderr)
+		if err == nil {
+			break
 		}
-		cmd := utils.NewCommand("kubectl", args...)
-		cmd.SetStdout(stdout)
-		cmd.SetStderr(stderr)
-		cmdResult, err := cmd.Run()
-		if err != nil {
+		if IsNotExist(err) || r.context.ctx.Err() != nil {
 			return err
 		}
-		if cmdResult.ExitCode == 0 {
-			break
-		}
+		// the log stream was interrupted, resume with the last few lines
+		opts.Tail = 10
 	}
 	return nil
 }
 
 // GetStatus .
 func (r *Resource) GetStatus(name, kind string) (RsStatus, error) {
+	output, err := r.context.get(name, kind)
+	if err != nil {
+		return RsStatusUnknown, err
+	}
+	return parseStatus(kind, output)
+}
+
+// parseStatus returns the status of an object from its manifest, nil meaning the object does not exist
+func parseStatus(kind string, output []byte) (RsStatus, error) {
 	switch kind {
 	case "pod":
-		return r.getPodStatus(name)
+		return parsePodStatus(output)
 	case "job":
-		return r.getJobStatus(name)
+		return parseJobStatus(output)
 	default:
-		return r.context.getNonPodStatus(name, kind)
+		return parseNonPodStatus(kind, output)
 	}
 }
 
-func (r *Resource) getPodStatus(name string) (RsStatus, error) {
-	args := r.context.completeArgs([]string{"get", "pod", name, "-o", "yaml"})
-	cmdResult, err := utils.NewCommand("kubectl", args...).Run()
-	if err != nil {
-		return RsStatusUnknown, err
-	}
-	if cmdResult.ExitCode != 0 {
-		output, err := ioutil.ReadAll(cmdResult.Stderr)
-		if err != nil {
-			return RsStatusUnknown, stacktrace.Propagate(err, "cannot read stderr")
-		}
-		errOutput := string(output)
-		if strings.Contains(errOutput, "(NotFound)") {
-			return RsStatusNotExist, nil
-		}
-		return RsStatusUnknown, stacktrace.Propagate(ErrCommandExecute{cmdResult.ExitCode, errOutput}, "error execute command")
-	}
-	output, err := ioutil.ReadAll(cmdResult.Stdout)
-	if err != nil {
-		return RsStatusUnknown, stacktrace.Propagate(err, "cannot read stdout")
+func parsePodStatus(output []byte) (RsStatus, error) {
+	if output == nil {
+		return RsStatusNotExist, nil
 	}
 	podInfo := &podResourceInfo{}
-	err = yaml.Unmarshal(output, podInfo)
+	err := yaml.Unmarshal(output, podInfo)
 	if err != nil {
 		return RsStatusUnknown, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
 	}
@@ -338,26 +385,12 @@ func (r *Resource) getPodStatus(name string) (RsStatus, error) {
 	}
 }
 
-func (r *Resource) getJobStatus(name string) (RsStatus, error) {
-	args := r.context.completeArgs([]string{"get", "job", name, "-o", "yaml"})
-	cmdResult, err := utils.NewCommand("kubectl", args...).Run()
-	if err != nil {
-		return RsStatusUnknown, err
-	}
-	if cmdResult.ExitCode != 0 {
-		output, _ := ioutil.ReadAll(cmdResult.Stderr)
-		errOutput := string(output)
-		if strings.Contains(errOutput, "(NotFound)") {
-			return RsStatusNotExist, nil
-		}
-		return RsStatusUnknown, stacktrace.Propagate(ErrCommandExecute{cmdResult.ExitCode, errOutput}, "error execute command")
-	}
-	output, err := ioutil.ReadAll(cmdResult.Stdout)
-	if err != nil {
-		return RsStatusUnknown, stacktrace.Propagate(err, "cannot read stdout")
+func parseJobStatus(output []byte) (RsStatus, error) {
+	if output == nil {
+		return RsStatusNotExist, nil
 	}
 	jobInfo := &jobResourceInfo{}
-	err = yaml.Unmarshal(output, jobInfo)
+	err := yaml.Unmarshal(output, jobInfo)
 	if err != nil {
 		return RsStatusUnknown, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
 	}
@@ -378,47 +411,11 @@ func (r *Resource) getJobStatus(name string) (RsStatus, error) {
 }
 
 func (r *Resource) waitForPending(name, kind string) error {
-	check := 0
-	for {
-		status, err := r.GetStatus(name, kind)
-		if err != nil {
-			return err
-		}
-		switch status {
-		case RsStatusPending:
-			time.Sleep(defaultPendingInterval)
-			check++
-			if check > defaultPendingCheckLimit {
-				return ErrTimeout{}
-			}
-		case RsStatusUnknown:
-			return stacktrace.Propagate(ErrUnknownStatus{name, kind, status}, "unknown status")
-		default:
-			return nil
-		}
-	}
+	return r.context.waitWhile(name, kind, RsStatusPending)
 }
 
 func (r *Resource) waitForTerminating(name, kind string) error {
-	check := 0
-	for {
-		status, err := r.GetStatus(name, kind)
-		if err != nil {
-			return err
-		}
-		switch status {
-		case RsStatusTerminating:
-			time.Sleep(defaultTerminateInterval)
-			check++
-			if check > defaultTerminateCheckLimit {
-				return stacktrace.Propagate(ErrTimeout{}, "timeout waiting for terminating %s %q", kind, name)
-			}
-		case RsStatusUnknown:
-			return stacktrace.Propagate(ErrUnknownStatus{name, kind, status}, "unknown status")
-		default:
-			return nil
-		}
-	}
+	return r.context.waitWhile(name, kind, RsStatusTerminating)
 }
 
 func (r *Resource) getFirstContainerName(kind, name string) (containerName string, err error) {
@@ -432,33 +429,60 @@ func (r *Resource) getFirstContainerName(kind, name string) (containerName strin
 }
 
 func (r *Resource) getFirstContainerNameFromPod(name string) (containerName string, err error) {
-	args := r.context.completeArgs([]string{"get", "pod", name, "-o", "jsonpath={.spec.containers[0].name}"})
-	cmdResult, err := utils.NewCommand("kubectl", args...).Run()
+	output, err := r.context.get(name, "pod")
 	if err != nil {
 		return "", err
 	}
-	if cmdResult.ExitCode != 0 {
-		errOutput, _ := ioutil.ReadAll(cmdResult.Stderr)
-		return "", stacktrace.Propagate(ErrCommandExecute{cmdResult.ExitCode, string(errOutput)}, "error execute command")
+	if output == nil {
+		return "", stacktrace.Propagate(ErrNotExist{name, "pod"}, "not exist")
 	}
-	output, err := ioutil.ReadAll(cmdResult.Stdout)
+	podInfo := &podResourceInfo{}
+	err = yaml.Unmarshal(output, podInfo)
 	if err != nil {
-		return "", stacktrace.Propagate(err, "cannot read stdout")
+		return "", stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
 	}
-	return strings.TrimSpace(string(output)), nil
+	if podInfo.Spec == nil || len(podInfo.Spec.Containers) == 0 {
+		return "", nil
+	}
+	return podInfo.Spec.Containers[0].Name, nil
+}
+
+func (r *Resource) apply(rawContent string) error {
+	results, err := r.context.backend.Apply(r.context.ctx, r.context.namespace, []byte(rawContent))
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		fmt.Fprintf(r.context.out, "%s/%s %s\n", result.Kind, result.Name, result.Action)
+	}
+	return nil
 }
 
 type podResourceInfo struct {
 	Metadata *podMetadata `yaml:"metadata"`
+	Spec     *podSpec     `yaml:"spec"`
 	Status   *podStatus   `yaml:"status"`
 }
 
+type podSpec struct {
+	InitContainers []*podContainer `yaml:"initContainers"`
+	Containers     []*podContainer `yaml:"containers"`
+}
+
+type podContainer struct {
+	Name string `yaml:"name"`
+}
+
 type podMetadata struct {
+	Name              string `yaml:"name"`
 	DeletionTimestamp string `yaml:"deletionTimestamp"`
 }
 
 type podStatus struct {
-	Phase string `yaml:"phase"`
+	Phase                 string                `yaml:"phase"`
+	Conditions            []*podCondition       `yaml:"conditions"`
+	InitContainerStatuses []*podContainerStatus `yaml:"initContainerStatuses"`
+	ContainerStatuses     []*podContainerStatus `yaml:"containerStatuses"`
 }
 
 type jobResourceInfo struct {
@@ -472,3 +496,57 @@ type jobStatus struct {
 type jobCondition struct {
 	Type string `yaml:"type"`
 }
+
+type deploymentResourceInfo struct {
+	Metadata *deploymentMetadata `yaml:"metadata"`
+	Spec     *deploymentSpec     `yaml:"spec"`
+	Status   *deploymentStatus   `yaml:"status"`
+}
+
+type deploymentMetadata struct {
+	Name       string `yaml:"name"`
+	Generation int64  `yaml:"generation"`
+}
+
+type deploymentSpec struct {
+	Replicas *int32 `yaml:"replicas"`
+}
+
+type deploymentStatus struct {
+	ObservedGeneration int64                  `yaml:"observedGeneration"`
+	Replicas           int32                  `yaml:"replicas"`
+	UpdatedReplicas    int32                  `yaml:"updatedReplicas"`
+	AvailableReplicas  int32                  `yaml:"availableReplicas"`
+	Conditions         []*deploymentCondition `yaml:"conditions"`
+}
+
+type deploymentCondition struct {
+	Type   string `yaml:"type"`
+	Reason string `yaml:"reason"`
+}
+
+// rolloutStatus follows the same rules as kubectl rollout status
+func (d *deploymentResourceInfo) rolloutStatus() (done bool, failed bool, message string) {
+	if d.Metadata == nil || d.Status == nil || d.Metadata.Generation > d.Status.ObservedGeneration {
+		return false, false, "Waiting for deployment spec update to be observed..."
+	}
+	for _, condition := range d.Status.Conditions {
+		if condition.Type == "Progressing" && condition.Reason == "ProgressDeadlineExceeded" {
+			return false, true, fmt.Sprintf("deployment %q exceeded its progress deadline", d.Metadata.Name)
+		}
+	}
+	replicas := int32(1)
+	if d.Spec != nil && d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	if d.Status.UpdatedReplicas < replicas {
+		return false, false, fmt.Sprintf("Waiting for deployment %q rollout to finish: %d out of %d new replicas have been updated...", d.Metadata.Name, d.Status.UpdatedReplicas, replicas)
+	}
+	if d.Status.Replicas > d.Status.UpdatedReplicas {
+		return false, false, fmt.Sprintf("Waiting for deployment %q rollout to finish: %d old replicas are pending termination...", d.Metadata.Name, d.Status.Replicas-d.Status.UpdatedReplicas)
+	}
+	if d.Status.AvailableReplicas < d.Status.UpdatedReplicas {
+		return false, false, fmt.Sprintf("Waiting for deployment %q rollout to finish: %d of %d updated replicas are available...", d.Metadata.Name, d.Status.AvailableReplicas, d.Status.UpdatedReplicas)
+	}
+	return true, false, fmt.Sprintf("deployment %q successfully rolled out", d.Metadata.Name)
+}
@@ -1,11 +1,8 @@
 package kubernetes
 
 import (
-	"bufio"
-	"io/ioutil"
 	"strings"
 
-	"github.com/anduintransaction/rivendell/utils"
 	"github.com/palantir/stacktrace"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -15,6 +12,7 @@ type Service struct {
 	context *Context
 }
 
+// ListPods returns the names of all pods selected by a service
 func (s *Service) ListPods(name string) ([]string, error) {
 	status, err := s.context.getNonPodStatus(name, "service")
 	if err != nil {
@@ -24,16 +22,13 @@ func (s *Service) ListPods(name string) ([]string, error) {
 		// We ignore errors here.
 		return nil, nil
 	}
-	cmdResult, err := utils.NewCommand("kubectl", s.context.completeArgs([]string{"get", "services", name, "-o", "yaml"})...).Run()
+	output, err := s.context.get(name, "service")
 	if err != nil {
 		return nil, err
 	}
-	if cmdResult.ExitCode != 0 {
-		output, _ := ioutil.ReadAll(cmdResult.Stderr)
-		errOutput := string(output)
-		return nil, stacktrace.Propagate(ErrCommandExecute{cmdResult.ExitCode, errOutput}, "error execute command")
+	if output == nil {
+		return nil, nil
 	}
-	output, _ := ioutil.ReadAll(cmdResult.Stdout)
 	serviceInfo := &serviceResourceInfo{}
 	err = yaml.Unmarshal(output, serviceInfo)
 	if err != nil {
@@ -46,28 +41,37 @@ func (s *Service) ListPods(name string) ([]string, error) {
 	for k, v := range serviceInfo.Spec.Selector {
 		selectors = append(selectors, k+"="+v)
 	}
-	args := []string{"get", "pod", "-l", strings.Join(selectors, ","), "-o", "name"}
-	cmdResult, err = utils.NewCommand("kubectl", s.context.completeArgs(args)...).Run()
+	items, err := s.context.backend.List(s.context.ctx, s.context.namespace, "pod", strings.Join(selectors, ","))
 	if err != nil {
 		return nil, err
 	}
-	if cmdResult.ExitCode != 0 {
-		output, _ := ioutil.ReadAll(cmdResult.Stderr)
-		errOutput := string(output)
-		return nil, stacktrace.Propagate(ErrCommandExecute{cmdResult.ExitCode, errOutput}, "error execute command")
-	}
 	pods := []string{}
-	scanner := bufio.NewScanner(cmdResult.Stdout)
-	for scanner.Scan() {
-		pods = append(pods, strings.TrimPrefix(scanner.Text(), "pod/"))
+	for _, item := range items {
+		podInfo := &podResourceInfo{}
+		err = yaml.Unmarshal(item, podInfo)
+		if err != nil {
+			return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(item)}, "invalid response")
+		}
+		if podInfo.Metadata != nil {
+			pods = append(pods, podInfo.Metadata.Name)
+		}
 	}
 	return pods, nil
 }
 
 type serviceResourceInfo struct {
-	Spec *serviceSpec `yaml:"spec"`
+	Metadata *podMetadata   `yaml:"metadata"`
+	Spec     *serviceSpec   `yaml:"spec"`
+	Status   *serviceStatus `yaml:"status"`
 }
 
 type serviceSpec struct {
+	Type     string            `yaml:"type"`
 	Selector map[string]string `yaml:"selector"`
 }
+
+type serviceStatus struct {
+	LoadBalancer *struct {
+		Ingress []interface{} `yaml:"ingress"`
+	} `yaml:"loadBalancer"`
+}
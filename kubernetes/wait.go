@@ -0,0 +1,157 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/palantir/stacktrace"
+	yaml "gopkg.in/yaml.v2"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// waitCheck tells if an object reached the state a wait is looking for
+type waitCheck func(output []byte) (*Readiness, error)
+
+// ValidateWaitCondition checks the syntax of a wait condition
+func ValidateWaitCondition(condition string) error {
+	_, err := parseWaitCondition(condition)
+	return err
+}
+
+// parseWaitCondition parses conditions in the same forms as kubectl wait --for:
+// condition=Ready, condition=Ready=False or jsonpath={.status.phase}=Running
+func parseWaitCondition(condition string) (waitCheck, error) {
+	segments := strings.SplitN(condition, "=", 2)
+	if len(segments) != 2 || segments[1] == "" {
+		return nil, stacktrace.Propagate(ErrInvalidWaitCondition{condition}, "invalid wait condition")
+	}
+	switch strings.ToLower(segments[0]) {
+	case "condition":
+		conditionSegments := strings.SplitN(segments[1], "=", 2)
+		expected := "True"
+		if len(conditionSegments) == 2 {
+			expected = conditionSegments[1]
+		}
+		return conditionCheck(conditionSegments[0], expected), nil
+	case "jsonpath":
+		return parseJSONPathCheck(condition, segments[1])
+	default:
+		return nil, stacktrace.Propagate(ErrInvalidWaitCondition{condition}, "invalid wait condition")
+	}
+}
+
+func parseJSONPathCheck(condition, expression string) (waitCheck, error) {
+	end := strings.LastIndex(expression, "}")
+	if end < 0 {
+		return nil, stacktrace.Propagate(ErrInvalidWaitCondition{condition}, "invalid wait condition")
+	}
+	path := strings.Trim(expression[:end+1], "'\"")
+	rest := expression[end+1:]
+	rest = strings.TrimPrefix(rest, "'")
+	rest = strings.TrimPrefix(rest, "\"")
+	hasValue := strings.HasPrefix(rest, "=")
+	if rest != "" && !hasValue {
+		return nil, stacktrace.Propagate(ErrInvalidWaitCondition{condition}, "invalid wait condition")
+	}
+	expected := strings.Trim(strings.TrimPrefix(rest, "="), "'\"")
+	parser := jsonpath.New("wait").AllowMissingKeys(true)
+	err := parser.Parse(path)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrInvalidWaitCondition{condition}, "invalid jsonpath: %s", err)
+	}
+	return func(output []byte) (*Readiness, error) {
+		var data interface{}
+		err := json.Unmarshal(output, &data)
+		if err != nil {
+			return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+		}
+		buf := &bytes.Buffer{}
+		err = parser.Execute(buf, data)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot evaluate jsonpath %s", path)
+		}
+		value := buf.String()
+		if (hasValue && value == expected) || (!hasValue && value != "") {
+			return &Readiness{Ready: true, Message: fmt.Sprintf("%s is %q", path, value)}, nil
+		}
+		if !hasValue {
+			return &Readiness{Message: fmt.Sprintf("Waiting for %s to be set...", path)}, nil
+		}
+		return &Readiness{Message: fmt.Sprintf("Waiting for %s to be %q, currently %q...", path, expected, value)}, nil
+	}, nil
+}
+
+// conditionCheck waits for a status condition, values are compared case insensitively
+func conditionCheck(conditionType, expected string) waitCheck {
+	return func(output []byte) (*Readiness, error) {
+		info := &conditionResourceInfo{}
+		err := yaml.Unmarshal(output, info)
+		if err != nil {
+			return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+		}
+		current := "Unknown"
+		if info.Status != nil {
+			for _, condition := range info.Status.Conditions {
+				if strings.EqualFold(condition.Type, conditionType) {
+					current = condition.Status
+				}
+			}
+		}
+		if strings.EqualFold(current, expected) {
+			return &Readiness{Ready: true, Message: fmt.Sprintf("condition %s is %s", conditionType, current)}, nil
+		}
+		return &Readiness{Message: fmt.Sprintf("Waiting for condition %s to be %s, currently %s...", conditionType, expected, current)}, nil
+	}
+}
+
+// pvcBoundCheck waits for a persistent volume claim to be bound
+func pvcBoundCheck(output []byte) (*Readiness, error) {
+	info, err := parsePVCInfo(output)
+	if err != nil {
+		return nil, err
+	}
+	return info.boundReadiness(), nil
+}
+
+// crdEstablishedCheck waits for a custom resource definition to be served
+func crdEstablishedCheck(output []byte) (*Readiness, error) {
+	info := &conditionResourceInfo{}
+	err := yaml.Unmarshal(output, info)
+	if err != nil {
+		return nil, stacktrace.Propagate(ErrInvalidResponse{err, string(output)}, "invalid response")
+	}
+	if info.Status != nil {
+		for _, condition := range info.Status.Conditions {
+			if condition.Type == "NamesAccepted" && condition.Status == "False" {
+				return &Readiness{Failed: true, Message: fmt.Sprintf("custom resource definition names are not accepted: %s", condition.Message)}, nil
+			}
+		}
+	}
+	return conditionCheck("Established", "True")(output)
+}
+
+// waitChecks are used by waits without an explicit condition
+var waitChecks = map[string]waitCheck{
+	"deployment":               deploymentReadiness,
+	"statefulset":              statefulSetReadiness,
+	"daemonset":                daemonSetReadiness,
+	"replicaset":               replicaSetReadiness,
+	"persistentvolumeclaim":    pvcBoundCheck,
+	"customresourcedefinition": crdEstablishedCheck,
+}
+
+type conditionResourceInfo struct {
+	Status *conditionStatus `yaml:"status"`
+}
+
+type conditionStatus struct {
+	Conditions []*statusCondition `yaml:"conditions"`
+}
+
+type statusCondition struct {
+	Type    string `yaml:"type"`
+	Status  string `yaml:"status"`
+	Message string `yaml:"message"`
+}
@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type WaitTestSuite struct {
+	suite.Suite
+	resourceRoot string
+	namespace    string
+	cluster      *FakeCluster
+	kubeContext  *Context
+}
+
+func (s *WaitTestSuite) SetupTest() {
+	s.resourceRoot = "../test-resources"
+	s.namespace = "wait-ns"
+	s.cluster = NewFakeCluster()
+	s.kubeContext = NewContextWithBackend(s.namespace, s.cluster)
+	_, err := s.kubeContext.Namespace().Create()
+	require.Nil(s.T(), err)
+}
+
+func (s *WaitTestSuite) TestParseWaitCondition() {
+	validConditions := []string{
+		"condition=Ready",
+		"condition=Available=False",
+		"jsonpath={.status.phase}=Running",
+		"jsonpath='{.status.phase}'=Running",
+		"jsonpath={.status.loadBalancer.ingress[0].ip}",
+	}
+	for _, condition := range validConditions {
+		require.Nil(s.T(), ValidateWaitCondition(condition), condition)
+	}
+	invalidConditions := []string{
+		"Ready",
+		"condition=",
+		"delete=true",
+		"jsonpath=.status.phase",
+		"jsonpath={.status.phase}Running",
+		"jsonpath={.status.phase[}=Running",
+	}
+	for _, condition := range invalidConditions {
+		err := ValidateWaitCondition(condition)
+		require.NotNil(s.T(), err, condition)
+		_, ok := stacktrace.RootCause(err).(ErrInvalidWaitCondition)
+		require.True(s.T(), ok, condition)
+	}
+}
+
+func (s *WaitTestSuite) TestChecks() {
+	output := []byte(`{"status": {"phase": "Running", "replicas": 2, "conditions": [{"type": "Ready", "status": "True"}, {"type": "Progressing", "status": "False"}]}}`)
+	expectations := map[string]bool{
+		"condition=Ready":                  true,
+		"condition=ready=true":             true,
+		"condition=Progressing":            false,
+		"condition=Progressing=False":      true,
+		"condition=Missing":                false,
+		"jsonpath={.status.phase}=Running": true,
+		"jsonpath={.status.phase}=Pending": false,
+		"jsonpath={.status.replicas}=2":    true,
+		"jsonpath={.status.phase}":         true,
+		"jsonpath={.status.missing}":       false,
+	}
+	for condition, expected := range expectations {
+		check, err := parseWaitCondition(condition)
+		require.Nil(s.T(), err)
+		readiness, err := check(output)
+		require.Nil(s.T(), err)
+		require.Equal(s.T(), expected, readiness.Ready, condition)
+	}
+}
+
+func (s *WaitTestSuite) TestWaitKinds() {
+	s.create("deployment", "deployment", s.readResource(filepath.Join("pod-based", "deployment.yml")))
+	s.create("statefulset", "statefulset", s.readResource(filepath.Join("pod-based", "statefulset.yml")))
+	s.create("daemonset", "daemonset", s.readResource(filepath.Join("pod-based", "daemonset.yml")))
+	s.create("claim", "pvc", `{"apiVersion": "v1", "kind": "PersistentVolumeClaim", "metadata": {"name": "claim"}}`)
+	s.create("crontabs.stable.example.com", "crd", `{"apiVersion": "apiextensions.k8s.io/v1", "kind": "CustomResourceDefinition", "metadata": {"name": "crontabs.stable.example.com"}}`)
+	s.wait("deployment", "deploy", "", true)
+	s.wait("statefulset", "sts", "", true)
+	s.wait("daemonset", "daemonsets", "", true)
+	s.wait("claim", "persistentvolumeclaim", "", true)
+	s.wait("crontabs.stable.example.com", "customresourcedefinitions", "", true)
+	_, err := s.kubeContext.Resource().Wait("config-map", "configmap")
+	_, ok := stacktrace.RootCause(err).(ErrUnsupportedKind)
+	require.True(s.T(), ok)
+}
+
+func (s *WaitTestSuite) TestWaitForCondition() {
+	s.create("happy", "pod", s.readResource(filepath.Join("pod", "happy.yml")))
+	s.wait("happy", "pod", "condition=Ready", true)
+	s.wait("happy", "pod", "jsonpath={.status.phase}=Running", true)
+	s.create("success", "job", s.readResource(filepath.Join("job", "success.yml")))
+	s.wait("success", "job", "jsonpath='{.status.succeeded}'=1", true)
+	s.create("config-map", "configmap", s.readResource(filepath.Join("static", "config-map.yml")))
+	s.wait("config-map", "configmap", "jsonpath={.metadata.name}=config-map", true)
+	_, err := s.kubeContext.Resource().WaitFor("not-exists", "configmap", "condition=Ready")
+	require.True(s.T(), IsNotExist(err))
+}
+
+func (s *WaitTestSuite) readResource(filename string) string {
+	content, err := ioutil.ReadFile(filepath.Join(s.resourceRoot, "resource-test", filename))
+	require.Nil(s.T(), err)
+	return string(content)
+}
+
+func (s *WaitTestSuite) create(name, kind, content string) {
+	exists, err := s.kubeContext.Resource().Create(name, kind, content)
+	require.Nil(s.T(), err)
+	require.False(s.T(), exists)
+}
+
+func (s *WaitTestSuite) wait(name, kind, condition string, expectedSuccess bool) {
+	success, err := s.kubeContext.Resource().WaitFor(name, kind, condition)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), expectedSuccess, success)
+}
+
+func TestWait(t *testing.T) {
+	suite.Run(t, new(WaitTestSuite))
+}
@@ -0,0 +1,113 @@
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/palantir/stacktrace"
+)
+
+// WaitOptions tune the wait layer. Waits follow a watch of the object, and poll it with an exponential backoff
+// from Interval up to MaxInterval when the backend cannot watch or to catch changes of related objects.
+type WaitOptions struct {
+	Interval    time.Duration
+	MaxInterval time.Duration
+	// TransitionTimeout bounds waits on transient statuses: pending pods and terminating objects
+	TransitionTimeout time.Duration
+}
+
+// DefaultWaitOptions .
+var DefaultWaitOptions = &WaitOptions{
+	Interval:          time.Second,
+	MaxInterval:       10 * time.Second,
+	TransitionTimeout: 2 * time.Minute,
+}
+
+// waitFunc is called with the manifest of the waited object, or nil if the object does not exist,
+// every time it may have changed. It returns true once waiting is over.
+type waitFunc func(output []byte) (bool, error)
+
+// waitUntil calls fn with the current manifest of an object, then on every change, until fn returns true.
+// A timeout <= 0 waits forever.
+func (c *Context) waitUntil(name, kind string, timeout time.Duration, fn waitFunc) error {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	var events <-chan *WatchEvent
+	watcher, err := c.backend.Watch(c.ctx, c.namespaceFor(kind), kind, name)
+	if err == nil {
+		defer watcher.Stop()
+		events = watcher.Events()
+	}
+	interval := c.waitOptions.Interval
+	output, err := c.get(name, kind)
+	if err != nil {
+		return err
+	}
+	for {
+		done, err := fn(output)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		poll := time.NewTimer(interval)
+		select {
+		case event, ok := <-events:
+			poll.Stop()
+			if !ok {
+				// the watch is over, keep polling
+				events = nil
+				output, err = c.get(name, kind)
+				break
+			}
+			if event.Type == WatchEventDeleted {
+				output = nil
+			} else {
+				output = event.Object
+			}
+			interval = c.waitOptions.Interval
+		case <-poll.C:
+			output, err = c.get(name, kind)
+			interval = c.waitOptions.backoff(interval)
+		case <-deadline:
+			poll.Stop()
+			return stacktrace.Propagate(ErrTimeout{}, "timeout waiting for %s %q", kind, name)
+		case <-c.ctx.Done():
+			poll.Stop()
+			return stacktrace.Propagate(c.ctx.Err(), "stopped waiting for %s %q", kind, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// waitWhile waits as long as an object stays in a transient status, up to the transition timeout
+func (c *Context) waitWhile(name, kind string, transient RsStatus) error {
+	return c.waitUntil(name, kind, c.waitOptions.TransitionTimeout, func(output []byte) (bool, error) {
+		status, err := parseStatus(kind, output)
+		if err != nil {
+			return false, err
+		}
+		switch status {
+		case transient:
+			return false, nil
+		case RsStatusUnknown:
+			return false, stacktrace.Propagate(ErrUnknownStatus{name, kind, status}, "unknown status")
+		default:
+			return true, nil
+		}
+	})
+}
+
+func (o *WaitOptions) backoff(interval time.Duration) time.Duration {
+	interval *= 2
+	if interval > o.MaxInterval {
+		return o.MaxInterval
+	}
+	return interval
+}
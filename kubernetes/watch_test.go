@@ -0,0 +1,137 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type WatchTestSuite struct {
+	suite.Suite
+	resourceRoot string
+	namespace    string
+	cluster      *FakeCluster
+}
+
+func (s *WatchTestSuite) SetupTest() {
+	s.resourceRoot = "../test-resources"
+	s.namespace = "watch-ns"
+	s.cluster = NewFakeCluster()
+	_, err := NewContextWithBackend(s.namespace, s.cluster).Namespace().Create()
+	require.Nil(s.T(), err)
+}
+
+func (s *WatchTestSuite) TestWatchChanges() {
+	// polling alone would not notice the deletion before the timeout
+	kubeContext := s.context(s.cluster, time.Hour)
+	s.create(kubeContext, "config-map", "configmap", filepath.Join("static", "config-map.yml"))
+	done := make(chan error, 1)
+	go func() {
+		done <- kubeContext.Resource().WaitDeleted("config-map", "configmap", 10*time.Second)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	_, err := kubeContext.Resource().Delete("config-map", "configmap")
+	require.Nil(s.T(), err)
+	select {
+	case err = <-done:
+		require.Nil(s.T(), err)
+	case <-time.After(5 * time.Second):
+		s.T().Fatal("deletion was not watched")
+	}
+}
+
+func (s *WatchTestSuite) TestPollingFallback() {
+	s.cluster.TransitionReads = 2
+	backend := &unwatchableBackend{Backend: s.cluster}
+	kubeContext := s.context(backend, time.Millisecond)
+	s.create(kubeContext, "success", "pod", filepath.Join("pod", "success.yml"))
+	success, err := kubeContext.Resource().Wait("success", "pod")
+	require.Nil(s.T(), err)
+	require.True(s.T(), success)
+	require.True(s.T(), atomic.LoadInt32(&backend.watches) > 0)
+}
+
+func (s *WatchTestSuite) TestWaitReadyProgress() {
+	s.cluster.TransitionReads = 3
+	kubeContext := s.context(s.cluster, time.Millisecond)
+	s.create(kubeContext, "deployment", "deployment", filepath.Join("pod-based", "deployment.yml"))
+	messages := []string{}
+	readiness, err := kubeContext.Resource().WaitReady("deployment", "deployment", 5*time.Second, func(readiness *Readiness) {
+		messages = append(messages, readiness.Message)
+	})
+	require.Nil(s.T(), err)
+	require.True(s.T(), readiness.Ready)
+	require.True(s.T(), len(messages) > 1)
+	require.Equal(s.T(), readiness.Message, messages[len(messages)-1])
+}
+
+func (s *WatchTestSuite) TestTimeout() {
+	kubeContext := s.context(s.cluster, time.Millisecond)
+	s.create(kubeContext, "config-map", "configmap", filepath.Join("static", "config-map.yml"))
+	err := kubeContext.Resource().WaitDeleted("config-map", "configmap", 50*time.Millisecond)
+	require.NotNil(s.T(), err)
+	_, ok := stacktrace.RootCause(err).(ErrTimeout)
+	require.True(s.T(), ok, err.Error())
+}
+
+func (s *WatchTestSuite) TestCancel() {
+	s.cluster.SetOutcome(s.namespace, "job", "success", FakeOutcomeStuck)
+	kubeContext := s.context(s.cluster, time.Hour)
+	s.create(kubeContext, "success", "job", filepath.Join("job", "success.yml"))
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	_, err := kubeContext.WithContext(ctx).Resource().Wait("success", "job")
+	require.NotNil(s.T(), err)
+	require.Equal(s.T(), context.Canceled, stacktrace.RootCause(err))
+	_, err = kubeContext.WithContext(ctx).Resource().Exists("success", "job")
+	require.Equal(s.T(), context.Canceled, stacktrace.RootCause(err))
+}
+
+func (s *WatchTestSuite) TestBackoff() {
+	waitOptions := &WaitOptions{Interval: time.Second, MaxInterval: 5 * time.Second}
+	require.Equal(s.T(), 2*time.Second, waitOptions.backoff(time.Second))
+	require.Equal(s.T(), 4*time.Second, waitOptions.backoff(2*time.Second))
+	require.Equal(s.T(), 5*time.Second, waitOptions.backoff(4*time.Second))
+	require.Equal(s.T(), 5*time.Second, waitOptions.backoff(5*time.Second))
+}
+
+func (s *WatchTestSuite) context(backend Backend, interval time.Duration) *Context {
+	return NewContextWithBackend(s.namespace, backend).WithWaitOptions(&WaitOptions{
+		Interval:          interval,
+		MaxInterval:       interval,
+		TransitionTimeout: 10 * time.Second,
+	})
+}
+
+func (s *WatchTestSuite) create(kubeContext *Context, name, kind, filename string) {
+	content, err := ioutil.ReadFile(filepath.Join(s.resourceRoot, "resource-test", filename))
+	require.Nil(s.T(), err)
+	exists, err := kubeContext.Resource().Create(name, kind, string(content))
+	require.Nil(s.T(), err)
+	require.False(s.T(), exists)
+}
+
+type unwatchableBackend struct {
+	Backend
+	watches int32
+}
+
+func (b *unwatchableBackend) Watch(ctx context.Context, namespace, kind, name string) (Watcher, error) {
+	atomic.AddInt32(&b.watches, 1)
+	return nil, errors.New("watch is not supported")
+}
+
+func TestWatch(t *testing.T) {
+	suite.Run(t, new(WatchTestSuite))
+}
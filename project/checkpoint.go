@@ -0,0 +1,229 @@
+package project
+
+import (
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+)
+
+const checkpointKey = "checkpoint"
+
+// checkpoint lists the groups an up or upgrade completed, with the hash of their content when they completed.
+// It is stored in a ConfigMap next to the objects while the operation runs, and deleted once it succeeds.
+type checkpoint struct {
+	Project   string            `json:"project"`
+	Operation string            `json:"operation"`
+	DeployID  string            `json:"deployId"`
+	Groups    map[string]string `json:"groups"`
+}
+
+// checkpointTracker records the groups of a running operation in its checkpoint
+type checkpointTracker struct {
+	mu          sync.Mutex
+	kubeContext *kubernetes.Context
+	checkpoint  *checkpoint
+	hashes      map[string]string
+}
+
+// SetResume makes up and upgrade skip the groups completed by the last failed run of the same operation,
+// when neither their content nor the content of the groups they depend on changed
+func (p *Project) SetResume(resume bool) *Project {
+	p.resume = resume
+	return p
+}
+
+func (p *Project) checkpointName() string {
+	return "rivendell-checkpoint-" + p.identityName()
+}
+
+// startCheckpoint starts recording the groups completed by an operation. When resuming, the groups completed by the
+// last failed run which did not change are skipped by the walks with wait.
+func (p *Project) startCheckpoint(kubeContext *kubernetes.Context, operation string) error {
+	p.resourceGraph.skipped = nil
+	if p.filtered {
+		if p.resume {
+			return stacktrace.NewError("cannot resume a project read with include or exclude patterns")
+		}
+		return nil
+	}
+	tracker := &checkpointTracker{
+		kubeContext: kubeContext.WithContext(gocontext.Background()),
+		checkpoint: &checkpoint{
+			Project:   p.name,
+			Operation: operation,
+			DeployID:  p.deployID,
+			Groups:    make(map[string]string),
+		},
+		hashes: p.groupHashes(),
+	}
+	if p.resume {
+		skipped, err := p.resumableGroups(kubeContext, operation, tracker.hashes)
+		if err != nil {
+			return err
+		}
+		for name := range skipped {
+			tracker.checkpoint.Groups[name] = tracker.hashes[name]
+		}
+		p.resourceGraph.skipped = skipped
+	}
+	p.checkpoint = tracker
+	return nil
+}
+
+// resumableGroups returns the groups of the last checkpoint of the operation whose hash did not change
+func (p *Project) resumableGroups(kubeContext *kubernetes.Context, operation string, hashes map[string]string) (map[string]bool, error) {
+	previous, err := p.readCheckpoint(kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		utils.Warnf(kubeContext.Output(), "No checkpoint found, nothing to resume")
+		return nil, nil
+	}
+	if previous.Operation != operation {
+		utils.Warnf(kubeContext.Output(), "The checkpoint was written by %s, not %s, nothing to resume", previous.Operation, operation)
+		return nil, nil
+	}
+	skipped := make(map[string]bool)
+	names := []string{}
+	for name, hash := range previous.Groups {
+		if hashes[name] != "" && hashes[name] == hash {
+			skipped[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	utils.Infof(kubeContext.Output(), "Resuming %s of deploy %s, %d groups completed and unchanged: %s", operation, previous.DeployID, len(names), strings.Join(names, ", "))
+	return skipped, nil
+}
+
+// finishCheckpoint deletes the checkpoint once the operation succeeded. It returns the error of the operation first.
+func (p *Project) finishCheckpoint(kubeContext *kubernetes.Context, err error) error {
+	p.resourceGraph.skipped = nil
+	tracker := p.checkpoint
+	p.checkpoint = nil
+	if tracker == nil || err != nil {
+		return err
+	}
+	_, err = kubeContext.WithContext(gocontext.Background()).Resource().Delete(p.checkpointName(), "configmap")
+	return err
+}
+
+// checkpointGroups wraps a group function to record the groups it completed in the checkpoint
+func (p *Project) checkpointGroups(f GroupFunc) GroupFunc {
+	return func(ctx gocontext.Context, g *ResourceGroup) error {
+		err := f(ctx, g)
+		if err != nil || p.checkpoint == nil {
+			return err
+		}
+		recordErr := p.checkpoint.complete(p, g)
+		if recordErr != nil {
+			utils.Error(recordErr)
+		}
+		return nil
+	}
+}
+
+func (t *checkpointTracker) complete(p *Project, g *ResourceGroup) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.checkpoint.Groups[g.Name] = t.hashes[g.Name]
+	return p.writeCheckpoint(t.kubeContext, t.checkpoint)
+}
+
+// readCheckpoint returns the checkpoint of the project, nil if there is none
+func (p *Project) readCheckpoint(kubeContext *kubernetes.Context) (*checkpoint, error) {
+	manifest, err := kubeContext.Resource().Manifest(p.checkpointName(), "configmap")
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return nil, nil
+	}
+	configMap := &inventoryConfigMap{}
+	err = json.Unmarshal(manifest, configMap)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode checkpoint %q", p.checkpointName())
+	}
+	content, ok := configMap.Data[checkpointKey]
+	if !ok {
+		return nil, nil
+	}
+	cp := &checkpoint{}
+	err = json.Unmarshal([]byte(content), cp)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode checkpoint %q", p.checkpointName())
+	}
+	return cp, nil
+}
+
+func (p *Project) writeCheckpoint(kubeContext *kubernetes.Context, cp *checkpoint) error {
+	content, err := json.Marshal(cp)
+	if err != nil {
+		return stacktrace.Propagate(err, "cannot encode checkpoint")
+	}
+	configMap := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name": p.checkpointName(),
+			"labels": map[string]string{
+				LabelManagedBy: managedByRivendell,
+				LabelProject:   labelValue(p.name),
+			},
+		},
+		"data": map[string]string{
+			checkpointKey: string(content),
+		},
+	}
+	manifest, err := json.Marshal(configMap)
+	if err != nil {
+		return stacktrace.Propagate(err, "cannot encode checkpoint")
+	}
+	return kubeContext.Resource().Put(string(manifest))
+}
+
+// groupHashes returns a hash of every group covering its manifests, its waits and the hashes of the groups it
+// depends on. The deploy ID is left out, it changes on every run.
+func (p *Project) groupHashes() map[string]string {
+	hashes := make(map[string]string)
+	var hashGroup func(name string) string
+	hashGroup = func(name string) string {
+		if hash, ok := hashes[name]; ok {
+			return hash
+		}
+		g, ok := p.resourceGraph.ResourceGroups[name]
+		if !ok {
+			return ""
+		}
+		h := sha256.New()
+		fmt.Fprintf(h, "group %s\n", g.Name)
+		for _, depend := range g.Depend {
+			fmt.Fprintf(h, "depend %s %s\n", depend, hashGroup(depend))
+		}
+		waits, _ := json.Marshal(g.Wait)
+		fmt.Fprintf(h, "wait %s\n", waits)
+		for _, r := range g.allResources() {
+			content := r.RawContent
+			if p.deployID != "" {
+				content = strings.ReplaceAll(content, p.deployID, "")
+			}
+			fmt.Fprintf(h, "resource %s %s\n%s\n", r.Kind, r.Name, content)
+		}
+		hashes[name] = hex.EncodeToString(h.Sum(nil))
+		return hashes[name]
+	}
+	for name := range p.resourceGraph.ResourceGroups {
+		hashGroup(name)
+	}
+	return hashes
+}
@@ -0,0 +1,64 @@
+package project
+
+import (
+	"bytes"
+	gocontext "context"
+	"testing"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type CheckpointTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *CheckpointTestSuite) TestResume() {
+	s.cluster.SetOutcome(s.testNamespace, "deployment", "nginx", kubernetes.FakeOutcomeFailed)
+	p := s.readProject("wait-pod", nil)
+	err := p.Up(gocontext.Background())
+	require.NotNil(s.T(), err)
+	kubeContext := s.kubeContext(p)
+	cp, err := p.readCheckpoint(kubeContext)
+	require.Nil(s.T(), err)
+	require.NotNil(s.T(), cp)
+	require.Equal(s.T(), OperationUp, cp.Operation)
+	require.Contains(s.T(), cp.Groups, "jobs1")
+	require.Contains(s.T(), cp.Groups, "services")
+	require.NotContains(s.T(), cp.Groups, "jobs2")
+
+	s.cluster.SetOutcome(s.testNamespace, "deployment", "nginx", kubernetes.FakeOutcomeSucceeded)
+	out := &bytes.Buffer{}
+	resumed := s.readProject("wait-pod", nil).SetResume(true).SetEventOutput(out)
+	err = resumed.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+	skipped := []string{}
+	for _, event := range s.readEvents(out) {
+		if event.Type == EventGroupSkipped {
+			skipped = append(skipped, event.Group)
+		}
+		require.False(s.T(), event.Type == EventWaitStarted && event.Name == "job1" && event.For == "", "wait of a skipped group")
+	}
+	require.Equal(s.T(), []string{"jobs1", "services"}, skipped)
+	cp, err = p.readCheckpoint(kubeContext)
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), cp)
+	s.down(p)
+}
+
+func (s *CheckpointTestSuite) TestGroupHashes() {
+	p := s.readProject("wait-pod", nil)
+	hashes := p.groupHashes()
+	require.Equal(s.T(), hashes, s.readProject("wait-pod", nil).groupHashes())
+	r := p.resourceGraph.ResourceGroups["services"].allResources()[0]
+	r.RawContent += "\n# changed"
+	changed := p.groupHashes()
+	require.Equal(s.T(), hashes["jobs1"], changed["jobs1"])
+	require.NotEqual(s.T(), hashes["services"], changed["services"])
+	require.NotEqual(s.T(), hashes["jobs2"], changed["jobs2"])
+}
+
+func TestCheckpoint(t *testing.T) {
+	suite.Run(t, new(CheckpointTestSuite))
+}
@@ -0,0 +1,132 @@
+package project
+
+import (
+	gocontext "context"
+	"testing"
+	"time"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type FakeCommandTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *FakeCommandTestSuite) TestUpAndDown() {
+	p := s.up("up-down", nil)
+	kubeContext := s.kubeContext(p)
+	p.resourceGraph.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
+		for _, r := range g.allResources() {
+			require.True(s.T(), s.exists(kubeContext, r.Name, r.Kind))
+		}
+		return nil
+	})
+	s.down(p)
+	p.resourceGraph.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
+		for _, r := range g.allResources() {
+			require.False(s.T(), s.exists(kubeContext, r.Name, r.Kind))
+		}
+		return nil
+	})
+}
+
+func (s *FakeCommandTestSuite) TestUpAndDownInParallel() {
+	s.cluster.TransitionReads = 2
+	p := s.readProject("parallel", nil).SetParallelism(2)
+	err := p.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+	kubeContext := s.kubeContext(p)
+	for _, name := range []string{"redis", "nginx"} {
+		require.True(s.T(), s.exists(kubeContext, name, "deployment"))
+	}
+	s.down(p)
+	require.False(s.T(), s.exists(kubeContext, "nginx-conf", "configmap"))
+}
+
+func (s *FakeCommandTestSuite) TestUpDependencyNotReady() {
+	s.cluster.SetOutcome(s.testNamespace, "deployment", "redis", kubernetes.FakeOutcomeFailed)
+	p := s.readProject("up-down", nil)
+	err := p.Up(gocontext.Background())
+	require.NotNil(s.T(), err)
+	_, ok := stacktrace.RootCause(err).(ErrWaitFailed)
+	require.True(s.T(), ok)
+	kubeContext := s.kubeContext(p)
+	require.False(s.T(), s.exists(kubeContext, "nginx", "deployment"), "groups depending on a failed deployment are not created")
+	s.down(p)
+}
+
+func (s *FakeCommandTestSuite) TestWaitOptions() {
+	s.cluster.SetOutcome(s.testNamespace, "deployment", "redis", kubernetes.FakeOutcomeStuck)
+	p := s.readProject("wait-options", nil)
+	waitOptions := p.waitOptions()
+	require.Equal(s.T(), time.Second, waitOptions.Interval)
+	require.Equal(s.T(), 2*time.Second, waitOptions.MaxInterval)
+	require.Equal(s.T(), kubernetes.DefaultWaitOptions.TransitionTimeout, waitOptions.TransitionTimeout)
+	require.Equal(s.T(), time.Second, p.readyTimeout())
+	require.Equal(s.T(), defaultDeleteTimeout, p.deleteTimeout())
+	err := p.Up(gocontext.Background())
+	require.NotNil(s.T(), err)
+	cause, ok := stacktrace.RootCause(err).(ErrWaitTimeout)
+	require.True(s.T(), ok)
+	require.Equal(s.T(), "redis", cause.Name)
+	s.down(p)
+}
+
+func (s *FakeCommandTestSuite) TestUpdate() {
+	p := s.up("update", map[string]string{"tag": "1.13.12"})
+	updatedProject := s.readProject("update", map[string]string{"tag": "1.13"})
+	err := updatedProject.Update(gocontext.Background())
+	require.Nil(s.T(), err)
+	s.down(p)
+}
+
+func (s *FakeCommandTestSuite) TestUpgrade() {
+	p := s.up("upgrade", map[string]string{"nginxTag": "1.13.12", "ubuntuTag": "16.04"})
+	err := Wait(gocontext.Background(), s.testNamespace, "", "", "job", "success", 60)
+	require.Nil(s.T(), err)
+	updatedProject := s.readProject("upgrade", map[string]string{"nginxTag": "1.13", "ubuntuTag": "16.10"})
+	err = updatedProject.Upgrade(gocontext.Background())
+	require.Nil(s.T(), err)
+	err = Wait(gocontext.Background(), s.testNamespace, "", "", "job", "success", 60)
+	require.Nil(s.T(), err)
+	s.down(p)
+}
+
+func (s *FakeCommandTestSuite) TestBatch() {
+	p := s.readProject("upgrade", map[string]string{"nginxTag": "1.13.12", "ubuntuTag": "16.04"}).SetBatch(true)
+	err := p.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+	err = Wait(gocontext.Background(), s.testNamespace, "", "", "job", "success", 60)
+	require.Nil(s.T(), err)
+	kubeContext := s.kubeContext(p)
+	p.resourceGraph.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
+		for _, r := range g.allResources() {
+			require.True(s.T(), s.exists(kubeContext, r.Name, r.Kind))
+		}
+		return nil
+	})
+	updatedProject := s.readProject("upgrade", map[string]string{"nginxTag": "1.13", "ubuntuTag": "16.10"}).SetBatch(true)
+	err = updatedProject.Update(gocontext.Background())
+	require.Nil(s.T(), err)
+	err = updatedProject.Upgrade(gocontext.Background())
+	require.Nil(s.T(), err)
+	err = Wait(gocontext.Background(), s.testNamespace, "", "", "job", "success", 60)
+	require.Nil(s.T(), err)
+	s.down(p)
+}
+
+func (s *FakeCommandTestSuite) TestRestart() {
+	p := s.up("up-down", nil)
+	pods, err := p.GetServicePods()
+	require.Nil(s.T(), err)
+	err = p.Restart(gocontext.Background(), pods)
+	require.Nil(s.T(), err)
+	s.down(p)
+}
+
+func TestFakeCommand(t *testing.T) {
+	suite.Run(t, new(FakeCommandTestSuite))
+}
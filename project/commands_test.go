@@ -1,6 +1,7 @@
 package project
 
 import (
+	gocontext "context"
 	"fmt"
 	"path/filepath"
 	"testing"
@@ -37,11 +38,11 @@ func (s *CommandTestSuite) TestUpAndDown() {
 	variableFiles := []string{}
 	project, err := ReadProject(projectFile, namespace, context, kubeConfig, variables, variableFiles, nil, nil)
 	require.Nil(s.T(), err)
-	err = project.Up()
+	err = project.Up(gocontext.Background())
 	require.Nil(s.T(), err)
 	kubeContext, err := kubernetes.NewContext(project.namespace, project.context, project.kubeConfig)
 	require.Nil(s.T(), err)
-	project.resourceGraph.WalkForward(func(g *ResourceGroup) error {
+	project.resourceGraph.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
 		for _, r := range g.allResources() {
 			exists, err := kubeContext.Resource().Exists(r.Name, r.Kind)
 			require.Nil(s.T(), err)
@@ -49,7 +50,7 @@ func (s *CommandTestSuite) TestUpAndDown() {
 		}
 		return nil
 	})
-	err = project.Down(true, true)
+	err = project.Down(gocontext.Background(), true, true)
 	require.Nil(s.T(), err)
 }
 
@@ -68,14 +69,14 @@ func (s *CommandTestSuite) TestUpdate() {
 	variableFiles := []string{}
 	project, err := ReadProject(projectFile, namespace, context, kubeConfig, variables, variableFiles, nil, nil)
 	require.Nil(s.T(), err)
-	err = project.Up()
+	err = project.Up(gocontext.Background())
 	require.Nil(s.T(), err)
 	variables["tag"] = "1.13"
 	updatedProject, err := ReadProject(projectFile, namespace, context, kubeConfig, variables, variableFiles, nil, nil)
 	require.Nil(s.T(), err)
-	err = updatedProject.Update()
+	err = updatedProject.Update(gocontext.Background())
 	require.Nil(s.T(), err)
-	err = project.Down(true, true)
+	err = project.Down(gocontext.Background(), true, true)
 	require.Nil(s.T(), err)
 }
 
@@ -95,19 +96,19 @@ func (s *CommandTestSuite) TestUpgrade() {
 	variableFiles := []string{}
 	project, err := ReadProject(projectFile, namespace, context, kubeConfig, variables, variableFiles, nil, nil)
 	require.Nil(s.T(), err)
-	err = project.Up()
+	err = project.Up(gocontext.Background())
 	require.Nil(s.T(), err)
-	err = Wait(namespace, context, kubeConfig, "job", "success", 60)
+	err = Wait(gocontext.Background(), namespace, context, kubeConfig, "job", "success", 60)
 	require.Nil(s.T(), err)
 	variables["nginxTag"] = "1.13"
 	variables["ubuntuTag"] = "16.10"
 	updatedProject, err := ReadProject(projectFile, namespace, context, kubeConfig, variables, variableFiles, nil, nil)
 	require.Nil(s.T(), err)
-	err = updatedProject.Upgrade()
+	err = updatedProject.Upgrade(gocontext.Background())
 	require.Nil(s.T(), err)
-	err = Wait(namespace, context, kubeConfig, "job", "success", 60)
+	err = Wait(gocontext.Background(), namespace, context, kubeConfig, "job", "success", 60)
 	require.Nil(s.T(), err)
-	err = project.Down(true, true)
+	err = project.Down(gocontext.Background(), true, true)
 	require.Nil(s.T(), err)
 }
 
@@ -124,11 +125,11 @@ func (s *CommandTestSuite) TestWaitPod() {
 	variableFiles := []string{}
 	p, err := ReadProject(projectFile, namespace, context, kubeConfig, variables, variableFiles, nil, nil)
 	require.Nil(s.T(), err)
-	err = p.Up()
+	err = p.Up(gocontext.Background())
 	require.Nil(s.T(), err)
 	kubeContext, err := kubernetes.NewContext(p.namespace, p.context, p.kubeConfig)
 	require.Nil(s.T(), err)
-	p.resourceGraph.WalkForward(func(g *ResourceGroup) error {
+	p.resourceGraph.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
 		for _, r := range g.allResources() {
 			exists, err := kubeContext.Resource().Exists(r.Name, r.Kind)
 			require.Nil(s.T(), err)
@@ -136,9 +137,9 @@ func (s *CommandTestSuite) TestWaitPod() {
 		}
 		return nil
 	})
-	err = Wait(namespace, context, kubeConfig, "pod", "pod2", 300)
+	err = Wait(gocontext.Background(), namespace, context, kubeConfig, "pod", "pod2", 300)
 	require.Nil(s.T(), err)
-	err = p.Down(true, true)
+	err = p.Down(gocontext.Background(), true, true)
 	require.Nil(s.T(), err)
 }
 
@@ -155,11 +156,11 @@ func (s *CommandTestSuite) TestWaitJob() {
 	variableFiles := []string{}
 	p, err := ReadProject(projectFile, namespace, context, kubeConfig, variables, variableFiles, nil, nil)
 	require.Nil(s.T(), err)
-	err = p.Up()
+	err = p.Up(gocontext.Background())
 	require.Nil(s.T(), err)
 	kubeContext, err := kubernetes.NewContext(p.namespace, p.context, p.kubeConfig)
 	require.Nil(s.T(), err)
-	p.resourceGraph.WalkForward(func(g *ResourceGroup) error {
+	p.resourceGraph.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
 		for _, r := range g.allResources() {
 			exists, err := kubeContext.Resource().Exists(r.Name, r.Kind)
 			require.Nil(s.T(), err)
@@ -167,9 +168,9 @@ func (s *CommandTestSuite) TestWaitJob() {
 		}
 		return nil
 	})
-	err = Wait(namespace, context, kubeConfig, "job", "job2", 300)
+	err = Wait(gocontext.Background(), namespace, context, kubeConfig, "job", "job2", 300)
 	require.Nil(s.T(), err)
-	err = p.Down(true, true)
+	err = p.Down(gocontext.Background(), true, true)
 	require.Nil(s.T(), err)
 }
 
@@ -178,7 +179,7 @@ func (s *CommandTestSuite) TestWaitNotExists() {
 		fmt.Println("Skipping test wait not exists")
 		return
 	}
-	err := Wait("", "", "", "job", "not-exists", 0)
+	err := Wait(gocontext.Background(), "", "", "", "job", "not-exists", 0)
 	require.NotNil(s.T(), err)
 	_, ok := stacktrace.RootCause(err).(kubernetes.ErrNotExist)
 	require.True(s.T(), ok)
@@ -197,11 +198,11 @@ func (s *CommandTestSuite) TestJobWaitTimeoutInProject() {
 	variableFiles := []string{}
 	p, err := ReadProject(projectFile, namespace, context, kubeConfig, variables, variableFiles, nil, nil)
 	require.Nil(s.T(), err)
-	err = p.Up()
+	err = p.Up(gocontext.Background())
 	require.NotNil(s.T(), err)
 	_, ok := stacktrace.RootCause(err).(ErrWaitTimeout)
 	require.True(s.T(), ok)
-	err = p.Down(true, true)
+	err = p.Down(gocontext.Background(), true, true)
 	require.Nil(s.T(), err)
 }
 
@@ -218,11 +219,11 @@ func (s *CommandTestSuite) TestJobWaitFailedInProject() {
 	variableFiles := []string{}
 	p, err := ReadProject(projectFile, namespace, context, kubeConfig, variables, variableFiles, nil, nil)
 	require.Nil(s.T(), err)
-	err = p.Up()
+	err = p.Up(gocontext.Background())
 	require.NotNil(s.T(), err)
 	_, ok := stacktrace.RootCause(err).(ErrWaitFailed)
 	require.True(s.T(), ok)
-	err = p.Down(true, true)
+	err = p.Down(gocontext.Background(), true, true)
 	require.Nil(s.T(), err)
 }
 
@@ -239,11 +240,11 @@ func (s *CommandTestSuite) TestPodWaitTimeoutInProject() {
 	variableFiles := []string{}
 	p, err := ReadProject(projectFile, namespace, context, kubeConfig, variables, variableFiles, nil, nil)
 	require.Nil(s.T(), err)
-	err = p.Up()
+	err = p.Up(gocontext.Background())
 	require.NotNil(s.T(), err)
 	_, ok := stacktrace.RootCause(err).(ErrWaitTimeout)
 	require.True(s.T(), ok)
-	err = p.Down(true, true)
+	err = p.Down(gocontext.Background(), true, true)
 	require.Nil(s.T(), err)
 }
 
@@ -260,11 +261,11 @@ func (s *CommandTestSuite) TestPodWaitFailedInProject() {
 	variableFiles := []string{}
 	p, err := ReadProject(projectFile, namespace, context, kubeConfig, variables, variableFiles, nil, nil)
 	require.Nil(s.T(), err)
-	err = p.Up()
+	err = p.Up(gocontext.Background())
 	require.NotNil(s.T(), err)
 	_, ok := stacktrace.RootCause(err).(ErrWaitFailed)
 	require.True(s.T(), ok)
-	err = p.Down(true, true)
+	err = p.Down(gocontext.Background(), true, true)
 	require.Nil(s.T(), err)
 }
 
@@ -10,11 +10,16 @@ import (
 
 // Config holds configuration data parsed from yaml file
 type Config struct {
+	Name            string                 `yaml:"name,omitempty"`
 	RootDir         string                 `yaml:"root_dir"`
 	Namespace       string                 `yaml:"namespace"`
 	Variables       map[string]string      `yaml:"variables"`
 	ResourceGroups  []*ResourceGroupConfig `yaml:"resource_groups"`
 	DeleteNamespace bool                   `yaml:"delete_namespace"`
+	WaitOptions     *WaitOptionsConfig     `yaml:"wait_options,omitempty"`
+	Ownership       *OwnershipConfig       `yaml:"ownership,omitempty"`
+	Prune           *PruneConfig           `yaml:"prune,omitempty"`
+	HistoryLimit    int                    `yaml:"history_limit,omitempty"`
 }
 
 // ResourceGroupConfig holds configuration for resource group
@@ -24,13 +29,40 @@ type ResourceGroupConfig struct {
 	Excludes  []string      `yaml:"excludes"`
 	Depend    []string      `yaml:"depend"`
 	Wait      []*WaitConfig `yaml:"wait"`
+	// DisableOwnership leaves the resources of the group without ownership labels and annotations
+	DisableOwnership bool `yaml:"disable_ownership,omitempty"`
 }
 
 // WaitConfig .
 type WaitConfig struct {
-	Name    string `yaml:"name"`
-	Kind    string `yaml:"kind"`
-	Timeout int    `yaml:"timeout"`
+	Name                     string `yaml:"name"`
+	Kind                     string `yaml:"kind"`
+	Timeout                  int    `yaml:"timeout"`
+	For                      string `yaml:"for"`
+	RestartThreshold         int    `yaml:"restart_threshold"`
+	UnschedulableGracePeriod int    `yaml:"unschedulable_grace_period"`
+}
+
+// WaitOptionsConfig tunes how long and how often rivendell waits for resources, all values are in seconds
+type WaitOptionsConfig struct {
+	Interval          int `yaml:"interval"`
+	MaxInterval       int `yaml:"max_interval"`
+	TransitionTimeout int `yaml:"transition_timeout"`
+	ReadyTimeout      int `yaml:"ready_timeout"`
+	DeleteTimeout     int `yaml:"delete_timeout"`
+}
+
+// OwnershipConfig tunes the labels and annotations stamped on every resource
+type OwnershipConfig struct {
+	Disabled    bool              `yaml:"disabled"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// PruneConfig .
+type PruneConfig struct {
+	// ExcludeKinds are never pruned, persistent volume claims by default
+	ExcludeKinds []string `yaml:"exclude_kinds"`
 }
 
 // ReadProjectConfig .
@@ -42,7 +74,7 @@ func ReadProjectConfig(projectFile string, variables map[string]string) (*Config
 	projectConfig := &Config{}
 	err = yaml.Unmarshal(content, projectConfig)
 	if err != nil {
-		return nil, stacktrace.Propagate(err, "cannot parse yaml configuration")
+		return nil, stacktrace.Propagate(ErrParse{projectFile, err}, "cannot parse yaml configuration")
 	}
 	return projectConfig, nil
 }
@@ -0,0 +1,70 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+)
+
+// DiagnosticsDir is the directory diagnostics of failed resources are written to, nothing is written if empty
+var DiagnosticsDir = ""
+
+// DiagnosticsTailLines is the number of log lines collected for every container of a failed resource
+var DiagnosticsTailLines = 50
+
+// diagnoseFailure reports diagnostics for the resource responsible of a failed or timed out wait
+func diagnoseFailure(kubeContext *kubernetes.Context, err error) {
+	switch cause := stacktrace.RootCause(err).(type) {
+	case ErrWaitFailed:
+		diagnose(kubeContext, cause.Name, cause.Kind)
+	case ErrWaitTimeout:
+		diagnose(kubeContext, cause.Name, cause.Kind)
+	case kubernetes.ErrPodFailure:
+		diagnose(kubeContext, cause.Pod, "pod")
+	}
+}
+
+// diagnose prints diagnostics of a resource and writes them to DiagnosticsDir.
+// Errors are only printed, the original failure is what matters to the caller.
+func diagnose(kubeContext *kubernetes.Context, name, kind string) {
+	utils.Warnf(kubeContext.Output(), "Collecting diagnostics for %s %q", kind, name)
+	diagnostics, err := kubeContext.Diagnose(name, kind, DiagnosticsTailLines)
+	if err != nil {
+		utils.Error(err)
+		return
+	}
+	err = diagnostics.Write(kubeContext.Output())
+	if err != nil {
+		utils.Error(err)
+		return
+	}
+	if DiagnosticsDir == "" {
+		return
+	}
+	err = os.MkdirAll(DiagnosticsDir, 0755)
+	if err != nil {
+		utils.Error(stacktrace.Propagate(err, "cannot create diagnostics directory %q", DiagnosticsDir))
+		return
+	}
+	filename := fmt.Sprintf("%s-%s.txt", kind, name)
+	if namespace := kubeContext.Namespace().Name(); namespace != "" {
+		filename = namespace + "-" + filename
+	}
+	filename = filepath.Join(DiagnosticsDir, filename)
+	f, err := os.Create(filename)
+	if err != nil {
+		utils.Error(stacktrace.Propagate(err, "cannot create diagnostics file %q", filename))
+		return
+	}
+	defer f.Close()
+	err = diagnostics.Write(f)
+	if err != nil {
+		utils.Error(err)
+		return
+	}
+	utils.Infof2(kubeContext.Output(), "Diagnostics written to %s", filename)
+}
@@ -0,0 +1,39 @@
+package project
+
+import (
+	gocontext "context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type DiagnosticsTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *DiagnosticsTestSuite) TestDiagnosticsOnWaitFailure() {
+	dir, err := ioutil.TempDir("", "rivendell-diagnostics")
+	require.Nil(s.T(), err)
+	defer os.RemoveAll(dir)
+	DiagnosticsDir = filepath.Join(dir, "diagnostics")
+	s.cluster.SetOutcome(s.testNamespace, "pod", "pod1", kubernetes.FakeOutcomeFailed)
+	s.cluster.SetLogs(s.testNamespace, "pod1", "pod1", "cannot connect to database\n")
+	p := s.readProject("pod-wait-failed-in-project", nil)
+	err = p.Up(gocontext.Background())
+	require.NotNil(s.T(), err)
+	content, err := ioutil.ReadFile(filepath.Join(DiagnosticsDir, s.testNamespace+"-pod-pod1.txt"))
+	require.Nil(s.T(), err)
+	require.Contains(s.T(), string(content), "===== pod \"pod1\" =====")
+	require.Contains(s.T(), string(content), "phase: Failed")
+	require.Contains(s.T(), string(content), "cannot connect to database")
+	s.down(p)
+}
+
+func TestDiagnostics(t *testing.T) {
+	suite.Run(t, new(DiagnosticsTestSuite))
+}
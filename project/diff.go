@@ -0,0 +1,130 @@
+package project
+
+import (
+	gocontext "context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/fatih/color"
+	"github.com/palantir/stacktrace"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// diffIgnoredAnnotations change on every run without changing the resources
+var diffIgnoredAnnotations = []string{AnnotationDeployID, AnnotationVersion}
+
+// ResourceDiff is the difference between a rendered resource and its live counterpart
+type ResourceDiff struct {
+	Group  string
+	Kind   string
+	Name   string
+	Exists bool
+	// Diff is a unified diff from the live object to the rendered manifest, empty when they are the same
+	Diff string
+}
+
+// Diff compares every resource of the project with the live cluster, in the order of the graph
+func (p *Project) Diff(ctx gocontext.Context) ([]*ResourceDiff, error) {
+	kubeContext, err := p.newKubeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	diffs := []*ResourceDiff{}
+	err = p.resourceGraph.WalkForward(ctx, func(g *ResourceGroup) error {
+		for _, r := range g.allResources() {
+			comparison, err := kubeContext.Resource().Compare(r.Name, r.Kind, r.RawContent, diffIgnoredAnnotations)
+			if err != nil {
+				return err
+			}
+			diff, err := unifiedDiff(comparison)
+			if err != nil {
+				return err
+			}
+			diffs = append(diffs, &ResourceDiff{
+				Group:  g.Name,
+				Kind:   r.Kind,
+				Name:   r.Name,
+				Exists: comparison.Exists,
+				Diff:   diff,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diffs, nil
+}
+
+// PrintDiff prints the diffs grouped by resource group and tells if any resource differs
+func (p *Project) PrintDiff(out io.Writer, diffs []*ResourceDiff) bool {
+	changed := 0
+	group := ""
+	for _, diff := range diffs {
+		if diff.Diff == "" {
+			continue
+		}
+		if diff.Group != group {
+			group = diff.Group
+			utils.Infof(out, "==> Group %q", group)
+		}
+		changed++
+		if diff.Exists {
+			utils.Warnf(out, "%s %q differs", diff.Kind, diff.Name)
+		} else {
+			utils.Successf(out, "%s %q will be created", diff.Kind, diff.Name)
+		}
+		printColoredDiff(out, diff.Diff)
+	}
+	if changed == 0 {
+		utils.Successf(out, "No differences")
+		return false
+	}
+	utils.Warnf(out, "%d of %d resources differ", changed, len(diffs))
+	return true
+}
+
+func unifiedDiff(comparison *kubernetes.Comparison) (string, error) {
+	if !comparison.Changed() {
+		return "", nil
+	}
+	name := comparison.Kind + "/" + comparison.Name
+	fromFile := "live/" + name
+	if !comparison.Exists {
+		fromFile = "/dev/null"
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(comparison.Live),
+		B:        difflib.SplitLines(comparison.Desired),
+		FromFile: fromFile,
+		ToFile:   "rendered/" + name,
+		Context:  3,
+	})
+	if err != nil {
+		return "", stacktrace.Propagate(err, "cannot diff %s", name)
+	}
+	return diff, nil
+}
+
+func printColoredDiff(out io.Writer, diff string) {
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if line == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			color.New(color.Bold).Fprint(out, line)
+		case strings.HasPrefix(line, "+"):
+			color.New(color.FgGreen).Fprint(out, line)
+		case strings.HasPrefix(line, "-"):
+			color.New(color.FgRed).Fprint(out, line)
+		case strings.HasPrefix(line, "@@"):
+			color.New(color.FgCyan).Fprint(out, line)
+		default:
+			fmt.Fprint(out, line)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package project
+
+import (
+	gocontext "context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type DiffTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *DiffTestSuite) TestDiff() {
+	projectFile := filepath.Join(s.resourceRoot, "command-test", "rollback", "project.yml")
+	first, err := ReadProject(projectFile, s.testNamespace, "", "", nil, []string{}, nil, []string{"**/extra.yml"})
+	require.Nil(s.T(), err)
+	err = first.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+
+	diffs, err := first.Diff(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Len(s.T(), diffs, 1)
+	require.Empty(s.T(), diffs[0].Diff, "a new deploy ID is not a difference")
+	require.False(s.T(), first.PrintDiff(ioutil.Discard, diffs))
+
+	p := s.readProject("rollback", map[string]string{"greeting": "hi"})
+	diffs, err = p.Diff(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Len(s.T(), diffs, 2)
+	require.Equal(s.T(), "greeting", diffs[0].Name)
+	require.True(s.T(), diffs[0].Exists)
+	require.Contains(s.T(), diffs[0].Diff, "-  greeting: hello\n")
+	require.Contains(s.T(), diffs[0].Diff, "+  greeting: hi\n")
+	require.Equal(s.T(), "extra", diffs[1].Name)
+	require.False(s.T(), diffs[1].Exists)
+	require.Contains(s.T(), diffs[1].Diff, "--- /dev/null")
+	require.True(s.T(), p.PrintDiff(ioutil.Discard, diffs))
+	s.down(first)
+}
+
+func TestDiff(t *testing.T) {
+	suite.Run(t, new(DiffTestSuite))
+}
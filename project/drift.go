@@ -0,0 +1,134 @@
+package project
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/palantir/stacktrace"
+)
+
+// DriftStatus tells how a live object drifted from the project
+type DriftStatus string
+
+// DriftStatus values
+const (
+	DriftInSync   DriftStatus = "in-sync"
+	DriftMissing  DriftStatus = "missing"
+	DriftModified DriftStatus = "modified"
+	DriftOrphaned DriftStatus = "orphaned"
+)
+
+// DriftEntry is the drift of a resource of the project, or of an object rivendell applied before
+type DriftEntry struct {
+	Group  string      `json:"group"`
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	Status DriftStatus `json:"status"`
+	// Diff is a unified diff from the live object to the rendered manifest of a modified resource
+	Diff string `json:"diff,omitempty"`
+}
+
+// DriftReport .
+type DriftReport struct {
+	Project   string `json:"project"`
+	Namespace string `json:"namespace"`
+	// OrphansChecked is false when the project was read with include or exclude patterns
+	OrphansChecked bool          `json:"orphansChecked"`
+	Entries        []*DriftEntry `json:"entries"`
+}
+
+// Drift checks every resource of the project against the cluster, and looks for objects recorded in the inventory
+// which the project does not declare anymore
+func (p *Project) Drift(ctx gocontext.Context) (*DriftReport, error) {
+	diffs, err := p.Diff(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &DriftReport{
+		Project:   p.name,
+		Namespace: p.namespace,
+		Entries:   []*DriftEntry{},
+	}
+	for _, diff := range diffs {
+		entry := &DriftEntry{Group: diff.Group, Kind: diff.Kind, Name: diff.Name, Status: DriftInSync}
+		switch {
+		case !diff.Exists:
+			entry.Status = DriftMissing
+		case diff.Diff != "":
+			entry.Status = DriftModified
+			entry.Diff = diff.Diff
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+	if p.filtered {
+		return report, nil
+	}
+	kubeContext, err := p.newKubeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orphans, err := p.orphans(kubeContext, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	report.OrphansChecked = true
+	for _, orphan := range orphans {
+		report.Entries = append(report.Entries, &DriftEntry{
+			Group:  orphan.Group,
+			Kind:   orphan.Kind,
+			Name:   orphan.Name,
+			Status: DriftOrphaned,
+		})
+	}
+	return report, nil
+}
+
+// Drifted tells if any object is not in sync
+func (report *DriftReport) Drifted() bool {
+	for _, entry := range report.Entries {
+		if entry.Status != DriftInSync {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of objects per status
+func (report *DriftReport) Count() map[DriftStatus]int {
+	counts := make(map[DriftStatus]int)
+	for _, entry := range report.Entries {
+		counts[entry.Status]++
+	}
+	return counts
+}
+
+// PrintDriftReport prints a report as a table, or as json
+func (p *Project) PrintDriftReport(out io.Writer, report *DriftReport, format string) error {
+	switch format {
+	case "json":
+		content, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return stacktrace.Propagate(err, "cannot encode drift report")
+		}
+		fmt.Fprintln(out, string(content))
+		return nil
+	case "table", "":
+		w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "GROUP\tKIND\tNAME\tSTATUS")
+		for _, entry := range report.Entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Group, entry.Kind, entry.Name, entry.Status)
+		}
+		w.Flush()
+		counts := report.Count()
+		fmt.Fprintf(out, "\n%d in sync, %d missing, %d modified, %d orphaned\n", counts[DriftInSync], counts[DriftMissing], counts[DriftModified], counts[DriftOrphaned])
+		if !report.OrphansChecked {
+			fmt.Fprintln(out, "Orphaned objects were not checked, the project was read with include or exclude patterns")
+		}
+		return nil
+	default:
+		return stacktrace.NewError("unknown output format %q, one of: table|json", format)
+	}
+}
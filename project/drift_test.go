@@ -0,0 +1,64 @@
+package project
+
+import (
+	gocontext "context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type DriftTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *DriftTestSuite) TestDrift() {
+	p := s.up("prune", nil)
+	report, err := p.Drift(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.False(s.T(), report.Drifted())
+	require.True(s.T(), report.OrphansChecked)
+
+	kubeContext := s.kubeContext(p)
+	err = kubeContext.Resource().Put("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: nginx-conf\ndata:\n  default.conf: hotfix\n")
+	require.Nil(s.T(), err)
+	_, err = kubeContext.Resource().Delete("nginx", "service")
+	require.Nil(s.T(), err)
+
+	projectFile := filepath.Join(s.resourceRoot, "command-test", "prune", "project-reduced.yml")
+	reduced, err := ReadProject(projectFile, s.testNamespace, "", "", nil, []string{}, nil, nil)
+	require.Nil(s.T(), err)
+	report, err = reduced.Drift(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.True(s.T(), report.Drifted())
+	statuses := make(map[string]DriftStatus)
+	for _, entry := range report.Entries {
+		statuses[entry.Kind+"/"+entry.Name] = entry.Status
+	}
+	require.Equal(s.T(), map[string]DriftStatus{
+		"ConfigMap/nginx-conf":       DriftModified,
+		"Deployment/nginx":           DriftInSync,
+		"Service/nginx":              DriftMissing,
+		"Deployment/redis":           DriftOrphaned,
+		"Service/redis":              DriftOrphaned,
+		"PersistentVolumeClaim/data": DriftOrphaned,
+	}, statuses)
+	require.Contains(s.T(), report.Entries[0].Diff, "hotfix")
+	require.Nil(s.T(), reduced.PrintDriftReport(ioutil.Discard, report, "json"))
+	require.Nil(s.T(), reduced.PrintDriftReport(ioutil.Discard, report, "table"))
+	require.NotNil(s.T(), reduced.PrintDriftReport(ioutil.Discard, report, "xml"))
+
+	filtered, err := ReadProject(projectFile, s.testNamespace, "", "", nil, []string{}, nil, []string{"**/nginx.yml"})
+	require.Nil(s.T(), err)
+	report, err = filtered.Drift(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.False(s.T(), report.OrphansChecked)
+	require.Len(s.T(), report.Entries, 1)
+	s.down(p)
+}
+
+func TestDrift(t *testing.T) {
+	suite.Run(t, new(DriftTestSuite))
+}
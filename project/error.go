@@ -2,6 +2,9 @@ package project
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 )
 
 // ErrMissingDependency .
@@ -23,6 +26,20 @@ func (err ErrCyclicDependency) Error() string {
 	return fmt.Sprintf("cyclic dependency found for %q", err.Node)
 }
 
+// ErrParse is returned when a project file or a rendered resource file is not valid yaml
+type ErrParse struct {
+	Source string
+	Err    error
+}
+
+func (err ErrParse) Error() string {
+	return fmt.Sprintf("cannot parse %s: %s", err.Source, err.Err)
+}
+
+func (err ErrParse) Unwrap() error {
+	return err.Err
+}
+
 // ErrWaitTimeout .
 type ErrWaitTimeout struct {
 	Name string
@@ -42,3 +59,73 @@ type ErrWaitFailed struct {
 func (err ErrWaitFailed) Error() string {
 	return fmt.Sprintf("wait failed for %s %q", err.Kind, err.Name)
 }
+
+// ErrReleaseNotFound .
+type ErrReleaseNotFound struct {
+	Project string
+	Version int
+}
+
+func (err ErrReleaseNotFound) Error() string {
+	return fmt.Sprintf("release %d of project %q not found", err.Version, err.Project)
+}
+
+// ErrGroupsFailed is returned by the walks going on after failures, see ResourceGraph.KeepGoing
+type ErrGroupsFailed struct {
+	Failed map[string]error
+	// Skipped maps the groups which were not processed to the failed group they come after
+	Skipped   map[string]string
+	Succeeded []string
+}
+
+func (err ErrGroupsFailed) Error() string {
+	names := []string{}
+	for name := range err.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("%d groups failed (%s), %d skipped", len(names), strings.Join(names, ", "), len(err.Skipped))
+}
+
+// ErrAborted is returned when the user declines to run an operation
+type ErrAborted struct {
+}
+
+func (err ErrAborted) Error() string {
+	return "aborted by user"
+}
+
+// ErrLocked is returned when another run holds the lock of the namespace
+type ErrLocked struct {
+	Namespace string
+	Holder    string
+	Command   string
+	Started   time.Time
+}
+
+func (err ErrLocked) Error() string {
+	return fmt.Sprintf("namespace %q is locked by %s running %s since %s", err.Namespace, err.Holder, err.Command, err.Started.Local().Format("2006-01-02 15:04:05"))
+}
+
+// ErrPlanChanged is returned when the resources of a namespace changed between planning an operation and running it
+type ErrPlanChanged struct {
+	Namespace string
+}
+
+func (err ErrPlanChanged) Error() string {
+	return fmt.Sprintf("the resources of namespace %q changed since they were planned, plan again", err.Namespace)
+}
+
+// ErrLockLost is returned when the lock of a run was taken over by another run or removed while the run held it
+type ErrLockLost struct {
+	Namespace string
+	Holder    string
+	Command   string
+}
+
+func (err ErrLockLost) Error() string {
+	if err.Holder == "" {
+		return fmt.Sprintf("the lock of namespace %q was removed", err.Namespace)
+	}
+	return fmt.Sprintf("the lock of namespace %q was taken over by %s running %s", err.Namespace, err.Holder, err.Command)
+}
@@ -0,0 +1,170 @@
+package project
+
+import (
+	gocontext "context"
+	"errors"
+	"os"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+)
+
+// ErrorType is the family of an error, every family exits with its own code
+type ErrorType string
+
+// ErrorType values
+const (
+	ErrorTypeGeneral            ErrorType = "error"
+	ErrorTypeConfig             ErrorType = "config"
+	ErrorTypeRender             ErrorType = "render"
+	ErrorTypeClusterUnreachable ErrorType = "cluster-unreachable"
+	ErrorTypeApplyRejected      ErrorType = "apply-rejected"
+	ErrorTypeWaitFailed         ErrorType = "wait-failed"
+	ErrorTypeWaitTimeout        ErrorType = "wait-timeout"
+	ErrorTypeLocked             ErrorType = "locked"
+	ErrorTypeAborted            ErrorType = "aborted"
+)
+
+// ExitCode of the error family. 2 is left to invalid command lines.
+func (errorType ErrorType) ExitCode() int {
+	switch errorType {
+	case ErrorTypeConfig:
+		return 3
+	case ErrorTypeRender:
+		return 4
+	case ErrorTypeClusterUnreachable:
+		return 5
+	case ErrorTypeApplyRejected:
+		return 6
+	case ErrorTypeWaitFailed:
+		return 7
+	case ErrorTypeWaitTimeout:
+		return 8
+	case ErrorTypeLocked:
+		return 9
+	case ErrorTypeAborted:
+		return 130
+	default:
+		return 1
+	}
+}
+
+// ErrorReport describes why a command failed, written as json with --error-format=json
+type ErrorReport struct {
+	Type     ErrorType      `json:"type"`
+	ExitCode int            `json:"exitCode"`
+	Resource *ErrorResource `json:"resource,omitempty"`
+	Cause    string         `json:"cause"`
+}
+
+// ErrorResource is the resource a failure is about
+type ErrorResource struct {
+	Group string `json:"group,omitempty"`
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+}
+
+// NewErrorReport classifies an error returned by ReadProject or by an operation
+func NewErrorReport(err error) *ErrorReport {
+	errorType := ErrorTypeOf(err)
+	return &ErrorReport{
+		Type:     errorType,
+		ExitCode: errorType.ExitCode(),
+		Resource: errorResource(err),
+		Cause:    errorMessage(err),
+	}
+}
+
+// ErrorTypeOf returns the family of the root cause of an error. The groups failed with --keep-going have the family
+// of their failures when they all share it.
+func ErrorTypeOf(err error) ErrorType {
+	cause := stacktrace.RootCause(err)
+	switch cause := cause.(type) {
+	case ErrAborted:
+		return ErrorTypeAborted
+	case ErrLocked, ErrLockLost, ErrPlanChanged:
+		return ErrorTypeLocked
+	case ErrWaitTimeout, kubernetes.ErrTimeout:
+		return ErrorTypeWaitTimeout
+	case ErrWaitFailed, kubernetes.ErrPodFailure:
+		return ErrorTypeWaitFailed
+	case ErrMissingDependency, ErrCyclicDependency, ErrReleaseNotFound, kubernetes.ErrInvalidWaitCondition, kubernetes.ErrUnknownBackend, *os.PathError:
+		return ErrorTypeConfig
+	case kubernetes.ErrMissingCommand:
+		return ErrorTypeClusterUnreachable
+	case kubernetes.ErrCommandExecute:
+		return commandErrorType(cause.Failure)
+	case kubernetes.ErrCommandExitCode:
+		return commandErrorType(cause.Failure)
+	case ErrGroupsFailed:
+		errorType := ErrorType("")
+		for _, groupErr := range cause.Failed {
+			groupType := ErrorTypeOf(groupErr)
+			if errorType != "" && groupType != errorType {
+				return ErrorTypeGeneral
+			}
+			errorType = groupType
+		}
+		if errorType == "" {
+			return ErrorTypeGeneral
+		}
+		return errorType
+	}
+	var renderErr utils.ErrRender
+	var parseErr ErrParse
+	switch {
+	case errors.As(cause, &renderErr):
+		return ErrorTypeRender
+	case errors.As(cause, &parseErr):
+		return ErrorTypeConfig
+	case cause == gocontext.Canceled:
+		return ErrorTypeAborted
+	case cause == gocontext.DeadlineExceeded:
+		return ErrorTypeWaitTimeout
+	case kubernetes.IsUnreachable(err):
+		return ErrorTypeClusterUnreachable
+	case kubernetes.IsRejected(err):
+		return ErrorTypeApplyRejected
+	default:
+		return ErrorTypeGeneral
+	}
+}
+
+// commandErrorType returns the family of a failed kubectl command
+func commandErrorType(failure kubernetes.CommandFailure) ErrorType {
+	switch {
+	case failure == kubernetes.CommandFailureUnreachable:
+		return ErrorTypeClusterUnreachable
+	case failure.Rejected():
+		return ErrorTypeApplyRejected
+	default:
+		return ErrorTypeGeneral
+	}
+}
+
+// errorResource returns the resource named by the root cause of an error, if any
+func errorResource(err error) *ErrorResource {
+	switch cause := stacktrace.RootCause(err).(type) {
+	case ErrWaitTimeout:
+		return &ErrorResource{Kind: cause.Kind, Name: cause.Name}
+	case ErrWaitFailed:
+		return &ErrorResource{Kind: cause.Kind, Name: cause.Name}
+	case kubernetes.ErrNotExist:
+		return &ErrorResource{Kind: cause.Kind, Name: cause.Name}
+	case kubernetes.ErrPodFailure:
+		return &ErrorResource{Kind: "pod", Name: cause.Pod}
+	case ErrGroupsFailed:
+		if len(cause.Failed) != 1 {
+			return nil
+		}
+		for group, groupErr := range cause.Failed {
+			resource := errorResource(groupErr)
+			if resource != nil {
+				resource.Group = group
+			}
+			return resource
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package project
+
+import (
+	gocontext "context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type ErrorReportTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *ErrorReportTestSuite) TestConfigAndRender() {
+	_, err := ReadProject(filepath.Join("..", "test-resources", "not-exist.yml"), "", "", "", nil, nil, nil, nil)
+	require.NotNil(s.T(), err)
+	require.Equal(s.T(), ErrorTypeConfig, ErrorTypeOf(err))
+	require.Equal(s.T(), ErrorTypeConfig, ErrorTypeOf(stacktrace.Propagate(ErrCyclicDependency{"a"}, "cyclic")))
+	_, err = utils.ExecuteTemplateContent(".", []byte("{{ .broken"), nil)
+	require.NotNil(s.T(), err)
+	require.Equal(s.T(), ErrorTypeRender, ErrorTypeOf(err))
+	require.Equal(s.T(), 4, NewErrorReport(err).ExitCode)
+
+	dir, err := ioutil.TempDir("", "rivendell-error")
+	require.Nil(s.T(), err)
+	defer os.RemoveAll(dir)
+	projectFile := filepath.Join(dir, "project.yml")
+	err = ioutil.WriteFile(projectFile, []byte("name: [broken"), 0644)
+	require.Nil(s.T(), err)
+	_, err = ReadProjectConfig(projectFile, nil)
+	require.Equal(s.T(), ErrorTypeConfig, ErrorTypeOf(err))
+	err = ioutil.WriteFile(projectFile, []byte("name: {{ .missing }}"), 0644)
+	require.Nil(s.T(), err)
+	_, err = ReadProjectConfig(projectFile, map[string]string{})
+	require.Equal(s.T(), ErrorTypeRender, ErrorTypeOf(err))
+	require.Equal(s.T(), ErrorTypeGeneral, ErrorTypeOf(errors.New("template: not a template error")))
+}
+
+func (s *ErrorReportTestSuite) TestCluster() {
+	err := stacktrace.Propagate(kubernetes.ErrCommandExecute{ExitCode: 1, Failure: kubernetes.CommandFailureUnreachable}, "error execute command")
+	require.Equal(s.T(), ErrorTypeClusterUnreachable, ErrorTypeOf(err))
+	err = stacktrace.Propagate(kubernetes.ErrMissingCommand{Command: "kubectl"}, "missing command")
+	require.Equal(s.T(), ErrorTypeClusterUnreachable, ErrorTypeOf(err))
+	err = stacktrace.Propagate(apierrors.NewUnauthorized("bad token"), "kubernetes api error")
+	require.Equal(s.T(), ErrorTypeClusterUnreachable, ErrorTypeOf(err))
+	err = stacktrace.Propagate(kubernetes.ErrCommandExitCode{ExitCode: 1, Failure: kubernetes.CommandFailureUnreachable}, "command execute error")
+	require.Equal(s.T(), ErrorTypeClusterUnreachable, ErrorTypeOf(err))
+	err = stacktrace.Propagate(kubernetes.ErrCommandExecute{ExitCode: 1, Failure: kubernetes.CommandFailureRejected}, "error execute command")
+	require.Equal(s.T(), ErrorTypeApplyRejected, ErrorTypeOf(err))
+	err = stacktrace.Propagate(kubernetes.ErrCommandExitCode{ExitCode: 1, Failure: kubernetes.CommandFailureNotFound}, "command execute error")
+	require.Equal(s.T(), ErrorTypeApplyRejected, ErrorTypeOf(err))
+	err = stacktrace.Propagate(kubernetes.ErrCommandExecute{ExitCode: 1, Output: "Unable to connect to the server"}, "error execute command")
+	require.Equal(s.T(), ErrorTypeGeneral, ErrorTypeOf(err))
+	err = stacktrace.Propagate(apierrors.NewForbidden(schema.GroupResource{Resource: "deployments"}, "nginx", errors.New("denied")), "kubernetes api error")
+	require.Equal(s.T(), ErrorTypeApplyRejected, ErrorTypeOf(err))
+	require.Equal(s.T(), 6, NewErrorReport(err).ExitCode)
+}
+
+func (s *ErrorReportTestSuite) TestWaitAndAbort() {
+	report := NewErrorReport(stacktrace.Propagate(ErrWaitTimeout{"pod1", "pod"}, "wait timeout"))
+	require.Equal(s.T(), ErrorTypeWaitTimeout, report.Type)
+	require.Equal(s.T(), 8, report.ExitCode)
+	require.Equal(s.T(), &ErrorResource{Kind: "pod", Name: "pod1"}, report.Resource)
+	require.Equal(s.T(), `wait timeout for pod "pod1"`, report.Cause)
+	report = NewErrorReport(stacktrace.Propagate(kubernetes.ErrPodFailure{Pod: "pod1", Reason: "ImagePullBackOff"}, "pod failed"))
+	require.Equal(s.T(), ErrorTypeWaitFailed, report.Type)
+	require.Equal(s.T(), &ErrorResource{Kind: "pod", Name: "pod1"}, report.Resource)
+	require.Equal(s.T(), ErrorTypeAborted, ErrorTypeOf(stacktrace.Propagate(gocontext.Canceled, "interrupted")))
+	require.Equal(s.T(), 130, NewErrorReport(ErrAborted{}).ExitCode)
+	require.Equal(s.T(), 1, NewErrorReport(errors.New("unknown")).ExitCode)
+}
+
+func (s *ErrorReportTestSuite) TestGroupsFailed() {
+	failed := ErrGroupsFailed{Failed: map[string]error{
+		"services": stacktrace.Propagate(ErrWaitFailed{"pod1", "pod"}, "wait failed"),
+	}}
+	report := NewErrorReport(stacktrace.Propagate(failed, "groups failed"))
+	require.Equal(s.T(), ErrorTypeWaitFailed, report.Type)
+	require.Equal(s.T(), &ErrorResource{Group: "services", Kind: "pod", Name: "pod1"}, report.Resource)
+	failed.Failed["configs"] = stacktrace.Propagate(kubernetes.ErrCommandExecute{ExitCode: 1, Failure: kubernetes.CommandFailureRejected}, "error execute command")
+	report = NewErrorReport(stacktrace.Propagate(failed, "groups failed"))
+	require.Equal(s.T(), ErrorTypeGeneral, report.Type)
+	require.Nil(s.T(), report.Resource)
+}
+
+func (s *ErrorReportTestSuite) TestWaitFailure() {
+	s.cluster.SetOutcome(s.testNamespace, "pod", "pod1", kubernetes.FakeOutcomeFailed)
+	p := s.readProject("pod-wait-failed-in-project", nil)
+	timings := p.RecordTimings()
+	err := p.Up(gocontext.Background())
+	require.NotNil(s.T(), err)
+	report := NewErrorReport(err)
+	require.Equal(s.T(), ErrorTypeWaitFailed, report.Type)
+	require.Equal(s.T(), 7, report.ExitCode)
+	require.Equal(s.T(), &ErrorResource{Group: "services", Kind: "pod", Name: "pod1"}, timings.FailedResource())
+	s.down(p)
+}
+
+func TestErrorReport(t *testing.T) {
+	suite.Run(t, new(ErrorReportTestSuite))
+}
@@ -0,0 +1,196 @@
+package project
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/palantir/stacktrace"
+)
+
+// EventType tells what step of an operation an event reports
+type EventType string
+
+// EventType values
+const (
+	EventOperationStarted  EventType = "operation.started"
+	EventOperationFinished EventType = "operation.finished"
+	EventGroupStarted      EventType = "group.started"
+	EventGroupFinished     EventType = "group.finished"
+	EventGroupSkipped      EventType = "group.skipped"
+	EventResourceCreated   EventType = "resource.created"
+	EventResourceExisted   EventType = "resource.existed"
+	EventResourceUpdated   EventType = "resource.updated"
+	EventResourceUnchanged EventType = "resource.unchanged"
+	EventResourceSkipped   EventType = "resource.skipped"
+	EventResourceDeleted   EventType = "resource.deleted"
+	EventResourceNotFound  EventType = "resource.not-found"
+	EventResourceFailed    EventType = "resource.failed"
+	EventWaitStarted       EventType = "wait.started"
+	EventWaitSucceeded     EventType = "wait.succeeded"
+	EventWaitFailed        EventType = "wait.failed"
+	EventWaitTimedOut      EventType = "wait.timed-out"
+)
+
+// Event is a step of an operation, written as a line of json by SetEventOutput
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      EventType `json:"type"`
+	Project   string    `json:"project"`
+	Operation string    `json:"operation,omitempty"`
+	Group     string    `json:"group,omitempty"`
+	Kind      string    `json:"kind,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	// For is the condition of a wait, see WaitConfig
+	For string `json:"for,omitempty"`
+	// DurationMs is set on finished groups and operations, and on the results of resources and waits
+	DurationMs int64  `json:"durationMs,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// eventEmitter passes the events of a project to its sinks, one event at a time. A nil emitter discards them.
+type eventEmitter struct {
+	mu        sync.Mutex
+	sinks     []func(event *Event)
+	project   string
+	operation string
+}
+
+// SetEventOutput writes an Event as a line of json to out for every step of up, down, update, upgrade, rollback,
+// restart and prune
+func (p *Project) SetEventOutput(out io.Writer) *Project {
+	p.addEventSink(func(event *Event) {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return
+		}
+		_, _ = out.Write(append(line, '\n'))
+	})
+	return p
+}
+
+func (p *Project) addEventSink(sink func(event *Event)) {
+	if p.events == nil {
+		p.events = &eventEmitter{project: p.name}
+		p.resourceGraph.events = p.events
+	}
+	p.events.mu.Lock()
+	defer p.events.mu.Unlock()
+	p.events.sinks = append(p.events.sinks, sink)
+}
+
+func (e *eventEmitter) emit(event *Event) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	event.Time = time.Now().UTC()
+	event.Project = e.project
+	event.Operation = e.operation
+	for _, sink := range e.sinks {
+		sink(event)
+	}
+}
+
+// startOperation emits the start of an operation, the returned function emits its end
+func (e *eventEmitter) startOperation(operation string) func(err error) {
+	if e == nil {
+		return func(err error) {}
+	}
+	e.mu.Lock()
+	e.operation = operation
+	e.mu.Unlock()
+	e.emit(&Event{Type: EventOperationStarted})
+	start := time.Now()
+	return func(err error) {
+		e.emit(&Event{Type: EventOperationFinished, DurationMs: since(start), Error: errorMessage(err)})
+	}
+}
+
+func (e *eventEmitter) groupStarted(g *ResourceGroup) {
+	e.emit(&Event{Type: EventGroupStarted, Group: g.Name})
+}
+
+func (e *eventEmitter) groupFinished(g *ResourceGroup, start time.Time, err error) {
+	e.emit(&Event{Type: EventGroupFinished, Group: g.Name, DurationMs: since(start), Error: errorMessage(err)})
+}
+
+// resource emits the result of a step on a resource, EventResourceFailed when err is not nil
+func (e *eventEmitter) resource(eventType EventType, group, kind, name string, start time.Time, err error) {
+	if err != nil {
+		eventType = EventResourceFailed
+	}
+	e.emit(&Event{Type: eventType, Group: group, Kind: kind, Name: name, DurationMs: since(start), Error: errorMessage(err)})
+}
+
+func (e *eventEmitter) waitStarted(group, kind, name, waitFor string) {
+	e.emit(&Event{Type: EventWaitStarted, Group: group, Kind: kind, Name: name, For: waitFor})
+}
+
+// waitFinished emits the result of a wait, telling timeouts from failures
+func (e *eventEmitter) waitFinished(group, kind, name, waitFor string, start time.Time, err error) {
+	eventType := EventWaitSucceeded
+	if err != nil {
+		eventType = EventWaitFailed
+		if _, ok := stacktrace.RootCause(err).(ErrWaitTimeout); ok {
+			eventType = EventWaitTimedOut
+		}
+	}
+	e.emit(&Event{Type: eventType, Group: group, Kind: kind, Name: name, For: waitFor, DurationMs: since(start), Error: errorMessage(err)})
+}
+
+func createEventType(exists bool) EventType {
+	if exists {
+		return EventResourceExisted
+	}
+	return EventResourceCreated
+}
+
+func deleteEventType(exists bool) EventType {
+	if exists {
+		return EventResourceDeleted
+	}
+	return EventResourceNotFound
+}
+
+func updateEventType(updateStatus kubernetes.UpdateStatus) EventType {
+	switch updateStatus {
+	case kubernetes.UpdateStatusNotExist:
+		return EventResourceNotFound
+	case kubernetes.UpdateStatusSkipped:
+		return EventResourceSkipped
+	default:
+		return EventResourceUpdated
+	}
+}
+
+func batchEventType(result *kubernetes.BatchResult) EventType {
+	switch {
+	case result.Exists && result.Action == "":
+		return EventResourceExisted
+	case result.UpdateStatus == kubernetes.UpdateStatusSkipped:
+		return EventResourceSkipped
+	case result.UpdateStatus == kubernetes.UpdateStatusNotExist && result.Action == "":
+		return EventResourceNotFound
+	case result.Action == "unchanged":
+		return EventResourceUnchanged
+	case result.Action == "created":
+		return EventResourceCreated
+	default:
+		return EventResourceUpdated
+	}
+}
+
+func since(start time.Time) int64 {
+	return time.Since(start).Milliseconds()
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return stacktrace.RootCause(err).Error()
+}
@@ -0,0 +1,84 @@
+package project
+
+import (
+	"bytes"
+	gocontext "context"
+	"testing"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type EventsTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *EventsTestSuite) TestEvents() {
+	out := &bytes.Buffer{}
+	p := s.readProject("wait-pod", nil).SetEventOutput(out)
+	err := p.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+	events := s.readEvents(out)
+	require.Equal(s.T(), EventOperationStarted, events[0].Type)
+	require.Equal(s.T(), "up", events[0].Operation)
+	last := events[len(events)-1]
+	require.Equal(s.T(), EventOperationFinished, last.Type)
+	require.Empty(s.T(), last.Error)
+	types := make(map[string]EventType)
+	for _, event := range events {
+		require.Equal(s.T(), p.Name(), event.Project)
+		types[string(event.Type)+" "+event.Group+" "+event.Kind+" "+event.Name] = event.Type
+	}
+	require.Contains(s.T(), types, "resource.created  Namespace fake-ns")
+	require.Contains(s.T(), types, "group.started jobs1  ")
+	require.Contains(s.T(), types, "group.finished services  ")
+	require.Contains(s.T(), types, "wait.started services job job1")
+	require.Contains(s.T(), types, "wait.succeeded services job job1")
+
+	out.Reset()
+	s.down(p)
+	events = s.readEvents(out)
+	require.Equal(s.T(), "down", events[0].Operation)
+	deleted := 0
+	for _, event := range events {
+		if event.Type == EventResourceDeleted {
+			deleted++
+		}
+	}
+	require.True(s.T(), deleted > 0)
+
+	s.cluster.SetOutcome(s.testNamespace, "pod", "pod1", kubernetes.FakeOutcomeFailed)
+	out.Reset()
+	p = s.readProject("pod-wait-failed-in-project", nil).SetEventOutput(out)
+	err = p.Up(gocontext.Background())
+	require.NotNil(s.T(), err)
+	events = s.readEvents(out)
+	failed := false
+	for _, event := range events {
+		if event.Type == EventWaitFailed && event.Name == "pod1" {
+			failed = true
+			require.NotEmpty(s.T(), event.Error)
+		}
+	}
+	require.True(s.T(), failed)
+	require.NotEmpty(s.T(), events[len(events)-1].Error)
+	s.down(p)
+}
+
+func (s *EventsTestSuite) TestHumanOutputKeptOutOfEvents() {
+	events := &bytes.Buffer{}
+	human := &bytes.Buffer{}
+	p := s.readProject("wait-pod", nil).SetEventOutput(events).SetOutput(human)
+	err := p.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+	s.readEvents(events)
+	require.Contains(s.T(), human.String(), `Creating Job "job1" in group "jobs1"`)
+	require.Contains(s.T(), human.String(), "namespace/fake-ns created")
+	require.NotContains(s.T(), events.String(), "====> Success")
+	s.down(p)
+}
+
+func TestEvents(t *testing.T) {
+	suite.Run(t, new(EventsTestSuite))
+}
@@ -0,0 +1,83 @@
+package project
+
+import (
+	"bytes"
+	gocontext "context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeClusterSuite runs projects of test-resources/command-test against a new in-memory cluster for every test.
+// The suites of the commands embed it.
+type fakeClusterSuite struct {
+	suite.Suite
+	resourceRoot  string
+	testNamespace string
+	cluster       *kubernetes.FakeCluster
+}
+
+func (s *fakeClusterSuite) SetupTest() {
+	s.resourceRoot = "../test-resources"
+	s.testNamespace = "fake-ns"
+	s.cluster = kubernetes.NewFakeCluster()
+	kubernetes.RegisterBackend(kubernetes.BackendFake, s.cluster.Factory)
+	kubernetes.DefaultBackend = kubernetes.BackendFake
+}
+
+func (s *fakeClusterSuite) TearDownTest() {
+	kubernetes.DefaultBackend = kubernetes.BackendKubectl
+	DiagnosticsDir = ""
+}
+
+func (s *fakeClusterSuite) readProject(name string, variables map[string]string) *Project {
+	if variables == nil {
+		variables = make(map[string]string)
+	}
+	projectFile := filepath.Join(s.resourceRoot, "command-test", name, "project.yml")
+	p, err := ReadProject(projectFile, s.testNamespace, "", "", variables, []string{}, nil, nil)
+	require.Nil(s.T(), err)
+	return p
+}
+
+// up reads a project and creates it
+func (s *fakeClusterSuite) up(name string, variables map[string]string) *Project {
+	p := s.readProject(name, variables)
+	err := p.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+	return p
+}
+
+// down deletes a project with its namespace
+func (s *fakeClusterSuite) down(p *Project) {
+	err := p.Down(gocontext.Background(), true, true)
+	require.Nil(s.T(), err)
+}
+
+func (s *fakeClusterSuite) kubeContext(p *Project) *kubernetes.Context {
+	kubeContext, err := p.newKubeContext(gocontext.Background())
+	require.Nil(s.T(), err)
+	return kubeContext
+}
+
+func (s *fakeClusterSuite) exists(kubeContext *kubernetes.Context, name, kind string) bool {
+	exists, err := kubeContext.Resource().Exists(name, kind)
+	require.Nil(s.T(), err)
+	return exists
+}
+
+func (s *fakeClusterSuite) readEvents(out *bytes.Buffer) []*Event {
+	events := []*Event{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		event := &Event{}
+		err := json.Unmarshal([]byte(line), event)
+		require.Nil(s.T(), err, line)
+		events = append(events, event)
+	}
+	require.NotEmpty(s.T(), events)
+	return events
+}
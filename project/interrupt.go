@@ -0,0 +1,200 @@
+package project
+
+import (
+	gocontext "context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+)
+
+// progress records the resources an operation went through, to tell what remains when it is interrupted.
+// Groups walked concurrently share it.
+type progress struct {
+	mu      sync.Mutex
+	done    map[*Resource]bool
+	current map[*Resource]bool
+	// applied are the done resources which were applied: created, configured or unchanged
+	applied map[*Resource]bool
+}
+
+func newProgress() *progress {
+	return &progress{
+		done:    make(map[*Resource]bool),
+		current: make(map[*Resource]bool),
+		applied: make(map[*Resource]bool),
+	}
+}
+
+// track wraps a resource function to record the resources it processed
+func (pr *progress) track(f ResourceFunc) ResourceFunc {
+	return func(ctx gocontext.Context, r *Resource, g *ResourceGroup) error {
+		pr.mu.Lock()
+		pr.current[r] = true
+		pr.mu.Unlock()
+		err := f(ctx, r, g)
+		if err != nil {
+			return err
+		}
+		pr.mu.Lock()
+		defer pr.mu.Unlock()
+		pr.done[r] = true
+		delete(pr.current, r)
+		return nil
+	}
+}
+
+// markDone records the resources of a group as done without processing them, like the groups skipped by a resume
+func (pr *progress) markDone(g *ResourceGroup) {
+	if g == nil {
+		return
+	}
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	for _, r := range g.allResources() {
+		pr.done[r] = true
+	}
+}
+
+func (pr *progress) isDone(r *Resource) bool {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	return pr.done[r]
+}
+
+// markApplied records a resource as applied, unlike an existing resource left by up or a missing one skipped by update
+func (pr *progress) markApplied(r *Resource) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	pr.applied[r] = true
+}
+
+// markBatchApplied records the resources of a group applied by a batch, results are in the order of the resources
+func (pr *progress) markBatchApplied(g *ResourceGroup, results []*kubernetes.BatchResult) {
+	resources := g.allResources()
+	for i, result := range results {
+		if i < len(resources) && result.Action != "" {
+			pr.markApplied(resources[i])
+		}
+	}
+}
+
+func (pr *progress) isApplied(r *Resource) bool {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	return pr.applied[r]
+}
+
+// trackGroup wraps a group function to record the resources of the groups it processed
+func (pr *progress) trackGroup(f GroupFunc) GroupFunc {
+	return func(ctx gocontext.Context, g *ResourceGroup) error {
+		resources := g.allResources()
+		pr.mu.Lock()
+		for _, r := range resources {
+			pr.current[r] = true
+		}
+		pr.mu.Unlock()
+		err := f(ctx, g)
+		if err != nil {
+			return err
+		}
+		pr.mu.Lock()
+		defer pr.mu.Unlock()
+		for _, r := range resources {
+			pr.done[r] = true
+			delete(pr.current, r)
+		}
+		return nil
+	}
+}
+
+// reportFailure prints what an interrupted operation did and left undone, or diagnostics of a failed resource
+func (p *Project) reportFailure(ctx gocontext.Context, kubeContext *kubernetes.Context, err error, pr *progress, backward bool) {
+	if err == nil {
+		return
+	}
+	if ctx.Err() != nil {
+		p.printInterruptSummary(pr, backward)
+		return
+	}
+	if groupsFailed, ok := stacktrace.RootCause(err).(ErrGroupsFailed); ok {
+		for _, groupErr := range groupsFailed.Failed {
+			diagnoseFailure(kubeContext, groupErr)
+		}
+		p.printGroupsReport(err)
+		return
+	}
+	diagnoseFailure(kubeContext, err)
+}
+
+// printGroupsReport prints the failed, skipped and succeeded groups of a walk which went on after failures
+func (p *Project) printGroupsReport(err error) {
+	out := p.Output()
+	groupsFailed, ok := stacktrace.RootCause(err).(ErrGroupsFailed)
+	if !ok {
+		return
+	}
+	names := []string{}
+	for name := range groupsFailed.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	utils.Warnf(out, "%d groups failed, %d skipped, %d succeeded", len(groupsFailed.Failed), len(groupsFailed.Skipped), len(groupsFailed.Succeeded))
+	for _, name := range names {
+		fmt.Fprintf(out, " - [failed] %s: %s\n", name, stacktrace.RootCause(groupsFailed.Failed[name]))
+	}
+	names = names[:0]
+	for name := range groupsFailed.Skipped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, " - [skipped] %s: after failed group %s\n", name, groupsFailed.Skipped[name])
+	}
+	for _, name := range groupsFailed.Succeeded {
+		fmt.Fprintf(out, " - [succeeded] %s\n", name)
+	}
+}
+
+func (p *Project) printInterruptSummary(pr *progress, backward bool) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	done := []*Resource{}
+	aborted := []*Resource{}
+	remaining := []*Resource{}
+	walk := p.resourceGraph.WalkForward
+	if backward {
+		walk = p.resourceGraph.WalkBackward
+	}
+	walk(gocontext.Background(), func(g *ResourceGroup) error {
+		for _, r := range g.allResources() {
+			if pr.done[r] {
+				done = append(done, r)
+			} else if pr.current[r] {
+				aborted = append(aborted, r)
+			} else {
+				remaining = append(remaining, r)
+			}
+		}
+		return nil
+	})
+	out := p.Output()
+	utils.Warnf(out, "Interrupted: %d resources done, %d remaining", len(done), len(remaining))
+	for _, r := range done {
+		fmt.Fprintf(out, " - [done] %s %q\n", r.Kind, r.Name)
+	}
+	for _, r := range aborted {
+		fmt.Fprintf(out, " - [aborted] %s %q\n", r.Kind, r.Name)
+	}
+	for _, r := range remaining {
+		fmt.Fprintf(out, " - [remaining] %s %q\n", r.Kind, r.Name)
+	}
+}
+
+func isInterrupted(err error) bool {
+	cause := stacktrace.RootCause(err)
+	return cause == gocontext.Canceled || cause == gocontext.DeadlineExceeded
+}
@@ -0,0 +1,37 @@
+package project
+
+import (
+	gocontext "context"
+	"testing"
+	"time"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type InterruptTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *InterruptTestSuite) TestUpInterrupted() {
+	s.cluster.SetOutcome(s.testNamespace, "deployment", "redis", kubernetes.FakeOutcomeStuck)
+	p := s.readProject("up-down", nil)
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+	err := p.Up(ctx)
+	require.NotNil(s.T(), err)
+	require.Equal(s.T(), gocontext.Canceled, stacktrace.RootCause(err))
+	kubeContext := s.kubeContext(p)
+	require.True(s.T(), s.exists(kubeContext, "redis", "deployment"))
+	require.False(s.T(), s.exists(kubeContext, "nginx", "deployment"), "resources after the interruption should not be created")
+	s.down(p)
+}
+
+func TestInterrupt(t *testing.T) {
+	suite.Run(t, new(InterruptTestSuite))
+}
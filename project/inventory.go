@@ -0,0 +1,184 @@
+package project
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"strings"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+)
+
+const inventoryKey = "inventory"
+
+// defaultPruneExcludeKinds are never pruned unless the project configures its own list
+var defaultPruneExcludeKinds = []string{"persistentvolumeclaim"}
+
+// InventoryEntry is an object applied by rivendell for a project
+type InventoryEntry struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Group string `json:"group"`
+}
+
+// inventory lists the objects applied for a project in a namespace, in the order they were applied.
+// It is stored in a ConfigMap next to the objects.
+type inventory struct {
+	Project  string            `json:"project"`
+	DeployID string            `json:"deployId"`
+	Entries  []*InventoryEntry `json:"objects"`
+}
+
+type inventoryConfigMap struct {
+	Data map[string]string `json:"data"`
+}
+
+func (e *InventoryEntry) key() string {
+	return inventoryEntryKey(e.Kind, e.Name)
+}
+
+func inventoryEntryKey(kind, name string) string {
+	return strings.ToLower(kind) + "/" + name
+}
+
+// identityName is the identity of the project in the names of the objects rivendell keeps for it
+func (p *Project) identityName() string {
+	name := strings.ToLower(labelValue(p.identity))
+	if name == "" {
+		name = "default"
+	}
+	return name
+}
+
+func (p *Project) inventoryName() string {
+	return "rivendell-inventory-" + p.identityName()
+}
+
+// readInventory returns the inventory of the project, empty if nothing was recorded yet
+func (p *Project) readInventory(kubeContext *kubernetes.Context) (*inventory, error) {
+	inv := &inventory{Project: p.name, Entries: []*InventoryEntry{}}
+	manifest, err := kubeContext.Resource().Manifest(p.inventoryName(), "configmap")
+	if err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return inv, nil
+	}
+	configMap := &inventoryConfigMap{}
+	err = json.Unmarshal(manifest, configMap)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode inventory %q", p.inventoryName())
+	}
+	content, ok := configMap.Data[inventoryKey]
+	if !ok {
+		return inv, nil
+	}
+	err = json.Unmarshal([]byte(content), inv)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode inventory %q", p.inventoryName())
+	}
+	return inv, nil
+}
+
+// writeInventory stores the inventory of the project, an empty inventory is deleted
+func (p *Project) writeInventory(kubeContext *kubernetes.Context, inv *inventory) error {
+	if len(inv.Entries) == 0 {
+		_, err := kubeContext.Resource().Delete(p.inventoryName(), "configmap")
+		return err
+	}
+	inv.Project = p.name
+	inv.DeployID = p.deployID
+	content, err := json.Marshal(inv)
+	if err != nil {
+		return stacktrace.Propagate(err, "cannot encode inventory")
+	}
+	configMap := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name": p.inventoryName(),
+			"labels": map[string]string{
+				LabelManagedBy: managedByRivendell,
+				LabelProject:   labelValue(p.name),
+			},
+		},
+		"data": map[string]string{
+			inventoryKey: string(content),
+		},
+	}
+	manifest, err := json.Marshal(configMap)
+	if err != nil {
+		return stacktrace.Propagate(err, "cannot encode inventory")
+	}
+	return kubeContext.Resource().Put(string(manifest))
+}
+
+// recordApplied adds the resources an operation applied to the inventory, in the order of the graph.
+// Objects recorded before and not rendered anymore are kept until they are pruned.
+func (p *Project) recordApplied(kubeContext *kubernetes.Context, pr *progress) error {
+	previous, err := p.readInventory(kubeContext)
+	if err != nil {
+		return err
+	}
+	inv := &inventory{Entries: []*InventoryEntry{}}
+	recorded := make(map[string]bool)
+	p.resourceGraph.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
+		for _, r := range g.allResources() {
+			entry := &InventoryEntry{Kind: r.Kind, Name: r.Name, Group: g.Name}
+			if pr.isApplied(r) && !recorded[entry.key()] {
+				inv.Entries = append(inv.Entries, entry)
+				recorded[entry.key()] = true
+			}
+		}
+		return nil
+	})
+	for _, entry := range previous.Entries {
+		if !recorded[entry.key()] {
+			inv.Entries = append(inv.Entries, entry)
+			recorded[entry.key()] = true
+		}
+	}
+	return p.writeInventory(kubeContext, inv)
+}
+
+// saveInventory records the resources an operation applied, even when it failed or was interrupted.
+// It returns the error of the operation first.
+func (p *Project) saveInventory(kubeContext *kubernetes.Context, pr *progress, err error) error {
+	recordErr := p.recordApplied(kubeContext.WithContext(gocontext.Background()), pr)
+	if err != nil {
+		if recordErr != nil {
+			utils.Error(recordErr)
+		}
+		return err
+	}
+	return recordErr
+}
+
+// recordDeleted removes the resources an operation deleted from the inventory
+func (p *Project) recordDeleted(kubeContext *kubernetes.Context, deleted map[string]bool) error {
+	previous, err := p.readInventory(kubeContext)
+	if err != nil {
+		return err
+	}
+	inv := &inventory{Entries: []*InventoryEntry{}}
+	for _, entry := range previous.Entries {
+		if !deleted[entry.key()] {
+			inv.Entries = append(inv.Entries, entry)
+		}
+	}
+	return p.writeInventory(kubeContext, inv)
+}
+
+// pruneExcludeKinds returns the kinds which are never pruned
+func (p *Project) pruneExcludeKinds() map[string]bool {
+	kinds := defaultPruneExcludeKinds
+	if p.config != nil && p.config.Prune != nil && p.config.Prune.ExcludeKinds != nil {
+		kinds = p.config.Prune.ExcludeKinds
+	}
+	excluded := make(map[string]bool)
+	for _, kind := range kinds {
+		excluded[strings.ToLower(kind)] = true
+	}
+	return excluded
+}
@@ -0,0 +1,383 @@
+package project
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	lockName          = "rivendell-lock"
+	lockKey           = "lock"
+	lockRetryInterval = 5 * time.Second
+)
+
+// LockTTL is how long the lock of a namespace lasts when its holder stops renewing it
+var LockTTL = 2 * time.Minute
+
+// LockInfo is the holder of the lock of a namespace, stored in the rivendell-lock ConfigMap of the namespace
+type LockInfo struct {
+	Holder     string    `json:"holder"`
+	Command    string    `json:"command"`
+	Project    string    `json:"project"`
+	DeployID   string    `json:"deployId"`
+	Started    time.Time `json:"started"`
+	Renewed    time.Time `json:"renewed"`
+	TTLSeconds int       `json:"ttlSeconds"`
+}
+
+// Expired tells if the holder stopped renewing the lock for longer than its TTL
+func (info *LockInfo) Expired(now time.Time) bool {
+	return now.After(info.Renewed.Add(time.Duration(info.TTLSeconds) * time.Second))
+}
+
+func (info *LockInfo) heldBy(other *LockInfo) bool {
+	return info.Holder == other.Holder && info.DeployID == other.DeployID
+}
+
+// Lock is the lock of a namespace held by a run, renewed every third of its TTL until released. The lock is lost when
+// another run took it over or it was removed, the context of the lock is then cancelled and Err returns ErrLockLost.
+type Lock struct {
+	kubeContext *kubernetes.Context
+	namespace   string
+	info        *LockInfo
+	version     string
+	err         error
+	lost        chan struct{}
+	stop        chan struct{}
+	done        chan struct{}
+	releaseOnce sync.Once
+}
+
+// Lock takes the lock of the namespace of the project for a command. When another run holds it, Lock waits up to
+// wait for it to be released or to expire. A project without a namespace locks the default namespace of its kubernetes
+// context. A namespace which does not exist is not locked, except by up which creates it first. ctx aborts taking the
+// lock, the lock is renewed and released regardless of it so that an interrupted run still releases its lock.
+func (p *Project) Lock(ctx gocontext.Context, command string, wait time.Duration) (*Lock, error) {
+	kubeContext, err := p.newKubeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.namespace != "" {
+		exists, err := kubeContext.Namespace().Exists()
+		if err != nil {
+			return nil, err
+		}
+		if !exists && command != OperationUp {
+			return &Lock{}, nil
+		}
+		if !exists {
+			err = p.createNamespace(kubeContext)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	now := time.Now().UTC()
+	l := &Lock{
+		kubeContext: kubeContext.WithContext(gocontext.Background()),
+		namespace:   kubeContext.Namespace().Name(),
+		info: &LockInfo{
+			Holder:     lockHolder(),
+			Command:    command,
+			Project:    p.name,
+			DeployID:   p.deployID,
+			Started:    now,
+			Renewed:    now,
+			TTLSeconds: int(LockTTL.Seconds()),
+		},
+	}
+	deadline := time.Now().Add(wait)
+	waiting := false
+	for {
+		current, err := l.tryAcquire(kubeContext)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			break
+		}
+		lockedErr := ErrLocked{Namespace: l.namespace, Holder: current.Holder, Command: current.Command, Started: current.Started}
+		if !time.Now().Before(deadline) {
+			return nil, stacktrace.Propagate(lockedErr, "cannot lock namespace")
+		}
+		if !waiting {
+			utils.Warnf(kubeContext.Output(), "%s, waiting for the lock", lockedErr.Error())
+			waiting = true
+		}
+		retry := lockRetryInterval
+		if remaining := time.Until(deadline); remaining < retry {
+			retry = remaining
+		}
+		select {
+		case <-ctx.Done():
+			return nil, stacktrace.Propagate(ctx.Err(), "interrupted while waiting for the lock of namespace %q", l.namespace)
+		case <-time.After(retry):
+		}
+	}
+	l.lost = make(chan struct{})
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	go l.heartbeat()
+	return l, nil
+}
+
+// tryAcquire takes the lock unless another run holds it, it returns that run. A free lock is created, an expired one
+// is replaced on the condition that it was not modified since it was read, so of two runs racing for the lock only one
+// writes it. The other one reads the lock again. kubeContext carries the context of the command.
+func (l *Lock) tryAcquire(kubeContext *kubernetes.Context) (*LockInfo, error) {
+	for {
+		current, version, err := readLock(kubeContext)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && !current.heldBy(l.info) && !current.Expired(time.Now()) {
+			return current, nil
+		}
+		if version == "" {
+			version, err = createLock(kubeContext, l.info)
+		} else {
+			version, err = replaceLock(kubeContext, l.info, version)
+		}
+		if kubernetes.IsAlreadyExists(err) || kubernetes.IsConflict(err) || kubernetes.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		l.version = version
+		if current != nil && !current.heldBy(l.info) {
+			utils.Warnf(kubeContext.Output(), "Took over the lock of namespace %q held by %s running %s, expired since %s", l.namespace, current.Holder, current.Command, current.Renewed.Add(time.Duration(current.TTLSeconds)*time.Second).Local().Format("2006-01-02 15:04:05"))
+		}
+		utils.Infof(kubeContext.Output(), "Locked namespace %q", l.namespace)
+		return nil, nil
+	}
+}
+
+// heartbeat renews the lock until it is released, or until it is lost
+func (l *Lock) heartbeat() {
+	defer close(l.done)
+	ticker := time.NewTicker(LockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+		renewed := *l.info
+		renewed.Renewed = time.Now().UTC()
+		version, err := replaceLock(l.kubeContext, &renewed, l.version)
+		if kubernetes.IsConflict(err) || kubernetes.IsNotExist(err) {
+			l.lose()
+			return
+		}
+		if err != nil {
+			utils.Warnf(l.kubeContext.Output(), "Cannot renew the lock of namespace %q: %s", l.namespace, stacktrace.RootCause(err))
+			continue
+		}
+		l.info = &renewed
+		l.version = version
+	}
+}
+
+// lose records that another run took the lock over or that it was removed, and cancels the context of the lock
+func (l *Lock) lose() {
+	lostErr := ErrLockLost{Namespace: l.namespace}
+	current, _, err := readLock(l.kubeContext)
+	if err == nil && current != nil {
+		lostErr.Holder = current.Holder
+		lostErr.Command = current.Command
+	}
+	utils.Warnf(l.kubeContext.Output(), "%s", lostErr.Error())
+	l.err = stacktrace.Propagate(lostErr, "cannot renew the lock")
+	close(l.lost)
+}
+
+// Context returns a context cancelled when the lock is lost, the operations run under the lock are given it
+func (l *Lock) Context(ctx gocontext.Context) gocontext.Context {
+	if l == nil || l.lost == nil {
+		return ctx
+	}
+	ctx, cancel := gocontext.WithCancel(ctx)
+	go func() {
+		defer cancel()
+		select {
+		case <-l.lost:
+		case <-l.done:
+		case <-ctx.Done():
+		}
+	}()
+	return ctx
+}
+
+// Err returns ErrLockLost once the lock was lost, nil while it is held
+func (l *Lock) Err() error {
+	if l == nil || l.lost == nil {
+		return nil
+	}
+	select {
+	case <-l.lost:
+		return l.err
+	default:
+		return nil
+	}
+}
+
+// Release stops renewing the lock and empties it, unless it was lost. The lock is emptied over the version the run
+// wrote last, a lock another run wrote since is left alone.
+func (l *Lock) Release() (err error) {
+	if l == nil || l.info == nil {
+		return nil
+	}
+	l.releaseOnce.Do(func() {
+		close(l.stop)
+		<-l.done
+		if l.Err() != nil {
+			return
+		}
+		_, err = replaceLock(l.kubeContext, nil, l.version)
+		if kubernetes.IsConflict(err) || kubernetes.IsNotExist(err) {
+			err = nil
+		}
+	})
+	return err
+}
+
+// ReadLock returns the holder of the lock of a namespace, nil if it is not locked
+func ReadLock(namespace, context, kubeConfig string) (*LockInfo, error) {
+	kubeContext, err := kubernetes.NewContext(namespace, context, kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	info, _, err := readLock(kubeContext)
+	return info, err
+}
+
+// Unlock deletes the lock of a namespace whoever holds it, to remove the lock of a run which was killed
+func Unlock(namespace, context, kubeConfig string) error {
+	kubeContext, err := kubernetes.NewContext(namespace, context, kubeConfig)
+	if err != nil {
+		return err
+	}
+	_, err = kubeContext.Resource().Delete(lockName, "configmap")
+	return err
+}
+
+// lockConfigMap is the rivendell-lock ConfigMap, its resource version conditions the updates of the lock
+type lockConfigMap struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data map[string]string `json:"data"`
+}
+
+// readLock returns the holder of the lock and the resource version of its ConfigMap, an empty version when there is
+// no ConfigMap
+func readLock(kubeContext *kubernetes.Context) (*LockInfo, string, error) {
+	manifest, err := kubeContext.Resource().Manifest(lockName, "configmap")
+	if err != nil {
+		return nil, "", err
+	}
+	if manifest == nil {
+		return nil, "", nil
+	}
+	return decodeLock(manifest)
+}
+
+func decodeLock(manifest []byte) (*LockInfo, string, error) {
+	configMap := &lockConfigMap{}
+	err := json.Unmarshal(manifest, configMap)
+	if err != nil {
+		return nil, "", stacktrace.Propagate(err, "cannot decode lock %q", lockName)
+	}
+	version := configMap.Metadata.ResourceVersion
+	content, ok := configMap.Data[lockKey]
+	if !ok {
+		return nil, version, nil
+	}
+	info := &LockInfo{}
+	err = json.Unmarshal([]byte(content), info)
+	if err != nil {
+		return nil, "", stacktrace.Propagate(err, "cannot decode lock %q", lockName)
+	}
+	return info, version, nil
+}
+
+// createLock creates the lock, it fails with kubernetes.ErrAlreadyExists when another run created it first
+func createLock(kubeContext *kubernetes.Context, info *LockInfo) (string, error) {
+	manifest, err := lockManifest(info, "")
+	if err != nil {
+		return "", err
+	}
+	written, err := kubeContext.Resource().PutNew(manifest)
+	if err != nil {
+		return "", err
+	}
+	_, version, err := decodeLock(written)
+	return version, err
+}
+
+// replaceLock writes the lock over the version read, it fails with kubernetes.ErrConflict when another run wrote it
+// since. A nil info empties the lock.
+func replaceLock(kubeContext *kubernetes.Context, info *LockInfo, version string) (string, error) {
+	manifest, err := lockManifest(info, version)
+	if err != nil {
+		return "", err
+	}
+	written, err := kubeContext.Resource().Replace(manifest)
+	if err != nil {
+		return "", err
+	}
+	_, version, err = decodeLock(written)
+	return version, err
+}
+
+func lockManifest(info *LockInfo, version string) (string, error) {
+	data := map[string]string{}
+	if info != nil {
+		content, err := json.Marshal(info)
+		if err != nil {
+			return "", stacktrace.Propagate(err, "cannot encode lock")
+		}
+		data[lockKey] = string(content)
+	}
+	metadata := map[string]interface{}{
+		"name": lockName,
+		"labels": map[string]string{
+			LabelManagedBy: managedByRivendell,
+		},
+	}
+	if version != "" {
+		metadata["resourceVersion"] = version
+	}
+	configMap := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   metadata,
+		"data":       data,
+	}
+	manifest, err := json.Marshal(configMap)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "cannot encode lock")
+	}
+	return string(manifest), nil
+}
+
+// lockHolder names the user, host and process running rivendell
+func lockHolder() string {
+	name := os.Getenv("USER")
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s (pid %d)", name, host, os.Getpid())
+}
@@ -0,0 +1,193 @@
+package project
+
+import (
+	gocontext "context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type LockTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *LockTestSuite) TestLock() {
+	p := s.readProject("wait-pod", nil)
+	skipped, err := p.Lock(gocontext.Background(), OperationDown, 0)
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), skipped.Release())
+	lock, err := p.Lock(gocontext.Background(), OperationUp, 0)
+	require.Nil(s.T(), err)
+	info, err := ReadLock(s.testNamespace, "", "")
+	require.Nil(s.T(), err)
+	require.NotNil(s.T(), info)
+	require.Equal(s.T(), OperationUp, info.Command)
+	require.Equal(s.T(), p.DeployID(), info.DeployID)
+
+	other := s.readProject("wait-pod", nil)
+	_, err = other.Lock(gocontext.Background(), OperationUpgrade, 100*time.Millisecond)
+	require.NotNil(s.T(), err)
+	lockedErr, ok := stacktrace.RootCause(err).(ErrLocked)
+	require.True(s.T(), ok, "%v", err)
+	require.Equal(s.T(), OperationUp, lockedErr.Command)
+	require.Equal(s.T(), ErrorTypeLocked, ErrorTypeOf(err))
+
+	require.Nil(s.T(), lock.Release())
+	info, err = ReadLock(s.testNamespace, "", "")
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), info)
+	lock, err = other.Lock(gocontext.Background(), OperationUpgrade, 0)
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), Unlock(s.testNamespace, "", ""))
+	require.Nil(s.T(), lock.Release())
+
+	kubeContext := s.kubeContext(p)
+	stale := &LockInfo{Holder: "someone", Command: OperationUpgrade, Renewed: time.Now().Add(-time.Hour), TTLSeconds: 60}
+	_, err = createLock(kubeContext, stale)
+	require.Nil(s.T(), err)
+	lock, err = p.Lock(gocontext.Background(), OperationUp, 0)
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), lock.Release())
+	s.down(p)
+}
+
+func (s *LockTestSuite) TestReleaseTakenOver() {
+	p := s.readProject("wait-pod", nil)
+	lock, err := p.Lock(gocontext.Background(), OperationUp, 0)
+	require.Nil(s.T(), err)
+	kubeContext := s.kubeContext(p)
+	_, version, err := readLock(kubeContext)
+	require.Nil(s.T(), err)
+	other := &LockInfo{Holder: "someone", Command: OperationUpgrade, Renewed: time.Now(), TTLSeconds: 60}
+	_, err = replaceLock(kubeContext, other, version)
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), lock.Release())
+	info, err := ReadLock(s.testNamespace, "", "")
+	require.Nil(s.T(), err)
+	require.NotNil(s.T(), info)
+	require.Equal(s.T(), "someone", info.Holder, "releasing leaves the lock of another run alone")
+	s.down(p)
+}
+
+func (s *LockTestSuite) TestLockInterrupted() {
+	p := s.readProject("wait-pod", nil)
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	_, err := p.Lock(ctx, OperationUp, 0)
+	require.NotNil(s.T(), err)
+	info, err := ReadLock(s.testNamespace, "", "")
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), info)
+
+	ctx, cancel = gocontext.WithCancel(gocontext.Background())
+	lock, err := p.Lock(ctx, OperationUp, 0)
+	require.Nil(s.T(), err)
+	cancel()
+	require.Nil(s.T(), lock.Release())
+	info, err = ReadLock(s.testNamespace, "", "")
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), info, "a lock is released after its command was interrupted")
+	s.down(p)
+}
+
+func (s *LockTestSuite) TestRace() {
+	p := s.readProject("wait-pod", nil)
+	kubeContext := s.kubeContext(p)
+	_, err := kubeContext.Namespace().Create()
+	require.Nil(s.T(), err)
+	stale := &LockInfo{Holder: "someone", Command: OperationUpgrade, Renewed: time.Now().Add(-time.Hour), TTLSeconds: 60}
+	for _, previous := range []*LockInfo{nil, stale} {
+		if previous != nil {
+			_, version, err := readLock(kubeContext)
+			require.Nil(s.T(), err)
+			require.NotEmpty(s.T(), version, "a released lock is emptied")
+			_, err = replaceLock(kubeContext, previous, version)
+			require.Nil(s.T(), err)
+		}
+		acquirers := []*Project{}
+		for i := 0; i < 5; i++ {
+			acquirers = append(acquirers, s.readProject("wait-pod", nil))
+		}
+		locks := make([]*Lock, len(acquirers))
+		errs := make([]error, len(acquirers))
+		start := make(chan struct{})
+		wg := sync.WaitGroup{}
+		for i, acquirer := range acquirers {
+			wg.Add(1)
+			go func(i int, acquirer *Project) {
+				defer wg.Done()
+				<-start
+				locks[i], errs[i] = acquirer.Lock(gocontext.Background(), OperationUpgrade, 0)
+			}(i, acquirer)
+		}
+		close(start)
+		wg.Wait()
+		acquired := 0
+		for i := range acquirers {
+			if errs[i] == nil {
+				acquired++
+				info, err := ReadLock(s.testNamespace, "", "")
+				require.Nil(s.T(), err)
+				require.Equal(s.T(), acquirers[i].DeployID(), info.DeployID)
+				continue
+			}
+			require.Equal(s.T(), ErrorTypeLocked, ErrorTypeOf(errs[i]), "%v", errs[i])
+		}
+		require.Equal(s.T(), 1, acquired)
+		for _, lock := range locks {
+			require.Nil(s.T(), lock.Release())
+		}
+	}
+	s.down(p)
+}
+
+func (s *LockTestSuite) TestLost() {
+	defer func(ttl time.Duration) { LockTTL = ttl }(LockTTL)
+	LockTTL = 300 * time.Millisecond
+	p := s.readProject("wait-pod", nil)
+	lock, err := p.Lock(gocontext.Background(), OperationUp, 0)
+	require.Nil(s.T(), err)
+	ctx := lock.Context(gocontext.Background())
+	kubeContext := s.kubeContext(p)
+	_, version, err := readLock(kubeContext)
+	require.Nil(s.T(), err)
+	time.Sleep(LockTTL / 2)
+	require.Nil(s.T(), lock.Err())
+	_, renewedVersion, err := readLock(kubeContext)
+	require.Nil(s.T(), err)
+	require.NotEqual(s.T(), version, renewedVersion, "the heartbeat renews the lock")
+
+	other := &LockInfo{Holder: "someone", Command: OperationUpgrade, Renewed: time.Now(), TTLSeconds: 60}
+	for {
+		_, err = replaceLock(kubeContext, other, renewedVersion)
+		if !kubernetes.IsConflict(err) {
+			break
+		}
+		_, renewedVersion, err = readLock(kubeContext)
+		require.Nil(s.T(), err)
+	}
+	require.Nil(s.T(), err)
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		s.T().Fatal("the context of a lost lock is cancelled")
+	}
+	lostErr, ok := stacktrace.RootCause(lock.Err()).(ErrLockLost)
+	require.True(s.T(), ok, "%v", lock.Err())
+	require.Equal(s.T(), "someone", lostErr.Holder)
+	require.Equal(s.T(), ErrorTypeLocked, ErrorTypeOf(lock.Err()))
+	require.Nil(s.T(), lock.Release())
+	info, err := ReadLock(s.testNamespace, "", "")
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), "someone", info.Holder, "a lost lock is not deleted")
+	s.down(p)
+}
+
+func TestLock(t *testing.T) {
+	suite.Run(t, new(LockTestSuite))
+}
@@ -1,38 +1,25 @@
 package project
 
 import (
+	gocontext "context"
 	"os"
 	"time"
 
 	"github.com/anduintransaction/rivendell/kubernetes"
-	"github.com/palantir/stacktrace"
 )
 
-// Logs .
-func Logs(namespace, context, kubeConfig, name, containerName string, timeout int) error {
+// Logs follows the logs of a pod until it ends, the timeout expires or ctx is done
+func Logs(ctx gocontext.Context, namespace, context, kubeConfig, name, containerName string, timeout int) error {
 	kubeContext, err := kubernetes.NewContext(namespace, context, kubeConfig)
 	if err != nil {
 		return err
 	}
-	waitChannel := make(chan error, 1)
-	go func() {
-		err := kubeContext.Resource().Logs(name, containerName, os.Stdout, os.Stderr)
-		if err != nil {
-			waitChannel <- err
-			return
-		}
-		waitChannel <- nil
-	}()
-
-	if timeout <= 0 {
-		<-waitChannel
-	} else {
-		timer := time.NewTimer(time.Duration(timeout) * time.Second)
-		select {
-		case <-waitChannel:
-		case <-timer.C:
-			stacktrace.Propagate(ErrWaitTimeout{name, "pod"}, "wait timeout")
-		}
+	if timeout > 0 {
+		var cancel gocontext.CancelFunc
+		ctx, cancel = gocontext.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
 	}
+	// errors of the log stream are not reported, like a timeout
+	_ = kubeContext.WithContext(ctx).Resource().Logs(name, containerName, os.Stdout, os.Stderr)
 	return nil
 }
@@ -0,0 +1,149 @@
+package project
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Labels and annotations stamped on every resource applied by rivendell
+const (
+	LabelManagedBy     = "app.kubernetes.io/managed-by"
+	LabelProject       = "rivendell.anduintransaction.com/project"
+	LabelGroup         = "rivendell.anduintransaction.com/group"
+	AnnotationSource   = "rivendell.anduintransaction.com/source"
+	AnnotationVersion  = "rivendell.anduintransaction.com/version"
+	AnnotationDeployID = "rivendell.anduintransaction.com/deploy-id"
+
+	managedByRivendell = "rivendell"
+)
+
+// DeployID identifies the current run in the deploy ID annotation, a new one is generated when empty
+var DeployID = ""
+
+// Ownership describes what is stamped on the resources of a project
+type Ownership struct {
+	Project     string
+	DeployID    string
+	RootDir     string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// stampOwnership adds the ownership labels and annotations to every resource of a file
+func stampOwnership(ownership *Ownership, groupName string) ResourceFileProcessorFunc {
+	return func(rf *ResourceFile) error {
+		labels := utils.MergeMaps(ownership.Labels, map[string]string{
+			LabelManagedBy: managedByRivendell,
+			LabelProject:   labelValue(ownership.Project),
+			LabelGroup:     labelValue(groupName),
+		})
+		annotations := utils.MergeMaps(ownership.Annotations, map[string]string{
+			AnnotationSource:   ownership.source(rf.Source),
+			AnnotationVersion:  utils.Version,
+			AnnotationDeployID: ownership.DeployID,
+		})
+		for _, r := range rf.Resources {
+			content, err := stampMetadata(r.RawContent, labels, annotations)
+			if err != nil {
+				return stacktrace.Propagate(err, "cannot stamp ownership on %s %q from %q", r.Kind, r.Name, rf.Source)
+			}
+			r.RawContent = content
+		}
+		return nil
+	}
+}
+
+// source returns the path of a resource file relative to the project root, URLs are kept as is
+func (o *Ownership) source(source string) string {
+	if utils.IsURL(source) {
+		return source
+	}
+	relative, err := filepath.Rel(o.RootDir, source)
+	if err != nil {
+		return source
+	}
+	return filepath.ToSlash(relative)
+}
+
+// stampMetadata sets labels and annotations in the metadata of a manifest, keeping the order of its keys
+func stampMetadata(content string, labels, annotations map[string]string) (string, error) {
+	manifest := yaml.MapSlice{}
+	err := yaml.Unmarshal([]byte(content), &manifest)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "cannot parse manifest")
+	}
+	metadata := mapSliceValue(manifest, "metadata")
+	metadata = setMapSliceValues(metadata, "labels", labels)
+	metadata = setMapSliceValues(metadata, "annotations", annotations)
+	manifest = setMapSliceValue(manifest, "metadata", metadata)
+	out, err := yaml.Marshal(manifest)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "cannot encode manifest")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func mapSliceValue(m yaml.MapSlice, key string) yaml.MapSlice {
+	for _, item := range m {
+		if item.Key == key {
+			if value, ok := item.Value.(yaml.MapSlice); ok {
+				return value
+			}
+		}
+	}
+	return yaml.MapSlice{}
+}
+
+func setMapSliceValue(m yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range m {
+		if item.Key == key {
+			m[i].Value = value
+			return m
+		}
+	}
+	return append(m, yaml.MapItem{Key: key, Value: value})
+}
+
+func setMapSliceValues(m yaml.MapSlice, key string, values map[string]string) yaml.MapSlice {
+	inner := mapSliceValue(m, key)
+	for _, k := range sortedKeys(values) {
+		inner = setMapSliceValue(inner, k, values[k])
+	}
+	return setMapSliceValue(m, key, inner)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// labelValue turns a name into a valid label value: at most 63 alphanumeric characters, '-', '_' or '.',
+// starting and ending with an alphanumeric character
+func labelValue(value string) string {
+	value = invalidLabelChars.ReplaceAllString(value, "-")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return strings.Trim(value, "-_.")
+}
+
+func newDeployID() string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return time.Now().UTC().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,252 @@
+package project
+
+import (
+	gocontext "context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/fatih/color"
+	"github.com/palantir/stacktrace"
+)
+
+// Operations a plan can be made for
+const (
+	OperationUp      = "up"
+	OperationUpdate  = "update"
+	OperationUpgrade = "upgrade"
+	OperationDown    = "down"
+)
+
+// PlanAction tells what an operation will do to a resource
+type PlanAction string
+
+// PlanAction values
+const (
+	PlanCreate    PlanAction = "create"
+	PlanUnchanged PlanAction = "unchanged"
+	PlanUpdate    PlanAction = "update"
+	PlanRecreate  PlanAction = "recreate"
+	PlanSkip      PlanAction = "skip"
+	PlanDelete    PlanAction = "delete"
+	PlanNotFound  PlanAction = "not-found"
+)
+
+// planActionOrder is the order actions are counted in
+var planActionOrder = []PlanAction{PlanCreate, PlanUpdate, PlanRecreate, PlanDelete, PlanUnchanged, PlanSkip, PlanNotFound}
+
+// Plan is what an operation will do to the resources of a project, group by group in the order of the walk
+type Plan struct {
+	Operation string       `json:"operation"`
+	Groups    []*GroupPlan `json:"groups"`
+}
+
+// GroupPlan is what an operation will do to the resources of a group
+type GroupPlan struct {
+	Name string `json:"name"`
+	// After lists the groups which must be ready, or deleted for down, before the group is processed
+	After []string      `json:"after,omitempty"`
+	Waits []*WaitConfig `json:"waits,omitempty"`
+	Steps []*PlanStep   `json:"steps"`
+}
+
+// PlanStep is what an operation will do to a resource
+type PlanStep struct {
+	Kind   string     `json:"kind"`
+	Name   string     `json:"name"`
+	Action PlanAction `json:"action"`
+}
+
+// Plan queries the cluster to tell what an operation will do to every resource of the project
+func (p *Project) Plan(ctx gocontext.Context, operation string, deletePVC bool) (*Plan, error) {
+	kubeContext, err := p.newKubeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{Operation: operation, Groups: []*GroupPlan{}}
+	walk := p.resourceGraph.WalkForward
+	if operation == OperationDown {
+		walk = p.resourceGraph.WalkBackward
+	}
+	err = walk(ctx, func(g *ResourceGroup) error {
+		groupPlan := &GroupPlan{Name: g.Name, Steps: []*PlanStep{}}
+		switch operation {
+		case OperationUp:
+			groupPlan.After = g.Depend
+			groupPlan.Waits = g.Wait
+		case OperationUpdate, OperationUpgrade:
+			groupPlan.Waits = g.Wait
+		case OperationDown:
+			groupPlan.After = g.Children
+		default:
+			return stacktrace.NewError("cannot plan operation %q", operation)
+		}
+		for _, r := range g.allResources() {
+			action, err := planAction(kubeContext, operation, r, deletePVC)
+			if err != nil {
+				return err
+			}
+			groupPlan.Steps = append(groupPlan.Steps, &PlanStep{Kind: r.Kind, Name: r.Name, Action: action})
+		}
+		plan.Groups = append(plan.Groups, groupPlan)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// CheckPlan plans an operation again, with its prune candidates when prune is set, and fails with ErrPlanChanged when
+// they differ from the ones shown before. Commands check their plan once they hold the lock of the namespace, another
+// run may have changed the namespace while they waited for the lock or for a confirmation.
+func (p *Project) CheckPlan(ctx gocontext.Context, plan *Plan, prune bool, pruneCandidates []*InventoryEntry) error {
+	if plan != nil {
+		current, err := p.Plan(ctx, plan.Operation, false)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(plan, current) {
+			return stacktrace.Propagate(ErrPlanChanged{p.namespace}, "plan changed")
+		}
+	}
+	if prune {
+		current, err := p.PrunePlan(ctx)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(pruneCandidates, current) {
+			return stacktrace.Propagate(ErrPlanChanged{p.namespace}, "prune candidates changed")
+		}
+	}
+	return nil
+}
+
+// planAction tells what an operation will do to a resource, following the kubernetes Create, Update, Upgrade and Delete
+func planAction(kubeContext *kubernetes.Context, operation string, r *Resource, deletePVC bool) (PlanAction, error) {
+	kind := strings.ToLower(r.Kind)
+	podOrJob := kind == "pod" || kind == "job"
+	if operation == OperationUpdate && podOrJob {
+		return PlanSkip, nil
+	}
+	if operation == OperationDown && !deletePVC && isPVC(kind) {
+		return PlanSkip, nil
+	}
+	status, err := kubeContext.Resource().GetStatus(r.Name, kind)
+	if err != nil {
+		return "", err
+	}
+	if status == kubernetes.RsStatusUnknown {
+		return "", stacktrace.NewError("unknown status of %s %q", r.Kind, r.Name)
+	}
+	exists := status != kubernetes.RsStatusNotExist && status != kubernetes.RsStatusTerminating
+	switch operation {
+	case OperationUp:
+		if exists {
+			return PlanSkip, nil
+		}
+		return PlanCreate, nil
+	case OperationDown:
+		if exists {
+			return PlanDelete, nil
+		}
+		return PlanNotFound, nil
+	case OperationUpdate:
+		if !exists {
+			return PlanNotFound, nil
+		}
+	case OperationUpgrade:
+		if !exists {
+			return PlanCreate, nil
+		}
+		if podOrJob {
+			if status == kubernetes.RsStatusActive || status == kubernetes.RsStatusPending {
+				return PlanSkip, nil
+			}
+			return PlanRecreate, nil
+		}
+	}
+	comparison, err := kubeContext.Resource().Compare(r.Name, kind, r.RawContent, diffIgnoredAnnotations)
+	if err != nil {
+		return "", err
+	}
+	if comparison.Changed() {
+		return PlanUpdate, nil
+	}
+	return PlanUnchanged, nil
+}
+
+// Count returns the number of resources per action
+func (plan *Plan) Count() map[PlanAction]int {
+	counts := make(map[PlanAction]int)
+	for _, groupPlan := range plan.Groups {
+		for action, count := range groupPlan.Count() {
+			counts[action] += count
+		}
+	}
+	return counts
+}
+
+// Count returns the number of resources of the group per action
+func (groupPlan *GroupPlan) Count() map[PlanAction]int {
+	counts := make(map[PlanAction]int)
+	for _, step := range groupPlan.Steps {
+		counts[step.Action]++
+	}
+	return counts
+}
+
+// PrintPlan .
+func (p *Project) PrintPlan(out io.Writer, plan *Plan) {
+	utils.Infof(out, "Plan for %s:", plan.Operation)
+	for _, groupPlan := range plan.Groups {
+		utils.Infof(out, "==> Group %q (%s)", groupPlan.Name, formatPlanCounts(groupPlan.Count()))
+		if len(groupPlan.After) > 0 {
+			verb := "ready"
+			if plan.Operation == OperationDown {
+				verb = "deleted"
+			}
+			fmt.Fprintf(out, " - [after] %s %s\n", strings.Join(groupPlan.After, ", "), verb)
+		}
+		for _, wait := range groupPlan.Waits {
+			if wait.For == "" {
+				fmt.Fprintf(out, " - [wait] %s/%s\n", wait.Kind, wait.Name)
+			} else {
+				fmt.Fprintf(out, " - [wait] %s/%s (%s)\n", wait.Kind, wait.Name, wait.For)
+			}
+		}
+		for _, step := range groupPlan.Steps {
+			planActionColor(step.Action).Fprintf(out, " - %-9s %s %q\n", step.Action, step.Kind, step.Name)
+		}
+	}
+	utils.Infof(out, "Total: %s", formatPlanCounts(plan.Count()))
+}
+
+func formatPlanCounts(counts map[PlanAction]int) string {
+	parts := []string{}
+	for _, action := range planActionOrder {
+		if counts[action] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[action], action))
+		}
+	}
+	if len(parts) == 0 {
+		return "no resources"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func planActionColor(action PlanAction) *color.Color {
+	switch action {
+	case PlanCreate:
+		return color.New(color.FgGreen)
+	case PlanUpdate:
+		return color.New(color.FgYellow)
+	case PlanRecreate, PlanDelete:
+		return color.New(color.FgRed)
+	default:
+		return color.New(color.Reset)
+	}
+}
@@ -0,0 +1,60 @@
+package project
+
+import (
+	gocontext "context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type PlanTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *PlanTestSuite) TestPlan() {
+	p := s.readProject("upgrade", map[string]string{"nginxTag": "1.13.12", "ubuntuTag": "16.04"})
+	plan, err := p.Plan(gocontext.Background(), OperationUp, false)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []*PlanStep{{Kind: "Job", Name: "success", Action: PlanCreate}}, plan.Groups[0].Steps)
+	require.Equal(s.T(), []*PlanStep{
+		{Kind: "Deployment", Name: "nginx", Action: PlanCreate},
+		{Kind: "Service", Name: "nginx", Action: PlanCreate},
+	}, plan.Groups[1].Steps)
+	require.Equal(s.T(), []string{"jobs"}, plan.Groups[1].After)
+	require.Len(s.T(), plan.Groups[1].Waits, 1)
+	err = p.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+	err = Wait(gocontext.Background(), s.testNamespace, "", "", "job", "success", 60)
+	require.Nil(s.T(), err)
+
+	plan, err = p.Plan(gocontext.Background(), OperationUp, false)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), map[PlanAction]int{PlanSkip: 3}, plan.Count())
+	plan, err = p.Plan(gocontext.Background(), OperationUpdate, false)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), map[PlanAction]int{PlanSkip: 1, PlanUnchanged: 2}, plan.Count())
+
+	updatedProject := s.readProject("upgrade", map[string]string{"nginxTag": "1.13", "ubuntuTag": "16.10"})
+	plan, err = updatedProject.Plan(gocontext.Background(), OperationUpgrade, false)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), PlanRecreate, plan.Groups[0].Steps[0].Action)
+	require.Equal(s.T(), PlanUpdate, plan.Groups[1].Steps[0].Action)
+	require.Equal(s.T(), PlanUnchanged, plan.Groups[1].Steps[1].Action)
+
+	plan, err = p.Plan(gocontext.Background(), OperationDown, true)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), "services", plan.Groups[0].Name, "down is planned backward")
+	require.Equal(s.T(), []string{"services"}, plan.Groups[1].After)
+	require.Equal(s.T(), map[PlanAction]int{PlanDelete: 3}, plan.Count())
+	p.PrintPlan(ioutil.Discard, plan)
+	s.down(p)
+	plan, err = p.Plan(gocontext.Background(), OperationDown, true)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), map[PlanAction]int{PlanNotFound: 3}, plan.Count())
+}
+
+func TestPlan(t *testing.T) {
+	suite.Run(t, new(PlanTestSuite))
+}
@@ -1,7 +1,11 @@
 package project
 
 import (
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,8 +18,8 @@ import (
 )
 
 const (
-	waitDelay = 3 * time.Second
-	waitCount = 10
+	defaultReadyTimeout  = 5 * time.Minute
+	defaultDeleteTimeout = 30 * time.Second
 )
 
 // FilterFunc criteria if a resource group should be process by Walk function
@@ -27,6 +31,11 @@ type Formatter interface {
 
 // Project holds configuration for a rivendell task
 type Project struct {
+	name string
+	// identity keys the inventory and the checkpoint of the project in its namespace, see resolveName
+	identity              string
+	projectFile           string
+	deployID              string
 	rootDir               string
 	namespace             string
 	context               string
@@ -36,13 +45,25 @@ type Project struct {
 	filterFn              FilterFunc
 	deleteNamespaceConfig bool
 	config                *Config
+	batch                 bool
+	filtered              bool
+	// release is set on projects rendering a previous release, see ForRelease
+	release *Release
+	events  *eventEmitter
+	// resume and checkpoint are used by up and upgrade, see SetResume
+	resume     bool
+	checkpoint *checkpointTracker
+	// out receives the human readable output of the operations, see SetOutput
+	out io.Writer
 }
 
 // ReadProject reads a project from file
 func ReadProject(projectFile, namespace, context, kubeConfig string, variables map[string]string, variableFiles []string, includeResources []string, excludeResources []string) (*Project, error) {
 	project := &Project{
-		context:    context,
-		kubeConfig: kubeConfig,
+		projectFile: projectFile,
+		context:     context,
+		kubeConfig:  kubeConfig,
+		filtered:    len(includeResources) > 0 || len(excludeResources) > 0,
 	}
 	err := project.resolveCommandlineVariables(variables, variableFiles)
 	if err != nil {
@@ -55,6 +76,8 @@ func ReadProject(projectFile, namespace, context, kubeConfig string, variables m
 	project.config = projectConfig
 	project.deleteNamespaceConfig = projectConfig.DeleteNamespace
 	project.resolveProjectRoot(projectFile, projectConfig.RootDir)
+	project.resolveName(projectFile, projectConfig.Name)
+	project.resolveDeployID()
 	project.resolveNamespace(namespace, projectConfig.Namespace)
 	project.resolveVariables(projectConfig.Variables)
 	err = project.resolveResourceGraph(projectConfig.ResourceGroups, includeResources, excludeResources)
@@ -64,14 +87,49 @@ func ReadProject(projectFile, namespace, context, kubeConfig string, variables m
 	return project, nil
 }
 
+// Name of the project, stamped on its resources
+func (p *Project) Name() string {
+	return p.name
+}
+
+// Namespace the project is deployed to, empty for the default namespace of the kubernetes context
+func (p *Project) Namespace() string {
+	return p.namespace
+}
+
+// DeployID identifies the current run of the project
+func (p *Project) DeployID() string {
+	return p.deployID
+}
+
+// SetOutput sends the human readable output of the operations, like progress, plans and reports, to out instead of
+// os.Stdout. The events of SetEventOutput are not affected.
+func (p *Project) SetOutput(out io.Writer) *Project {
+	p.out = out
+	p.resourceGraph.out = out
+	return p
+}
+
+// Output is where the project writes its human readable output, see SetOutput
+func (p *Project) Output() io.Writer {
+	if p.out == nil {
+		return os.Stdout
+	}
+	return p.out
+}
+
 // Debug .
 func (p *Project) Debug(f Formatter) {
 	f.Format(p)
 }
 
 // Up .
-func (p *Project) Up() error {
-	kubeContext, err := kubernetes.NewContext(p.namespace, p.context, p.kubeConfig)
+func (p *Project) Up(ctx gocontext.Context) (err error) {
+	finish := p.events.startOperation(OperationUp)
+	defer func() {
+		finish(err)
+	}()
+	kubeContext, err := p.newKubeContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -79,34 +137,68 @@ func (p *Project) Up() error {
 	if err != nil {
 		return err
 	}
-	return p.resourceGraph.WalkResourceForward(func(r *Resource, g *ResourceGroup) error {
-		return p.createResource(kubeContext, g, r)
-	}, func(r *Resource, g *ResourceGroup) error {
-		return p.waitForExists(kubeContext, r)
-	}, func(name, kind string) error {
-		utils.Info2("Waiting for %s %q", kind, name)
-		return p.waitForResource(kubeContext, name, kind)
+	err = p.startCheckpoint(kubeContext, OperationUp)
+	if err != nil {
+		return err
+	}
+	progress := newProgress()
+	err = p.walkApply(ctx, progress, func(ctx gocontext.Context, r *Resource, g *ResourceGroup) error {
+		return p.createResource(groupKubeContext(ctx, kubeContext), progress, g, r)
+	}, func(ctx gocontext.Context, g *ResourceGroup) error {
+		return p.createGroup(groupKubeContext(ctx, kubeContext), progress, g)
+	}, func(ctx gocontext.Context, r *Resource, g *ResourceGroup) error {
+		return p.waitForReady(groupKubeContext(ctx, kubeContext), g, r)
+	}, func(ctx gocontext.Context, wait *WaitConfig) error {
+		return p.waitForResource(groupKubeContext(ctx, kubeContext), wait)
 	})
+	p.reportFailure(ctx, kubeContext, err, progress, false)
+	err = p.saveInventory(kubeContext, progress, err)
+	err = p.finishCheckpoint(kubeContext, err)
+	if err != nil {
+		return err
+	}
+	return p.recordRelease(kubeContext, OperationUp)
 }
 
 // Down .
-func (p *Project) Down(deleteNS, deletePVC bool) error {
-	kubeContext, err := kubernetes.NewContext(p.namespace, p.context, p.kubeConfig)
+func (p *Project) Down(ctx gocontext.Context, deleteNS, deletePVC bool) (err error) {
+	finish := p.events.startOperation(OperationDown)
+	defer func() {
+		finish(err)
+	}()
+	kubeContext, err := p.newKubeContext(ctx)
 	if err != nil {
 		return err
 	}
-	err = p.resourceGraph.WalkResourceBackward(func(r *Resource, g *ResourceGroup) error {
-		kind := strings.ToLower(r.Kind)
-		isPVC := kind == "persistentvolumeclaim" || kind == "pvc"
-		if !deletePVC && isPVC {
+	progress := newProgress()
+	err = p.resourceGraph.WalkResourceBackward(ctx, progress.track(func(ctx gocontext.Context, r *Resource, g *ResourceGroup) error {
+		if !deletePVC && isPVC(r.Kind) {
 			return nil
 		}
-		return p.deleteResource(kubeContext, g, r)
-	}, func(r *Resource, g *ResourceGroup) error {
-		return p.waitForDeleted(kubeContext, r)
+		return p.deleteResource(groupKubeContext(ctx, kubeContext), g, r)
+	}), func(ctx gocontext.Context, r *Resource, g *ResourceGroup) error {
+		return p.waitForDeleted(groupKubeContext(ctx, kubeContext), g.Name, r)
 	})
-	if !deleteNS {
+	deleted := make(map[string]bool)
+	p.resourceGraph.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
+		for _, r := range g.allResources() {
+			if progress.isDone(r) && (deletePVC || !isPVC(r.Kind)) {
+				deleted[inventoryEntryKey(r.Kind, r.Name)] = true
+			}
+		}
 		return nil
+	})
+	recordErr := p.recordDeleted(kubeContext.WithContext(gocontext.Background()), deleted)
+	if recordErr != nil {
+		utils.Error(recordErr)
+	}
+	if ctx.Err() != nil {
+		p.printInterruptSummary(progress, true)
+		return err
+	}
+	p.printGroupsReport(err)
+	if !deleteNS {
+		return err
 	}
 
 	// Delete namespace anyway, this may have the side-effect of deleting all resources.
@@ -118,41 +210,70 @@ func (p *Project) Down(deleteNS, deletePVC bool) error {
 }
 
 // Update .
-func (p *Project) Update() error {
-	kubeContext, err := kubernetes.NewContext(p.namespace, p.context, p.kubeConfig)
+func (p *Project) Update(ctx gocontext.Context) (err error) {
+	finish := p.events.startOperation(OperationUpdate)
+	defer func() {
+		finish(err)
+	}()
+	kubeContext, err := p.newKubeContext(ctx)
 	if err != nil {
 		return err
 	}
-	return p.resourceGraph.WalkResourceForward(func(r *Resource, g *ResourceGroup) error {
-		return p.updateResource(kubeContext, g, r)
-	}, nil, func(name, kind string) error {
-		utils.Info2("Waiting for %s %q", kind, name)
-		return p.waitForResource(kubeContext, name, kind)
+	progress := newProgress()
+	err = p.walkApply(ctx, progress, func(ctx gocontext.Context, r *Resource, g *ResourceGroup) error {
+		return p.updateResource(groupKubeContext(ctx, kubeContext), progress, g, r)
+	}, func(ctx gocontext.Context, g *ResourceGroup) error {
+		return p.updateGroup(groupKubeContext(ctx, kubeContext), progress, g)
+	}, nil, func(ctx gocontext.Context, wait *WaitConfig) error {
+		return p.waitForResource(groupKubeContext(ctx, kubeContext), wait)
 	})
+	p.reportFailure(ctx, kubeContext, err, progress, false)
+	err = p.saveInventory(kubeContext, progress, err)
+	if err != nil {
+		return err
+	}
+	return p.recordRelease(kubeContext, OperationUpdate)
 }
 
 // Upgrade .
-func (p *Project) Upgrade() error {
-	kubeContext, err := kubernetes.NewContext(p.namespace, p.context, p.kubeConfig)
+func (p *Project) Upgrade(ctx gocontext.Context) (err error) {
+	finish := p.events.startOperation(OperationUpgrade)
+	defer func() {
+		finish(err)
+	}()
+	kubeContext, err := p.newKubeContext(ctx)
 	if err != nil {
 		return err
 	}
-	return p.resourceGraph.WalkResourceForward(func(r *Resource, g *ResourceGroup) error {
-		return p.upgradeResource(kubeContext, g, r)
-	}, nil, func(name, kind string) error {
-		utils.Info2("Waiting for %s %q", kind, name)
-		return p.waitForResource(kubeContext, name, kind)
+	err = p.startCheckpoint(kubeContext, OperationUpgrade)
+	if err != nil {
+		return err
+	}
+	progress := newProgress()
+	err = p.walkApply(ctx, progress, func(ctx gocontext.Context, r *Resource, g *ResourceGroup) error {
+		return p.upgradeResource(groupKubeContext(ctx, kubeContext), progress, g, r)
+	}, func(ctx gocontext.Context, g *ResourceGroup) error {
+		return p.upgradeGroup(groupKubeContext(ctx, kubeContext), progress, g)
+	}, nil, func(ctx gocontext.Context, wait *WaitConfig) error {
+		return p.waitForResource(groupKubeContext(ctx, kubeContext), wait)
 	})
+	p.reportFailure(ctx, kubeContext, err, progress, false)
+	err = p.saveInventory(kubeContext, progress, err)
+	err = p.finishCheckpoint(kubeContext, err)
+	if err != nil {
+		return err
+	}
+	return p.recordRelease(kubeContext, OperationUpgrade)
 }
 
 // GetServicePods
 func (p *Project) GetServicePods() ([]string, error) {
-	kubeContext, err := kubernetes.NewContext(p.namespace, p.context, p.kubeConfig)
+	kubeContext, err := p.newKubeContext(gocontext.Background())
 	if err != nil {
 		return nil, err
 	}
 	pods := utils.NewStringSet()
-	_ = p.resourceGraph.WalkResourceForward(func(r *Resource, g *ResourceGroup) error {
+	_ = p.resourceGraph.WalkResourceForward(gocontext.Background(), func(ctx gocontext.Context, r *Resource, g *ResourceGroup) error {
 		if strings.ToLower(r.Kind) == "service" {
 			servicePods, err := kubeContext.Service().ListPods(r.Name)
 			if err != nil {
@@ -166,18 +287,27 @@ func (p *Project) GetServicePods() ([]string, error) {
 }
 
 // Restart .
-func (p *Project) Restart(pods []string) error {
-	kubeContext, err := kubernetes.NewContext(p.namespace, p.context, p.kubeConfig)
+func (p *Project) Restart(ctx gocontext.Context, pods []string) (err error) {
+	finish := p.events.startOperation("restart")
+	defer func() {
+		finish(err)
+	}()
+	kubeContext, err := p.newKubeContext(ctx)
 	if err != nil {
 		return err
 	}
 	for _, pod := range pods {
-		utils.Warn("Deleting pod %q", pod)
+		if ctx.Err() != nil {
+			return stacktrace.Propagate(ctx.Err(), "interrupted before restarting pod %q", pod)
+		}
+		utils.Warnf(kubeContext.Output(), "Deleting pod %q", pod)
+		start := time.Now()
 		exists, err := kubeContext.Resource().Delete(pod, "pod")
+		p.events.resource(deleteEventType(exists), "", "Pod", pod, start, err)
 		if err != nil {
 			return err
 		}
-		p.printDeleteResult(exists)
+		p.printDeleteResult(kubeContext.Output(), exists)
 	}
 	return nil
 }
@@ -191,49 +321,19 @@ func (p *Project) PrintCommonInfo() {
 }
 
 func (p *Project) PrintConfig() {
-	p.config.Write(os.Stdout)
-}
-
-// PrintUpPlan .
-func (p *Project) PrintUpPlan() {
-	utils.Info("The following resources will be created:")
-	p.resourceGraph.WalkResourceForward(func(r *Resource, g *ResourceGroup) error {
-		fmt.Printf(" - %s %q\n", r.Kind, r.Name)
-		return nil
-	}, nil, nil)
-}
-
-// PrintDownPlan .
-func (p *Project) PrintDownPlan() {
-	utils.Warn("The following resources will be destroyed:")
-	p.resourceGraph.WalkResourceBackward(func(r *Resource, g *ResourceGroup) error {
-		fmt.Printf(" - %s %q\n", r.Kind, r.Name)
-		return nil
-	}, nil)
-}
-
-// PrintUpdatePlan .
-func (p *Project) PrintUpdatePlan() {
-	utils.Warn("The following resources will be updated: ")
-	p.resourceGraph.WalkResourceForward(func(r *Resource, g *ResourceGroup) error {
-		fmt.Printf(" - %s %q\n", r.Kind, r.Name)
-		return nil
-	}, nil, func(name, kind string) error {
-		fmt.Printf("- [wait] %s/%s\n", kind, name)
-		return nil
-	})
+	p.config.Write(p.Output())
 }
 
 // PrintRestartPlan .
-func (p *Project) PrintRestartPlan(pods []string) {
-	utils.Warn("The following pods will be restarted: ")
+func (p *Project) PrintRestartPlan(out io.Writer, pods []string) {
+	utils.Warnf(out, "The following pods will be restarted: ")
 	for _, pod := range pods {
-		fmt.Printf(" - %s\n", pod)
+		fmt.Fprintf(out, " - %s\n", pod)
 	}
 }
 
 func (p *Project) WalkForward(fn func(g *ResourceGroup) error) error {
-	return p.resourceGraph.WalkForward(func(g *ResourceGroup) error {
+	return p.resourceGraph.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
 		if p.filterFn != nil && !p.filterFn(g) {
 			return nil
 		}
@@ -246,11 +346,59 @@ func (p *Project) SetFilter(fn func(*ResourceGroup) bool) *Project {
 	return p
 }
 
+// SetBatch makes up, update and upgrade apply every resource group in a single request
+func (p *Project) SetBatch(batch bool) *Project {
+	p.batch = batch
+	return p
+}
+
+// SetParallelism sets the maximum number of resource groups processed at the same time
+func (p *Project) SetParallelism(parallelism int) *Project {
+	p.resourceGraph.Parallelism = parallelism
+	return p
+}
+
+// SetKeepGoing makes up, down, update, upgrade and rollback go on after a group failed, skipping the groups after it
+func (p *Project) SetKeepGoing(keepGoing bool) *Project {
+	p.resourceGraph.KeepGoing = keepGoing
+	return p
+}
+
 func (p *Project) resolveProjectRoot(projectFile, configRoot string) {
 	projectFileDirname := filepath.Dir(projectFile)
 	p.rootDir = filepath.Join(projectFileDirname, configRoot)
 }
 
+// resolveName uses the name from the config, or the name of the directory holding the project file. A configured
+// name identifies the project, a directory name is shared by projects of different paths so it is suffixed with a
+// hash of the path of the project file.
+func (p *Project) resolveName(projectFile, configName string) {
+	if configName != "" {
+		p.name = configName
+		p.identity = configName
+		return
+	}
+	absProjectFile, err := filepath.Abs(projectFile)
+	if err != nil {
+		absProjectFile = projectFile
+	}
+	p.name = filepath.Base(filepath.Dir(absProjectFile))
+	hash := sha256.Sum256([]byte(filepath.ToSlash(absProjectFile)))
+	// the hash is kept when the identity is cut to the length of a label value
+	name := labelValue(p.name)
+	if len(name) > 54 {
+		name = name[:54]
+	}
+	p.identity = name + "-" + hex.EncodeToString(hash[:])[:8]
+}
+
+func (p *Project) resolveDeployID() {
+	p.deployID = DeployID
+	if p.deployID == "" {
+		p.deployID = newDeployID()
+	}
+}
+
 func (p *Project) resolveNamespace(namespaceFromCommand, namespaceFromConfig string) {
 	if namespaceFromCommand != "" {
 		p.namespace = namespaceFromCommand
@@ -283,7 +431,7 @@ func (p *Project) resolveVariables(variablesFromConfig map[string]string) {
 }
 
 func (p *Project) resolveResourceGraph(resourceGroupConfigs []*ResourceGroupConfig, includeResources []string, excludeResources []string) error {
-	resourceGraph, err := ReadResourceGraph(p.rootDir, resourceGroupConfigs, p.variables, includeResources, excludeResources)
+	resourceGraph, err := ReadResourceGraph(p.rootDir, resourceGroupConfigs, p.variables, includeResources, excludeResources, p.ownership())
 	if err != nil {
 		return err
 	}
@@ -291,16 +439,88 @@ func (p *Project) resolveResourceGraph(resourceGroupConfigs []*ResourceGroupConf
 	return nil
 }
 
+// ownership returns what is stamped on the resources of the project, nil if disabled
+func (p *Project) ownership() *Ownership {
+	ownership := &Ownership{
+		Project:  p.name,
+		DeployID: p.deployID,
+		RootDir:  p.rootDir,
+	}
+	if p.config.Ownership != nil {
+		if p.config.Ownership.Disabled {
+			return nil
+		}
+		ownership.Labels = p.config.Ownership.Labels
+		ownership.Annotations = p.config.Ownership.Annotations
+	}
+	return ownership
+}
+
+func (p *Project) newKubeContext(ctx gocontext.Context) (*kubernetes.Context, error) {
+	kubeContext, err := kubernetes.NewContext(p.namespace, p.context, p.kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return kubeContext.WithContext(ctx).WithWaitOptions(p.waitOptions()).WithOutput(p.Output()), nil
+}
+
+// groupKubeContext binds a kubernetes context to the context and output of the group being walked
+func groupKubeContext(ctx gocontext.Context, kubeContext *kubernetes.Context) *kubernetes.Context {
+	return kubeContext.WithContext(ctx).WithOutput(GroupOutput(ctx))
+}
+
+// waitOptions of the project, unset values fall back to kubernetes.DefaultWaitOptions
+func (p *Project) waitOptions() *kubernetes.WaitOptions {
+	waitOptions := *kubernetes.DefaultWaitOptions
+	config := p.waitOptionsConfig()
+	if config.Interval > 0 {
+		waitOptions.Interval = time.Duration(config.Interval) * time.Second
+	}
+	if config.MaxInterval > 0 {
+		waitOptions.MaxInterval = time.Duration(config.MaxInterval) * time.Second
+	}
+	if waitOptions.MaxInterval < waitOptions.Interval {
+		waitOptions.MaxInterval = waitOptions.Interval
+	}
+	if config.TransitionTimeout > 0 {
+		waitOptions.TransitionTimeout = time.Duration(config.TransitionTimeout) * time.Second
+	}
+	return &waitOptions
+}
+
+func (p *Project) readyTimeout() time.Duration {
+	if timeout := p.waitOptionsConfig().ReadyTimeout; timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	return defaultReadyTimeout
+}
+
+func (p *Project) deleteTimeout() time.Duration {
+	if timeout := p.waitOptionsConfig().DeleteTimeout; timeout > 0 {
+		return time.Duration(timeout) * time.Second
+	}
+	return defaultDeleteTimeout
+}
+
+func (p *Project) waitOptionsConfig() *WaitOptionsConfig {
+	if p.config == nil || p.config.WaitOptions == nil {
+		return &WaitOptionsConfig{}
+	}
+	return p.config.WaitOptions
+}
+
 func (p *Project) createNamespace(kubeContext *kubernetes.Context) error {
 	if p.namespace == "" {
 		return nil
 	}
-	utils.Info("Creating namespace %q", p.namespace)
+	utils.Infof(kubeContext.Output(), "Creating namespace %q", p.namespace)
+	start := time.Now()
 	exists, err := kubeContext.Namespace().Create()
+	p.events.resource(createEventType(exists), "", "Namespace", p.namespace, start, err)
 	if err != nil {
 		return err
 	}
-	p.printCreateResult(exists)
+	p.printCreateResult(kubeContext.Output(), exists)
 	return nil
 }
 
@@ -308,125 +528,278 @@ func (p *Project) deleteNamespace(kubeContext *kubernetes.Context) error {
 	if p.namespace == "" || p.namespace == "default" || !p.deleteNamespaceConfig {
 		return nil
 	}
-	utils.Warn("Deleting namespace %q", p.namespace)
+	utils.Warnf(kubeContext.Output(), "Deleting namespace %q", p.namespace)
+	start := time.Now()
 	exists, err := kubeContext.Namespace().Delete()
+	p.events.resource(deleteEventType(exists), "", "Namespace", p.namespace, start, err)
 	if err != nil {
 		return err
 	}
-	p.printDeleteResult(exists)
+	p.printDeleteResult(kubeContext.Output(), exists)
 	return nil
 }
 
-func (p *Project) createResource(kubeContext *kubernetes.Context, g *ResourceGroup, r *Resource) error {
-	utils.Info("Creating %s %q in group %q", r.Kind, r.Name, g.Name)
+func (p *Project) createResource(kubeContext *kubernetes.Context, pr *progress, g *ResourceGroup, r *Resource) error {
+	utils.Infof(kubeContext.Output(), "Creating %s %q in group %q", r.Kind, r.Name, g.Name)
+	start := time.Now()
 	exists, err := kubeContext.Resource().Create(r.Name, r.Kind, r.RawContent)
+	p.events.resource(createEventType(exists), g.Name, r.Kind, r.Name, start, err)
 	if err != nil {
+		if !isInterrupted(err) {
+			diagnose(kubeContext, r.Name, r.Kind)
+		}
 		return err
 	}
-	p.printCreateResult(exists)
+	if !exists {
+		pr.markApplied(r)
+	}
+	p.printCreateResult(kubeContext.Output(), exists)
 	return nil
 }
 
 func (p *Project) deleteResource(kubeContext *kubernetes.Context, g *ResourceGroup, r *Resource) error {
-	utils.Warn("Deleting %s %q in group %q", r.Kind, r.Name, g.Name)
+	utils.Warnf(kubeContext.Output(), "Deleting %s %q in group %q", r.Kind, r.Name, g.Name)
+	start := time.Now()
 	exists, err := kubeContext.Resource().Delete(r.Name, r.Kind)
+	p.events.resource(deleteEventType(exists), g.Name, r.Kind, r.Name, start, err)
 	if err != nil {
 		return err
 	}
-	p.printDeleteResult(exists)
+	p.printDeleteResult(kubeContext.Output(), exists)
 	return nil
 }
 
-func (p *Project) updateResource(kubeContext *kubernetes.Context, g *ResourceGroup, r *Resource) error {
-	utils.Warn("Updating %s %q in group %q", r.Kind, r.Name, g.Name)
+func (p *Project) updateResource(kubeContext *kubernetes.Context, pr *progress, g *ResourceGroup, r *Resource) error {
+	utils.Warnf(kubeContext.Output(), "Updating %s %q in group %q", r.Kind, r.Name, g.Name)
+	start := time.Now()
 	updateStatus, err := kubeContext.Resource().Update(r.Name, r.Kind, r.RawContent)
+	p.events.resource(updateEventType(updateStatus), g.Name, r.Kind, r.Name, start, err)
 	if err != nil {
+		if !isInterrupted(err) {
+			diagnose(kubeContext, r.Name, r.Kind)
+		}
 		return err
 	}
-	p.printUpdateResult(updateStatus)
+	if updateStatus == kubernetes.UpdateStatusExisted {
+		pr.markApplied(r)
+	}
+	p.printUpdateResult(kubeContext.Output(), updateStatus)
 	return nil
 }
 
-func (p *Project) upgradeResource(kubeContext *kubernetes.Context, g *ResourceGroup, r *Resource) error {
-	utils.Warn("Upgrading %s %q in group %q", r.Kind, r.Name, g.Name)
+func (p *Project) upgradeResource(kubeContext *kubernetes.Context, pr *progress, g *ResourceGroup, r *Resource) error {
+	utils.Warnf(kubeContext.Output(), "Upgrading %s %q in group %q", r.Kind, r.Name, g.Name)
+	start := time.Now()
 	updateStatus, err := kubeContext.Resource().Upgrade(r.Name, r.Kind, r.RawContent)
+	p.events.resource(updateEventType(updateStatus), g.Name, r.Kind, r.Name, start, err)
 	if err != nil {
+		if !isInterrupted(err) {
+			diagnose(kubeContext, r.Name, r.Kind)
+		}
 		return err
 	}
-	p.printUpdateResult(updateStatus)
+	if updateStatus != kubernetes.UpdateStatusSkipped {
+		pr.markApplied(r)
+	}
+	p.printUpdateResult(kubeContext.Output(), updateStatus)
 	return nil
 }
 
-func (p *Project) waitForExists(kubeContext *kubernetes.Context, r *Resource) error {
-	count := 0
-	for {
-		exists, err := kubeContext.Resource().Exists(r.Name, r.Kind)
-		if err != nil {
-			return err
-		}
-		if exists {
-			return nil
-		}
-		count++
-		if count > waitCount {
-			return stacktrace.Propagate(ErrWaitTimeout{r.Name, r.Kind}, "wait timeout")
+// walkApply walks the resource graph forward, calling f on every resource, or batchFn on every group in batch mode.
+// Completed groups are recorded in the checkpoint of the operation, if any.
+func (p *Project) walkApply(ctx gocontext.Context, pr *progress, f ResourceFunc, batchFn GroupFunc, readyFunc ResourceFunc, waitFunc WaitFunc) error {
+	groupFn := pr.trackGroup(batchFn)
+	if !p.batch {
+		f = pr.track(f)
+		groupFn = func(ctx gocontext.Context, g *ResourceGroup) error {
+			return g.walkResources(ctx, f)
 		}
-		time.Sleep(waitDelay)
 	}
+	for name := range p.resourceGraph.skipped {
+		pr.markDone(p.resourceGraph.ResourceGroups[name])
+	}
+	return p.resourceGraph.WalkForwardWithWait(ctx, p.checkpointGroups(groupFn), readyFunc, waitFunc)
 }
 
-func (p *Project) waitForDeleted(kubeContext *kubernetes.Context, r *Resource) error {
-	count := 0
-	for {
-		exists, err := kubeContext.Resource().Exists(r.Name, r.Kind)
-		if err != nil {
-			return err
+// emitBatchResults emits the result of every resource of a batch, timed as the whole batch.
+// Every resource of a failed batch is reported as failed.
+func (p *Project) emitBatchResults(g *ResourceGroup, resources []*kubernetes.BatchResource, results []*kubernetes.BatchResult, start time.Time, err error) {
+	if err != nil {
+		for _, resource := range resources {
+			p.events.resource(EventResourceFailed, g.Name, resource.Kind, resource.Name, start, err)
 		}
-		if !exists {
-			return nil
+		return
+	}
+	for _, result := range results {
+		p.events.resource(batchEventType(result), g.Name, result.Kind, result.Name, start, nil)
+	}
+}
+
+func (p *Project) createGroup(kubeContext *kubernetes.Context, pr *progress, g *ResourceGroup) error {
+	resources := g.batchResources()
+	if len(resources) == 0 {
+		return nil
+	}
+	utils.Infof(kubeContext.Output(), "Creating %d resources in group %q", len(resources), g.Name)
+	start := time.Now()
+	results, err := kubeContext.Resource().CreateBatch(resources)
+	p.emitBatchResults(g, resources, results, start, err)
+	if err != nil {
+		return err
+	}
+	pr.markBatchApplied(g, results)
+	for _, result := range results {
+		if result.Exists {
+			utils.Warnf(kubeContext.Output(), "====> %s %q existed", result.Kind, result.Name)
+		} else {
+			p.printBatchResult(kubeContext.Output(), result)
 		}
-		count++
-		if count > waitCount {
-			return stacktrace.Propagate(ErrWaitTimeout{r.Name, r.Kind}, "wait timeout")
+	}
+	return nil
+}
+
+func (p *Project) updateGroup(kubeContext *kubernetes.Context, pr *progress, g *ResourceGroup) error {
+	resources := g.batchResources()
+	if len(resources) == 0 {
+		return nil
+	}
+	utils.Warnf(kubeContext.Output(), "Updating %d resources in group %q", len(resources), g.Name)
+	start := time.Now()
+	results, err := kubeContext.Resource().UpdateBatch(resources)
+	p.emitBatchResults(g, resources, results, start, err)
+	if err != nil {
+		return err
+	}
+	pr.markBatchApplied(g, results)
+	p.printBatchUpdateResults(kubeContext.Output(), results)
+	return nil
+}
+
+func (p *Project) upgradeGroup(kubeContext *kubernetes.Context, pr *progress, g *ResourceGroup) error {
+	resources := g.batchResources()
+	if len(resources) == 0 {
+		return nil
+	}
+	utils.Warnf(kubeContext.Output(), "Upgrading %d resources in group %q", len(resources), g.Name)
+	start := time.Now()
+	results, err := kubeContext.Resource().UpgradeBatch(resources)
+	p.emitBatchResults(g, resources, results, start, err)
+	if err != nil {
+		return err
+	}
+	pr.markBatchApplied(g, results)
+	p.printBatchUpdateResults(kubeContext.Output(), results)
+	return nil
+}
+
+// waitForReady waits until a resource can be used by the groups depending on it
+func (p *Project) waitForReady(kubeContext *kubernetes.Context, g *ResourceGroup, r *Resource) (err error) {
+	p.events.waitStarted(g.Name, r.Kind, r.Name, "ready")
+	start := time.Now()
+	defer func() {
+		p.events.waitFinished(g.Name, r.Kind, r.Name, "ready", start, err)
+	}()
+	readiness, err := kubeContext.Resource().WaitReady(r.Name, r.Kind, p.readyTimeout(), func(readiness *kubernetes.Readiness) {
+		if readiness.Message != "" && !readiness.Ready {
+			utils.Infof2(kubeContext.Output(), "%s", readiness.Message)
 		}
-		time.Sleep(waitDelay)
+	})
+	if _, ok := stacktrace.RootCause(err).(kubernetes.ErrTimeout); ok {
+		return stacktrace.Propagate(ErrWaitTimeout{r.Name, r.Kind}, "wait timeout")
+	}
+	if err != nil {
+		return err
+	}
+	if readiness.Failed {
+		return stacktrace.Propagate(ErrWaitFailed{r.Name, r.Kind}, "%s", readiness.Message)
 	}
+	return nil
 }
 
-func (p *Project) waitForResource(kubeContext *kubernetes.Context, name, kind string) error {
-	success, err := kubeContext.Resource().Wait(name, kind)
+func (p *Project) waitForDeleted(kubeContext *kubernetes.Context, group string, r *Resource) (err error) {
+	p.events.waitStarted(group, r.Kind, r.Name, "deleted")
+	start := time.Now()
+	defer func() {
+		p.events.waitFinished(group, r.Kind, r.Name, "deleted", start, err)
+	}()
+	err = kubeContext.Resource().WaitDeleted(r.Name, r.Kind, p.deleteTimeout())
+	if _, ok := stacktrace.RootCause(err).(kubernetes.ErrTimeout); ok {
+		return stacktrace.Propagate(ErrWaitTimeout{r.Name, r.Kind}, "wait timeout")
+	}
+	return err
+}
+
+func (p *Project) waitForResource(kubeContext *kubernetes.Context, wait *WaitConfig) error {
+	utils.Infof2(kubeContext.Output(), "Waiting for %s %q", wait.Kind, wait.Name)
+	if wait.RestartThreshold != 0 {
+		kubeContext = kubeContext.WithRestartThreshold(wait.RestartThreshold)
+	}
+	if wait.UnschedulableGracePeriod != 0 {
+		kubeContext = kubeContext.WithUnschedulableGracePeriod(time.Duration(wait.UnschedulableGracePeriod) * time.Second)
+	}
+	success, err := kubeContext.Resource().WaitFor(wait.Name, wait.Kind, wait.For)
 	if err != nil {
 		return err
 	}
 	if success {
 		return nil
 	}
-	return stacktrace.Propagate(ErrWaitFailed{name, kind}, "wait failed")
+	return stacktrace.Propagate(ErrWaitFailed{wait.Name, wait.Kind}, "wait failed")
 }
 
-func (p *Project) printCreateResult(exists bool) {
+func isPVC(kind string) bool {
+	kind = strings.ToLower(kind)
+	return kind == "persistentvolumeclaim" || kind == "pvc"
+}
+
+func (p *Project) printCreateResult(out io.Writer, exists bool) {
 	if exists {
-		utils.Warn("====> Existed")
+		utils.Warnf(out, "====> Existed")
 	} else {
-		utils.Success("====> Success")
+		utils.Successf(out, "====> Success")
 	}
 }
 
-func (p *Project) printDeleteResult(exists bool) {
+func (p *Project) printDeleteResult(out io.Writer, exists bool) {
 	if exists {
-		utils.Success("====> Success")
+		utils.Successf(out, "====> Success")
 	} else {
-		utils.Warn("====> Not exist")
+		utils.Warnf(out, "====> Not exist")
 	}
 }
 
-func (p *Project) printUpdateResult(updateStatus kubernetes.UpdateStatus) {
+func (p *Project) printUpdateResult(out io.Writer, updateStatus kubernetes.UpdateStatus) {
 	switch updateStatus {
 	case kubernetes.UpdateStatusNotExist:
-		utils.Warn("====> Not exist")
+		utils.Warnf(out, "====> Not exist")
 	case kubernetes.UpdateStatusExisted:
-		utils.Success("====> Success")
+		utils.Successf(out, "====> Success")
 	case kubernetes.UpdateStatusSkipped:
-		utils.Info2("====> Skipped")
+		utils.Infof2(out, "====> Skipped")
+	}
+}
+
+func (p *Project) printBatchUpdateResults(out io.Writer, results []*kubernetes.BatchResult) {
+	for _, result := range results {
+		switch result.UpdateStatus {
+		case kubernetes.UpdateStatusSkipped:
+			utils.Infof2(out, "====> %s %q skipped", result.Kind, result.Name)
+		case kubernetes.UpdateStatusNotExist:
+			if result.Action == "" {
+				utils.Warnf(out, "====> %s %q not exist", result.Kind, result.Name)
+				break
+			}
+			p.printBatchResult(out, result)
+		default:
+			p.printBatchResult(out, result)
+		}
+	}
+}
+
+func (p *Project) printBatchResult(out io.Writer, result *kubernetes.BatchResult) {
+	if result.Action == "unchanged" {
+		utils.Infof2(out, "====> %s %q unchanged", result.Kind, result.Name)
+	} else {
+		utils.Successf(out, "====> %s %q %s", result.Kind, result.Name, result.Action)
 	}
 }
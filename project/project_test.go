@@ -3,6 +3,7 @@ package project
 import (
 	"bufio"
 	"bytes"
+	gocontext "context"
 	"encoding/json"
 	"path/filepath"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/anduintransaction/rivendell/utils"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	yaml "gopkg.in/yaml.v2"
 )
 
 type ProjectTestSuite struct {
@@ -269,7 +271,7 @@ func (s *ProjectTestSuite) TestGlobUrl() {
 	project, err := ReadProject(projectFile, "dota", "", "", nil, variableFiles, includes, excludes)
 	require.Nil(s.T(), err)
 	actualFiles := []string{}
-	project.resourceGraph.WalkForward(func(g *ResourceGroup) error {
+	project.resourceGraph.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
 		for _, f := range g.ResourceFiles {
 			actualFiles = append(actualFiles, f.Source)
 		}
@@ -287,6 +289,54 @@ func (s *ProjectTestSuite) TestGlobUrl() {
 	require.Equal(s.T(), expected, actualFiles)
 }
 
+func (s *ProjectTestSuite) TestOwnership() {
+	DeployID = "deploy-1"
+	defer func() {
+		DeployID = ""
+	}()
+	projectFile := filepath.Join(s.resourceRoot, "config-test", "ownership", "project.yml")
+	project, err := ReadProject(projectFile, "ownership", "", "", nil, nil, nil, nil)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), "ownership test", project.Name())
+	require.Equal(s.T(), "deploy-1", project.DeployID())
+
+	resources := project.resourceGraph.ResourceGroups["configs"].allResources()
+	require.Len(s.T(), resources, 2)
+	manifest := &ownershipManifest{}
+	err = yaml.Unmarshal([]byte(resources[0].RawContent), manifest)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), map[string]string{
+		"app":          "web",
+		"team":         "platform",
+		LabelManagedBy: "rivendell",
+		LabelProject:   "ownership-test",
+		LabelGroup:     "configs",
+	}, manifest.Metadata.Labels)
+	require.Equal(s.T(), map[string]string{
+		"owner":            "platform@example.com",
+		AnnotationSource:   "configs/config.yml",
+		AnnotationVersion:  utils.Version,
+		AnnotationDeployID: "deploy-1",
+	}, manifest.Metadata.Annotations)
+	require.Equal(s.T(), map[string]string{"port": "8080", "debug": "true"}, manifest.Data)
+	require.True(s.T(), strings.HasPrefix(resources[0].RawContent, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n"))
+
+	manifest = &ownershipManifest{}
+	err = yaml.Unmarshal([]byte(resources[1].RawContent), manifest)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), "ownership-test", manifest.Metadata.Labels[LabelProject])
+
+	resources = project.resourceGraph.ResourceGroups["jobs"].allResources()
+	require.Len(s.T(), resources, 1)
+	require.NotContains(s.T(), resources[0].RawContent, LabelManagedBy)
+}
+
+func (s *ProjectTestSuite) TestLabelValue() {
+	require.Equal(s.T(), "my-project", labelValue("my project"))
+	require.Equal(s.T(), "project", labelValue("-project/"))
+	require.Len(s.T(), labelValue(strings.Repeat("a", 100)), 63)
+}
+
 func (s *ProjectTestSuite) stripResourceContent(resourceGraph *ResourceGraph) *ResourceGraph {
 	// Deep copy to a new resource by encode - decode json
 	b, err := json.Marshal(resourceGraph)
@@ -322,6 +372,14 @@ func (s *ProjectTestSuite) verifyVariableValue(rawContent string, search, expect
 	}
 }
 
+type ownershipManifest struct {
+	Metadata struct {
+		Labels      map[string]string `yaml:"labels"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+	Data map[string]string `yaml:"data"`
+}
+
 func TestProject(t *testing.T) {
 	suite.Run(t, new(ProjectTestSuite))
 }
@@ -0,0 +1,145 @@
+package project
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+)
+
+// PrunePlan returns the live objects recorded in the inventory which are not rendered by the project anymore,
+// in the order they should be deleted
+func (p *Project) PrunePlan(ctx gocontext.Context) ([]*InventoryEntry, error) {
+	if p.release != nil && p.release.Partial {
+		return nil, stacktrace.NewError("cannot prune to release %d, it was deployed with include or exclude patterns", p.release.Version)
+	}
+	if p.filtered {
+		return nil, stacktrace.NewError("cannot prune a project read with include or exclude patterns")
+	}
+	kubeContext, err := p.newKubeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.orphans(kubeContext, p.pruneExcludeKinds())
+}
+
+// orphans returns the live objects recorded in the inventory which are not rendered by the project anymore,
+// latest applied first, leaving out excluded kinds
+func (p *Project) orphans(kubeContext *kubernetes.Context, excluded map[string]bool) ([]*InventoryEntry, error) {
+	inv, err := p.readInventory(kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	rendered := p.renderedKeys()
+	candidates := []*InventoryEntry{}
+	for i := len(inv.Entries) - 1; i >= 0; i-- {
+		entry := inv.Entries[i]
+		if rendered[entry.key()] || excluded[strings.ToLower(entry.Kind)] {
+			continue
+		}
+		manifest, err := kubeContext.Resource().Manifest(entry.Name, entry.Kind)
+		if err != nil {
+			return nil, err
+		}
+		if manifest == nil {
+			continue
+		}
+		owned, err := p.ownsEntry(manifest, entry)
+		if err != nil {
+			return nil, err
+		}
+		if owned {
+			candidates = append(candidates, entry)
+		}
+	}
+	return candidates, nil
+}
+
+type liveMetadata struct {
+	Metadata struct {
+		Labels map[string]string `json:"labels"`
+	} `json:"metadata"`
+}
+
+// ownsEntry tells whether the live manifest of an inventory entry is still labelled for the project and the group it
+// was recorded in, an object which another project or group applied since is not pruned
+func (p *Project) ownsEntry(manifest []byte, entry *InventoryEntry) (bool, error) {
+	live := &liveMetadata{}
+	err := json.Unmarshal(manifest, live)
+	if err != nil {
+		return false, stacktrace.Propagate(err, "cannot decode %s %q", entry.Kind, entry.Name)
+	}
+	labels := live.Metadata.Labels
+	return labels[LabelProject] == labelValue(p.name) && labels[LabelGroup] == labelValue(entry.Group), nil
+}
+
+// PrintPrunePlan .
+func (p *Project) PrintPrunePlan(out io.Writer, candidates []*InventoryEntry) {
+	if len(candidates) == 0 {
+		utils.Infof(out, "No resources to prune")
+		return
+	}
+	utils.Warnf(out, "The following resources are not in the project anymore and will be pruned:")
+	for _, entry := range candidates {
+		fmt.Fprintf(out, " - %s %q (group %q)\n", entry.Kind, entry.Name, entry.Group)
+	}
+}
+
+// Prune deletes objects returned by PrunePlan and removes them from the inventory
+func (p *Project) Prune(ctx gocontext.Context, candidates []*InventoryEntry) (err error) {
+	finish := p.events.startOperation("prune")
+	defer func() {
+		finish(err)
+	}()
+	kubeContext, err := p.newKubeContext(ctx)
+	if err != nil {
+		return err
+	}
+	deleted := make(map[string]bool)
+	for _, entry := range candidates {
+		if ctx.Err() != nil {
+			err = stacktrace.Propagate(ctx.Err(), "interrupted before pruning %s %q", entry.Kind, entry.Name)
+			break
+		}
+		utils.Warnf(kubeContext.Output(), "Pruning %s %q from group %q", entry.Kind, entry.Name, entry.Group)
+		var exists bool
+		start := time.Now()
+		exists, err = kubeContext.Resource().Delete(entry.Name, entry.Kind)
+		p.events.resource(deleteEventType(exists), entry.Group, entry.Kind, entry.Name, start, err)
+		if err != nil {
+			break
+		}
+		p.printDeleteResult(kubeContext.Output(), exists)
+		err = p.waitForDeleted(kubeContext, entry.Group, &Resource{Name: entry.Name, Kind: entry.Kind})
+		if err != nil {
+			break
+		}
+		deleted[entry.key()] = true
+	}
+	recordErr := p.recordDeleted(kubeContext.WithContext(gocontext.Background()), deleted)
+	if err != nil {
+		if recordErr != nil {
+			utils.Error(recordErr)
+		}
+		return err
+	}
+	return recordErr
+}
+
+// renderedKeys returns the inventory keys of all resources of the project
+func (p *Project) renderedKeys() map[string]bool {
+	rendered := make(map[string]bool)
+	p.resourceGraph.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
+		for _, r := range g.allResources() {
+			rendered[inventoryEntryKey(r.Kind, r.Name)] = true
+		}
+		return nil
+	})
+	return rendered
+}
@@ -0,0 +1,156 @@
+package project
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type PruneTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *PruneTestSuite) TestPrune() {
+	p := s.up("prune", nil)
+	kubeContext := s.kubeContext(p)
+	inv, err := p.readInventory(kubeContext)
+	require.Nil(s.T(), err)
+	require.Len(s.T(), inv.Entries, 6)
+
+	projectFile := filepath.Join(s.resourceRoot, "command-test", "prune", "project-reduced.yml")
+	reduced, err := ReadProject(projectFile, s.testNamespace, "", "", nil, []string{}, nil, nil)
+	require.Nil(s.T(), err)
+	candidates, err := reduced.PrunePlan(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []*InventoryEntry{
+		{Kind: "Service", Name: "redis", Group: "redis"},
+		{Kind: "Deployment", Name: "redis", Group: "redis"},
+	}, candidates, "removed resources are pruned in reverse order, except claims")
+	err = reduced.Update(gocontext.Background())
+	require.Nil(s.T(), err)
+	err = reduced.Prune(gocontext.Background(), candidates)
+	require.Nil(s.T(), err)
+	for _, kind := range []string{"service", "deployment"} {
+		require.False(s.T(), s.exists(kubeContext, "redis", kind))
+	}
+	require.True(s.T(), s.exists(kubeContext, "data", "persistentvolumeclaim"))
+	candidates, err = reduced.PrunePlan(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Empty(s.T(), candidates)
+
+	err = reduced.Down(gocontext.Background(), false, true)
+	require.Nil(s.T(), err)
+	inv, err = p.readInventory(kubeContext)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []*InventoryEntry{{Kind: "PersistentVolumeClaim", Name: "data", Group: "storage"}}, inv.Entries)
+
+	filtered, err := ReadProject(projectFile, s.testNamespace, "", "", nil, []string{}, nil, []string{"**/nginx.yml"})
+	require.Nil(s.T(), err)
+	_, err = filtered.PrunePlan(gocontext.Background())
+	require.NotNil(s.T(), err, "a filtered project cannot tell what was removed")
+}
+
+func (s *PruneTestSuite) TestCheckPlan() {
+	p := s.up("prune", nil)
+	kubeContext := s.kubeContext(p)
+	projectFile := filepath.Join(s.resourceRoot, "command-test", "prune", "project-reduced.yml")
+	reduced, err := ReadProject(projectFile, s.testNamespace, "", "", nil, []string{}, nil, nil)
+	require.Nil(s.T(), err)
+	plan, err := reduced.Plan(gocontext.Background(), OperationUpgrade, false)
+	require.Nil(s.T(), err)
+	candidates, err := reduced.PrunePlan(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), reduced.CheckPlan(gocontext.Background(), plan, true, candidates))
+
+	_, err = kubeContext.Resource().Delete("redis", "service")
+	require.Nil(s.T(), err)
+	err = reduced.CheckPlan(gocontext.Background(), plan, true, candidates)
+	require.NotNil(s.T(), err, "prune candidates removed by another run")
+	require.Equal(s.T(), ErrorTypeLocked, ErrorTypeOf(err))
+	require.Nil(s.T(), reduced.CheckPlan(gocontext.Background(), plan, false, nil))
+
+	_, err = kubeContext.Resource().Delete("nginx", "deployment")
+	require.Nil(s.T(), err)
+	err = reduced.CheckPlan(gocontext.Background(), plan, false, nil)
+	require.NotNil(s.T(), err, "resources deleted by another run")
+	_, ok := stacktrace.RootCause(err).(ErrPlanChanged)
+	require.True(s.T(), ok, "%v", err)
+	s.down(p)
+}
+
+func (s *PruneTestSuite) TestInventoryRecordsAppliedResources() {
+	for _, batch := range []bool{false, true} {
+		p := s.up("prune", nil)
+		kubeContext := s.kubeContext(p)
+		for _, name := range []string{"redis", p.inventoryName()} {
+			kind := "service"
+			if name == p.inventoryName() {
+				kind = "configmap"
+			}
+			_, err := kubeContext.Resource().Delete(name, kind)
+			require.Nil(s.T(), err)
+		}
+		err := p.SetBatch(batch).Update(gocontext.Background())
+		require.Nil(s.T(), err)
+		inv, err := p.readInventory(kubeContext)
+		require.Nil(s.T(), err)
+		require.Len(s.T(), inv.Entries, 5)
+		for _, entry := range inv.Entries {
+			require.NotEqual(s.T(), "service/redis", entry.key(), "a missing resource skipped by update is not applied")
+		}
+		s.down(p)
+	}
+}
+
+func (s *PruneTestSuite) TestPruneSkipsTakenOverResources() {
+	p := s.up("prune", nil)
+	kubeContext := s.kubeContext(p)
+	for _, labels := range []map[string]string{
+		{LabelProject: "other", LabelGroup: "redis"},
+		{LabelProject: "prune", LabelGroup: "cache"},
+	} {
+		manifest, err := json.Marshal(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"name": "redis", "labels": labels},
+		})
+		require.Nil(s.T(), err)
+		require.Nil(s.T(), kubeContext.Resource().Put(string(manifest)))
+		projectFile := filepath.Join(s.resourceRoot, "command-test", "prune", "project-reduced.yml")
+		reduced, err := ReadProject(projectFile, s.testNamespace, "", "", nil, []string{}, nil, nil)
+		require.Nil(s.T(), err)
+		candidates, err := reduced.PrunePlan(gocontext.Background())
+		require.Nil(s.T(), err)
+		require.Equal(s.T(), []*InventoryEntry{
+			{Kind: "Deployment", Name: "redis", Group: "redis"},
+		}, candidates, "a resource labelled for another project or group is not pruned")
+	}
+	s.down(p)
+}
+
+func (s *PruneTestSuite) TestProjectIdentity() {
+	named := &Project{}
+	named.resolveName(filepath.Join("a", "app", "project.yml"), "shop")
+	require.Equal(s.T(), "rivendell-inventory-shop", named.inventoryName())
+	require.Equal(s.T(), "rivendell-checkpoint-shop", named.checkpointName())
+
+	first := &Project{}
+	first.resolveName(filepath.Join("a", "app", "project.yml"), "")
+	second := &Project{}
+	second.resolveName(filepath.Join("b", "app", "project.yml"), "")
+	require.Equal(s.T(), first.Name(), second.Name())
+	require.NotEqual(s.T(), first.inventoryName(), second.inventoryName(), "projects named after their directory do not share an inventory")
+	require.NotEqual(s.T(), first.checkpointName(), second.checkpointName())
+	again := &Project{}
+	again.resolveName(filepath.Join("a", "app", "project.yml"), "")
+	require.Equal(s.T(), first.inventoryName(), again.inventoryName())
+}
+
+func TestPrune(t *testing.T) {
+	suite.Run(t, new(PruneTestSuite))
+}
@@ -0,0 +1,374 @@
+package project
+
+import (
+	"bytes"
+	"compress/gzip"
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/palantir/stacktrace"
+)
+
+const (
+	// LabelRelease holds the version of a release record
+	LabelRelease = "rivendell.anduintransaction.com/release"
+
+	releaseKey          = "release"
+	releaseSecretType   = "rivendell.anduintransaction.com/release"
+	defaultHistoryLimit = 10
+	maskedValue         = "******"
+)
+
+// sensitiveVariable matches the names of variables masked in release records
+var sensitiveVariable = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|private|key|cert)`)
+
+// Release is the record of a successful deployment of a project, stored in a Secret of the namespace
+type Release struct {
+	Project string `json:"project"`
+	// Identity keys the inventory and the checkpoint of the project, see resolveName
+	Identity        string             `json:"identity,omitempty"`
+	Version         int                `json:"version"`
+	DeployID        string             `json:"deployId"`
+	Operation       string             `json:"operation"`
+	User            string             `json:"user"`
+	Timestamp       time.Time          `json:"timestamp"`
+	ProjectFileHash string             `json:"projectFileHash"`
+	Variables       map[string]string  `json:"variables"`
+	Groups          []*ReleaseGroup    `json:"groups"`
+	Manifests       []*ReleaseManifest `json:"manifests"`
+	// Partial tells the release was deployed with include or exclude patterns, its manifests are not the whole project
+	Partial bool `json:"partial,omitempty"`
+}
+
+// ReleaseGroup is a resource group of a release, with its dependencies and waits
+type ReleaseGroup struct {
+	Name   string        `json:"name"`
+	Depend []string      `json:"depend"`
+	Wait   []*WaitConfig `json:"wait"`
+}
+
+// ReleaseManifest is a rendered resource of a release
+type ReleaseManifest struct {
+	Group   string `json:"group"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+type releaseSecret struct {
+	Data map[string]string `json:"data"`
+}
+
+// History returns the releases of the project recorded in its namespace, latest first
+func (p *Project) History(ctx gocontext.Context) ([]*Release, error) {
+	kubeContext, err := p.newKubeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.readReleases(kubeContext)
+}
+
+// Release returns a release of the project by version
+func (p *Project) Release(ctx gocontext.Context, version int) (*Release, error) {
+	releases, err := p.History(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, release := range releases {
+		if release.Version == version {
+			return release, nil
+		}
+	}
+	return nil, stacktrace.Propagate(ErrReleaseNotFound{p.name, version}, "release not found")
+}
+
+// PrintHistory .
+func (p *Project) PrintHistory(out io.Writer, releases []*Release) {
+	if len(releases) == 0 {
+		fmt.Fprintf(out, "No release of project %q in namespace %q\n", p.name, p.namespace)
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tDEPLOYED\tOPERATION\tUSER\tDEPLOY ID\tRESOURCES\tPROJECT FILE")
+	for _, release := range releases {
+		operation := release.Operation
+		if release.Partial {
+			operation += " (partial)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			release.Version,
+			release.Timestamp.Local().Format(time.RFC3339),
+			operation,
+			release.User,
+			release.DeployID,
+			len(release.Manifests),
+			shortHash(release.ProjectFileHash),
+		)
+	}
+	w.Flush()
+}
+
+// PrintRelease prints the variables and rendered manifests of a release
+func (p *Project) PrintRelease(out io.Writer, release *Release) {
+	fmt.Fprintf(out, "Version:      %d\n", release.Version)
+	fmt.Fprintf(out, "Deployed:     %s\n", release.Timestamp.Local().Format(time.RFC3339))
+	fmt.Fprintf(out, "Operation:    %s\n", release.Operation)
+	fmt.Fprintf(out, "User:         %s\n", release.User)
+	fmt.Fprintf(out, "Deploy ID:    %s\n", release.DeployID)
+	fmt.Fprintf(out, "Project file: %s\n", release.ProjectFileHash)
+	if release.Partial {
+		fmt.Fprintln(out, "Partial:      yes, deployed with include or exclude patterns")
+	}
+	fmt.Fprintln(out, "Variables:")
+	names := []string{}
+	for name := range release.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %s=%s\n", name, release.Variables[name])
+	}
+	for _, manifest := range release.Manifests {
+		fmt.Fprintf(out, "---\n# group %q, %s %q\n%s\n", manifest.Group, manifest.Kind, manifest.Name, manifest.Content)
+	}
+}
+
+// recordRelease writes a release record after a successful operation
+func (p *Project) recordRelease(kubeContext *kubernetes.Context, operation string) error {
+	release, err := p.newRelease(operation)
+	if err != nil {
+		return err
+	}
+	return p.writeRelease(kubeContext, release)
+}
+
+// writeRelease stores a release as the next version and drops the records beyond the history limit
+func (p *Project) writeRelease(kubeContext *kubernetes.Context, release *Release) error {
+	releases, err := p.readReleases(kubeContext)
+	if err != nil {
+		return err
+	}
+	release.Version = 1
+	if len(releases) > 0 {
+		release.Version = releases[0].Version + 1
+	}
+	manifest, err := p.releaseManifest(release)
+	if err != nil {
+		return err
+	}
+	err = kubeContext.Resource().Put(manifest)
+	if err != nil {
+		return err
+	}
+	releases = append([]*Release{release}, releases...)
+	for _, old := range releases[min(len(releases), p.historyLimit()):] {
+		_, err = kubeContext.Resource().Delete(p.releaseName(old.Version), "secret")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newRelease records the rendered resource graph of the project
+func (p *Project) newRelease(operation string) (*Release, error) {
+	release := &Release{
+		Project:   p.name,
+		Identity:  p.identity,
+		DeployID:  p.deployID,
+		Operation: operation,
+		User:      currentUser(),
+		Timestamp: time.Now().UTC(),
+		Groups:    []*ReleaseGroup{},
+		Manifests: []*ReleaseManifest{},
+		Partial:   p.filtered,
+	}
+	if p.release != nil {
+		release.ProjectFileHash = p.release.ProjectFileHash
+		release.Variables = p.release.Variables
+	} else {
+		projectFileHash, err := p.projectFileHash()
+		if err != nil {
+			return nil, err
+		}
+		release.ProjectFileHash = projectFileHash
+		release.Variables = maskVariables(p.variables)
+	}
+	p.resourceGraph.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
+		release.Groups = append(release.Groups, &ReleaseGroup{
+			Name:   g.Name,
+			Depend: g.Depend,
+			Wait:   g.Wait,
+		})
+		for _, r := range g.allResources() {
+			release.Manifests = append(release.Manifests, &ReleaseManifest{
+				Group:   g.Name,
+				Kind:    r.Kind,
+				Name:    r.Name,
+				Content: r.RawContent,
+			})
+		}
+		return nil
+	})
+	return release, nil
+}
+
+// readReleases returns the release records of the project, latest first
+func (p *Project) readReleases(kubeContext *kubernetes.Context) ([]*Release, error) {
+	selector := fmt.Sprintf("%s=%s,%s=%s", LabelManagedBy, managedByRivendell, LabelProject, labelValue(p.name))
+	manifests, err := kubeContext.Resource().List("secret", selector)
+	if err != nil {
+		return nil, err
+	}
+	releases := []*Release{}
+	for _, manifest := range manifests {
+		secret := &releaseSecret{}
+		err = json.Unmarshal(manifest, secret)
+		if err != nil {
+			return nil, stacktrace.Propagate(err, "cannot decode release")
+		}
+		data, ok := secret.Data[releaseKey]
+		if !ok {
+			continue
+		}
+		release, err := decodeRelease(data)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, release)
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].Version > releases[j].Version
+	})
+	return releases, nil
+}
+
+// releaseManifest returns the Secret holding a release, compressed
+func (p *Project) releaseManifest(release *Release) (string, error) {
+	content, err := json.Marshal(release)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "cannot encode release")
+	}
+	compressed := &bytes.Buffer{}
+	writer := gzip.NewWriter(compressed)
+	_, err = writer.Write(content)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		return "", stacktrace.Propagate(err, "cannot compress release")
+	}
+	secret := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"type":       releaseSecretType,
+		"metadata": map[string]interface{}{
+			"name": p.releaseName(release.Version),
+			"labels": map[string]string{
+				LabelManagedBy: managedByRivendell,
+				LabelProject:   labelValue(p.name),
+				LabelRelease:   strconv.Itoa(release.Version),
+			},
+		},
+		"data": map[string]string{
+			releaseKey: base64.StdEncoding.EncodeToString(compressed.Bytes()),
+		},
+	}
+	manifest, err := json.Marshal(secret)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "cannot encode release")
+	}
+	return string(manifest), nil
+}
+
+func decodeRelease(data string) (*Release, error) {
+	compressed, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode release")
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decompress release")
+	}
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decompress release")
+	}
+	release := &Release{}
+	err = json.Unmarshal(content, release)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot decode release")
+	}
+	return release, nil
+}
+
+func (p *Project) releaseName(version int) string {
+	return fmt.Sprintf("rivendell-release-%s-v%d", strings.TrimPrefix(p.inventoryName(), "rivendell-inventory-"), version)
+}
+
+func (p *Project) historyLimit() int {
+	if p.config != nil && p.config.HistoryLimit > 0 {
+		return p.config.HistoryLimit
+	}
+	return defaultHistoryLimit
+}
+
+func (p *Project) projectFileHash() (string, error) {
+	content, err := ioutil.ReadFile(p.projectFile)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "cannot read project file %q", p.projectFile)
+	}
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// maskVariables hides the values of variables which look like secrets
+func maskVariables(variables map[string]string) map[string]string {
+	masked := make(map[string]string)
+	for name, value := range variables {
+		if sensitiveVariable.MatchString(name) {
+			value = maskedValue
+		}
+		masked[name] = value
+	}
+	return masked
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return "unknown"
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,71 @@
+package project
+
+import (
+	gocontext "context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReleaseTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *ReleaseTestSuite) TestHistory() {
+	p := s.readProject("history", nil)
+	releases, err := p.History(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Empty(s.T(), releases)
+
+	err = p.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+	err = p.Update(gocontext.Background())
+	require.Nil(s.T(), err)
+	updated := s.readProject("history", map[string]string{"greeting": "hi"})
+	err = updated.Upgrade(gocontext.Background())
+	require.Nil(s.T(), err)
+
+	releases, err = p.History(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Len(s.T(), releases, 2, "releases beyond the history limit are dropped")
+	require.Equal(s.T(), 3, releases[0].Version)
+	require.Equal(s.T(), "upgrade", releases[0].Operation)
+	require.Equal(s.T(), 2, releases[1].Version)
+	require.Equal(s.T(), "update", releases[1].Operation)
+	require.Equal(s.T(), "hi", releases[0].Variables["greeting"])
+	require.Equal(s.T(), "******", releases[0].Variables["dbPassword"], "secret looking variables are masked")
+	require.NotEmpty(s.T(), releases[0].ProjectFileHash)
+	require.NotEmpty(s.T(), releases[0].User)
+	require.Len(s.T(), releases[0].Manifests, 1)
+	require.Equal(s.T(), "greeting", releases[0].Manifests[0].Name)
+	require.Contains(s.T(), releases[0].Manifests[0].Content, "greeting: hi")
+
+	release, err := p.Release(gocontext.Background(), 2)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), "hello", release.Variables["greeting"])
+	_, err = p.Release(gocontext.Background(), 1)
+	require.NotNil(s.T(), err)
+	s.down(p)
+}
+
+func (s *ReleaseTestSuite) TestPartialRelease() {
+	p := s.up("history", nil)
+	projectFile := filepath.Join(s.resourceRoot, "command-test", "history", "project.yml")
+	filtered, err := ReadProject(projectFile, s.testNamespace, "", "", map[string]string{}, []string{}, nil, []string{"**/not-exists.yml"})
+	require.Nil(s.T(), err)
+	err = filtered.Update(gocontext.Background())
+	require.Nil(s.T(), err)
+
+	releases, err := p.History(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Len(s.T(), releases, 2)
+	require.True(s.T(), releases[0].Partial, "a filtered run does not render the whole project")
+	require.False(s.T(), releases[1].Partial)
+	s.down(p)
+}
+
+func TestRelease(t *testing.T) {
+	suite.Run(t, new(ReleaseTestSuite))
+}
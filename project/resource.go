@@ -1,9 +1,12 @@
 package project
 
 import (
+	gocontext "context"
+	"io"
 	"sort"
 	"time"
 
+	"github.com/anduintransaction/rivendell/kubernetes"
 	"github.com/anduintransaction/rivendell/utils"
 	"github.com/palantir/stacktrace"
 )
@@ -13,6 +16,18 @@ type ResourceGraph struct {
 	ResourceGroups map[string]*ResourceGroup
 	RootNodes      []string
 	LeafNodes      []string
+	// Parallelism is the maximum number of groups processed at the same time by the walks with wait.
+	// Groups are processed one at a time in BFS order when <= 1.
+	Parallelism int
+	// KeepGoing makes the walks with wait go on after a group failed: the groups after it are skipped, the other
+	// groups are processed and the walk returns ErrGroupsFailed.
+	KeepGoing bool
+	// events receives the start and end of every group and wait of the walks with wait, see SetEventOutput
+	events *eventEmitter
+	// skipped groups are neither processed nor waited for by the walks with wait, see SetResume
+	skipped map[string]bool
+	// out receives the human readable output of the walks with wait, see SetOutput
+	out io.Writer
 }
 
 // ResourceGroup holds configuration for a resource group
@@ -56,8 +71,11 @@ const (
 	defaultWaitTimeout = 300
 )
 
-// ReadResourceGraph .
-func ReadResourceGraph(rootDir string, resourceGroupConfigs []*ResourceGroupConfig, variables map[string]string, includeResources []string, excludeResources []string) (*ResourceGraph, error) {
+// WaitFunc waits for a resource of a group wait list, it must return once ctx is done
+type WaitFunc func(ctx gocontext.Context, wait *WaitConfig) error
+
+// ReadResourceGraph . Resources are stamped with the ownership labels and annotations unless ownership is nil
+func ReadResourceGraph(rootDir string, resourceGroupConfigs []*ResourceGroupConfig, variables map[string]string, includeResources []string, excludeResources []string, ownership *Ownership) (*ResourceGraph, error) {
 	rg := &ResourceGraph{
 		ResourceGroups: make(map[string]*ResourceGroup),
 		RootNodes:      []string{},
@@ -74,6 +92,15 @@ func ReadResourceGraph(rootDir string, resourceGroupConfigs []*ResourceGroupConf
 		if g.Wait == nil {
 			g.Wait = []*WaitConfig{}
 		}
+		for _, wait := range g.Wait {
+			if wait.For == "" {
+				continue
+			}
+			err := kubernetes.ValidateWaitCondition(wait.For)
+			if err != nil {
+				return nil, stacktrace.Propagate(err, "invalid wait for %s %q in group %q", wait.Kind, wait.Name, g.Name)
+			}
+		}
 		rg.ResourceGroups[g.Name] = g
 		if len(g.Depend) == 0 {
 			rg.RootNodes = append(rg.RootNodes, g.Name)
@@ -89,6 +116,9 @@ func ReadResourceGraph(rootDir string, resourceGroupConfigs []*ResourceGroupConf
 			stripNamespace(),
 			splitResourceContent(),
 		}
+		if ownership != nil && !resourceGroupConfig.DisableOwnership {
+			processors = append(processors, stampOwnership(ownership, g.Name))
+		}
 		for _, resourceFile := range resourceFiles {
 			for _, proc := range processors {
 				if err := proc.Process(resourceFile); err != nil {
@@ -112,29 +142,17 @@ func ReadResourceGraph(rootDir string, resourceGroupConfigs []*ResourceGroupConf
 }
 
 // WalkForwardWithWait from root nodes
-func (rg *ResourceGraph) WalkForwardWithWait(f func(g *ResourceGroup) error, readyFunc func(r *Resource, g *ResourceGroup) error, waitFunc func(name, kind string) error) error {
-	readyResourceGroups := make(map[*ResourceGroup]bool)
-	readyResources := make(map[*Resource]bool)
-	return rg.WalkForward(func(g *ResourceGroup) error {
+func (rg *ResourceGraph) WalkForwardWithWait(ctx gocontext.Context, f GroupFunc, readyFunc ResourceFunc, waitFunc WaitFunc) error {
+	readiness := newReadinessTracker()
+	return rg.walkGroups(ctx, false, func(ctx gocontext.Context, g *ResourceGroup) error {
 		for _, depGroupName := range g.Depend {
-			depGroup := rg.ResourceGroups[depGroupName]
-			if !readyResourceGroups[depGroup] {
-				for _, r := range depGroup.allResources() {
-					if !readyResources[r] {
-						if readyFunc != nil {
-							err := readyFunc(r, depGroup)
-							if err != nil {
-								return err
-							}
-						}
-						readyResources[r] = true
-					}
-				}
+			err := rg.waitReady(ctx, readiness, rg.ResourceGroups[depGroupName], readyFunc)
+			if err != nil {
+				return err
 			}
-			readyResourceGroups[depGroup] = true
 		}
 		for _, wait := range g.Wait {
-			err := rg.waitFor(wait, waitFunc)
+			err := rg.waitFor(ctx, g, wait, waitFunc)
 			if err != nil {
 				return err
 			}
@@ -142,41 +160,29 @@ func (rg *ResourceGraph) WalkForwardWithWait(f func(g *ResourceGroup) error, rea
 		if f == nil {
 			return nil
 		}
-		return f(g)
+		return f(ctx, g)
 	})
 }
 
 // WalkBackwardWithWait from leaf nodes
-func (rg *ResourceGraph) WalkBackwardWithWait(f func(g *ResourceGroup) error, readyFunc func(r *Resource, g *ResourceGroup) error) error {
-	readyResourceGroups := make(map[*ResourceGroup]bool)
-	readyResources := make(map[*Resource]bool)
-	return rg.WalkBackward(func(g *ResourceGroup) error {
+func (rg *ResourceGraph) WalkBackwardWithWait(ctx gocontext.Context, f GroupFunc, readyFunc ResourceFunc) error {
+	readiness := newReadinessTracker()
+	return rg.walkGroups(ctx, true, func(ctx gocontext.Context, g *ResourceGroup) error {
 		for _, depGroupName := range g.Children {
-			depGroup := rg.ResourceGroups[depGroupName]
-			if !readyResourceGroups[depGroup] {
-				for _, r := range depGroup.allResources() {
-					if !readyResources[r] {
-						if readyFunc != nil {
-							err := readyFunc(r, depGroup)
-							if err != nil {
-								return err
-							}
-						}
-						readyResources[r] = true
-					}
-				}
+			err := rg.waitReady(ctx, readiness, rg.ResourceGroups[depGroupName], readyFunc)
+			if err != nil {
+				return err
 			}
-			readyResourceGroups[depGroup] = true
 		}
 		if f == nil {
 			return nil
 		}
-		return f(g)
+		return f(ctx, g)
 	})
 }
 
-// WalkForward through the graph, BFS style
-func (rg *ResourceGraph) WalkForward(f func(g *ResourceGroup) error) error {
+// WalkForward through the graph, BFS style. The walk stops before the next group once ctx is done
+func (rg *ResourceGraph) WalkForward(ctx gocontext.Context, f func(g *ResourceGroup) error) error {
 	candidates := append([]string{}, rg.RootNodes...)
 	visited := utils.NewStringSet()
 	for len(candidates) > 0 {
@@ -190,6 +196,9 @@ func (rg *ResourceGraph) WalkForward(f func(g *ResourceGroup) error) error {
 				}
 			}
 			if depVisited {
+				if ctx.Err() != nil {
+					return stacktrace.Propagate(ctx.Err(), "interrupted before group %q", current)
+				}
 				if f != nil {
 					err := f(rg.ResourceGroups[current])
 					if err != nil {
@@ -204,8 +213,8 @@ func (rg *ResourceGraph) WalkForward(f func(g *ResourceGroup) error) error {
 	return nil
 }
 
-// WalkBackward through the graph, BFS style
-func (rg *ResourceGraph) WalkBackward(f func(g *ResourceGroup) error) error {
+// WalkBackward through the graph, BFS style. The walk stops before the next group once ctx is done
+func (rg *ResourceGraph) WalkBackward(ctx gocontext.Context, f func(g *ResourceGroup) error) error {
 	candidates := append([]string{}, rg.LeafNodes...)
 	visited := utils.NewStringSet()
 	for len(candidates) > 0 {
@@ -219,6 +228,9 @@ func (rg *ResourceGraph) WalkBackward(f func(g *ResourceGroup) error) error {
 				}
 			}
 			if depVisited {
+				if ctx.Err() != nil {
+					return stacktrace.Propagate(ctx.Err(), "interrupted before group %q", current)
+				}
 				if f != nil {
 					err := f(rg.ResourceGroups[current])
 					if err != nil {
@@ -234,39 +246,51 @@ func (rg *ResourceGraph) WalkBackward(f func(g *ResourceGroup) error) error {
 }
 
 // WalkResourceForward with waiting
-func (rg *ResourceGraph) WalkResourceForward(f func(r *Resource, g *ResourceGroup) error, readyFunc func(r *Resource, g *ResourceGroup) error, waitFunc func(name, kind string) error) error {
-	return rg.WalkForwardWithWait(func(g *ResourceGroup) error {
-		for _, rf := range g.ResourceFiles {
-			for _, r := range rf.Resources {
-				if f == nil {
-					return nil
-				}
-				err := f(r, g)
-				if err != nil {
-					return err
-				}
-			}
-		}
-		return nil
+func (rg *ResourceGraph) WalkResourceForward(ctx gocontext.Context, f ResourceFunc, readyFunc ResourceFunc, waitFunc WaitFunc) error {
+	return rg.WalkForwardWithWait(ctx, func(ctx gocontext.Context, g *ResourceGroup) error {
+		return g.walkResources(ctx, f)
 	}, readyFunc, waitFunc)
 }
 
 // WalkResourceBackward with waiting
-func (rg *ResourceGraph) WalkResourceBackward(f func(r *Resource, g *ResourceGroup) error, readyFunc func(r *Resource, g *ResourceGroup) error) error {
-	return rg.WalkBackwardWithWait(func(g *ResourceGroup) error {
-		for _, rf := range g.ResourceFiles {
-			for _, r := range rf.Resources {
-				if f == nil {
-					return nil
-				}
-				err := f(r, g)
-				if err != nil {
-					return err
-				}
+func (rg *ResourceGraph) WalkResourceBackward(ctx gocontext.Context, f ResourceFunc, readyFunc ResourceFunc) error {
+	return rg.WalkBackwardWithWait(ctx, func(ctx gocontext.Context, g *ResourceGroup) error {
+		return g.walkResources(ctx, f)
+	}, readyFunc)
+}
+
+func (g *ResourceGroup) walkResources(ctx gocontext.Context, f ResourceFunc) error {
+	if f == nil {
+		return nil
+	}
+	for _, rf := range g.ResourceFiles {
+		for _, r := range rf.Resources {
+			if ctx.Err() != nil {
+				return stacktrace.Propagate(ctx.Err(), "interrupted before %s %q", r.Kind, r.Name)
+			}
+			err := f(ctx, r, g)
+			if err != nil {
+				return err
 			}
 		}
+	}
+	return nil
+}
+
+// waitReady waits for all resources of a group to be ready, only once per walk
+func (rg *ResourceGraph) waitReady(ctx gocontext.Context, readiness *readinessTracker, g *ResourceGroup, readyFunc ResourceFunc) error {
+	if readyFunc == nil {
 		return nil
-	}, readyFunc)
+	}
+	return readiness.check(g, func() error {
+		for _, r := range g.allResources() {
+			err := readyFunc(ctx, r, g)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (rg *ResourceGraph) resolveChildren() error {
@@ -330,26 +354,51 @@ func (rg *ResourceGraph) cyclicDFS(current string, white, gray, black utils.Stri
 	return nil
 }
 
-func (rg *ResourceGraph) waitFor(wait *WaitConfig, waitFunc func(name, kind string) error) error {
+func (rg *ResourceGraph) waitFor(ctx gocontext.Context, g *ResourceGroup, wait *WaitConfig, waitFunc WaitFunc) (err error) {
 	if waitFunc == nil {
 		return nil
 	}
-	waitChan := make(chan error, 1)
-	go func() {
-		err := waitFunc(wait.Name, wait.Kind)
-		waitChan <- err
+	rg.events.waitStarted(g.Name, wait.Kind, wait.Name, wait.For)
+	start := time.Now()
+	defer func() {
+		rg.events.waitFinished(g.Name, wait.Kind, wait.Name, wait.For, start, err)
 	}()
 	timeout := wait.Timeout
 	if timeout <= 0 {
 		timeout = defaultWaitTimeout
 	}
-	timer := time.NewTimer(time.Duration(timeout) * time.Second)
+	waitCtx, cancel := gocontext.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+	waitChan := make(chan error, 1)
+	go func() {
+		err := waitFunc(waitCtx, wait)
+		waitChan <- err
+	}()
 	select {
-	case err := <-waitChan:
-		return err
-	case <-timer.C:
-		return stacktrace.Propagate(ErrWaitTimeout{wait.Name, wait.Kind}, "wait timeout")
+	case err = <-waitChan:
+		// A wait which succeeded, or failed before its context was done, is not a timeout even when the deadline
+		// passed meanwhile
+		if err == nil || waitCtx.Err() == nil {
+			return err
+		}
+	case <-waitCtx.Done():
 	}
+	if ctx.Err() != nil {
+		return stacktrace.Propagate(ctx.Err(), "interrupted while waiting for %s %q", wait.Kind, wait.Name)
+	}
+	return stacktrace.Propagate(ErrWaitTimeout{wait.Name, wait.Kind}, "wait timeout")
+}
+
+func (g *ResourceGroup) batchResources() []*kubernetes.BatchResource {
+	resources := []*kubernetes.BatchResource{}
+	for _, r := range g.allResources() {
+		resources = append(resources, &kubernetes.BatchResource{
+			Name:       r.Name,
+			Kind:       r.Kind,
+			RawContent: r.RawContent,
+		})
+	}
+	return resources
 }
 
 func (g *ResourceGroup) allResources() []*Resource {
@@ -1,6 +1,11 @@
 package project
 
 import (
+	"bytes"
+	gocontext "context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -248,13 +253,13 @@ func (s *ResourceTestSuite) TestWalk() {
 	}
 	rg.resolveChildren()
 	trailForward := ""
-	rg.WalkForward(func(g *ResourceGroup) error {
+	rg.WalkForward(gocontext.Background(), func(g *ResourceGroup) error {
 		trailForward += g.Name
 		return nil
 	})
 	require.Equal(s.T(), "abcfedgh", trailForward)
 	trailBackward := ""
-	rg.WalkBackward(func(g *ResourceGroup) error {
+	rg.WalkBackward(gocontext.Background(), func(g *ResourceGroup) error {
 		trailBackward += g.Name
 		return nil
 	})
@@ -307,7 +312,7 @@ func (s *ResourceTestSuite) TestWalkWithWait() {
 		value: 0,
 		done:  false,
 	}
-	err = rg.WalkForwardWithWait(func(g *ResourceGroup) error {
+	err = rg.WalkForwardWithWait(gocontext.Background(), func(ctx gocontext.Context, g *ResourceGroup) error {
 		if g.Name == "a" {
 			go func() {
 				require.Equal(s.T(), 21, work.value)
@@ -319,14 +324,14 @@ func (s *ResourceTestSuite) TestWalkWithWait() {
 			require.Equal(s.T(), 42, work.value)
 		}
 		return nil
-	}, func(r *Resource, g *ResourceGroup) error {
+	}, func(ctx gocontext.Context, r *Resource, g *ResourceGroup) error {
 		if g.Name == "a" {
 			for !work.done {
 				time.Sleep(time.Second)
 			}
 		}
 		return nil
-	}, func(name, kind string) error {
+	}, func(ctx gocontext.Context, wait *WaitConfig) error {
 		work.value = 21
 		time.Sleep(2 * time.Second)
 		return nil
@@ -334,7 +339,7 @@ func (s *ResourceTestSuite) TestWalkWithWait() {
 	require.Nil(s.T(), err)
 	work.value = 0
 	work.done = false
-	err = rg.WalkBackwardWithWait(func(g *ResourceGroup) error {
+	err = rg.WalkBackwardWithWait(gocontext.Background(), func(ctx gocontext.Context, g *ResourceGroup) error {
 		if g.Name == "b" {
 			go func() {
 				time.Sleep(2 * time.Second)
@@ -345,7 +350,7 @@ func (s *ResourceTestSuite) TestWalkWithWait() {
 			require.Equal(s.T(), 42, work.value)
 		}
 		return nil
-	}, func(r *Resource, g *ResourceGroup) error {
+	}, func(ctx gocontext.Context, r *Resource, g *ResourceGroup) error {
 		if g.Name == "b" {
 			for !work.done {
 				time.Sleep(time.Second)
@@ -356,6 +361,136 @@ func (s *ResourceTestSuite) TestWalkWithWait() {
 	require.Nil(s.T(), err)
 }
 
+func (s *ResourceTestSuite) TestWalkCancel() {
+	rg := &ResourceGraph{
+		RootNodes: []string{"a"},
+		ResourceGroups: map[string]*ResourceGroup{
+			"a": {
+				Name: "a",
+				ResourceFiles: []*ResourceFile{
+					{
+						Resources: []*Resource{
+							{
+								Name: "Test",
+							},
+						},
+					},
+				},
+			},
+			"b": {
+				Name:   "b",
+				Depend: []string{"a"},
+				Wait: []*WaitConfig{
+					{
+						Name:    "test",
+						Kind:    "test",
+						Timeout: 1,
+					},
+				},
+			},
+		},
+	}
+	err := rg.resolveChildren()
+	require.Nil(s.T(), err)
+
+	waitDone := make(chan error, 1)
+	err = rg.WalkForwardWithWait(gocontext.Background(), nil, nil, func(ctx gocontext.Context, wait *WaitConfig) error {
+		<-ctx.Done()
+		waitDone <- ctx.Err()
+		return ctx.Err()
+	})
+	require.NotNil(s.T(), err)
+	_, ok := stacktrace.RootCause(err).(ErrWaitTimeout)
+	require.True(s.T(), ok, "wait should time out")
+	require.Equal(s.T(), gocontext.DeadlineExceeded, <-waitDone, "wait should be cancelled on timeout")
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	walked := []string{}
+	err = rg.WalkForward(ctx, func(g *ResourceGroup) error {
+		walked = append(walked, g.Name)
+		cancel()
+		return nil
+	})
+	require.NotNil(s.T(), err)
+	require.Equal(s.T(), gocontext.Canceled, stacktrace.RootCause(err))
+	require.Equal(s.T(), []string{"a"}, walked)
+}
+
+func (s *ResourceTestSuite) TestWalkConcurrently() {
+	rg := &ResourceGraph{
+		RootNodes: []string{"a"},
+		ResourceGroups: map[string]*ResourceGroup{
+			"a": {
+				Name: "a",
+			},
+			"b": {
+				Name:   "b",
+				Depend: []string{"a"},
+			},
+			"c": {
+				Name:   "c",
+				Depend: []string{"a"},
+			},
+			"d": {
+				Name:   "d",
+				Depend: []string{"b", "c"},
+			},
+		},
+		Parallelism: 2,
+	}
+	err := rg.resolveChildren()
+	require.Nil(s.T(), err)
+
+	mu := sync.Mutex{}
+	trail := []string{}
+	started := map[string]chan bool{"b": make(chan bool), "c": make(chan bool)}
+	err = rg.WalkForwardWithWait(gocontext.Background(), func(ctx gocontext.Context, g *ResourceGroup) error {
+		fmt.Fprintf(GroupOutput(ctx), "walking %s\n", g.Name)
+		if g.Name == "b" || g.Name == "c" {
+			// b and c only finish if they run at the same time
+			close(started[g.Name])
+			other := map[string]string{"b": "c", "c": "b"}[g.Name]
+			select {
+			case <-started[other]:
+			case <-time.After(5 * time.Second):
+				return fmt.Errorf("group %s did not run concurrently with %s", g.Name, other)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		trail = append(trail, g.Name)
+		return nil
+	}, nil, nil)
+	require.Nil(s.T(), err)
+	require.Len(s.T(), trail, 4)
+	require.Equal(s.T(), "a", trail[0])
+	require.Equal(s.T(), "d", trail[3])
+
+	trail = []string{}
+	err = rg.WalkForwardWithWait(gocontext.Background(), func(ctx gocontext.Context, g *ResourceGroup) error {
+		mu.Lock()
+		trail = append(trail, g.Name)
+		mu.Unlock()
+		switch g.Name {
+		case "b":
+			return errors.New("b failed")
+		case "c":
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}, nil, nil)
+	require.NotNil(s.T(), err)
+	require.Equal(s.T(), "b failed", err.Error())
+	require.ElementsMatch(s.T(), []string{"a", "b", "c"}, trail)
+}
+
+func (s *ResourceTestSuite) TestPrintGroupOutput() {
+	buf := &bytes.Buffer{}
+	writeGroupOutput(buf, "group", bytes.NewBufferString("line1\nline2"))
+	require.Equal(s.T(), "[group] line1\n[group] line2\n", buf.String())
+}
+
 func TestResource(t *testing.T) {
 	suite.Run(t, new(ResourceTestSuite))
 }
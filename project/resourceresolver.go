@@ -163,7 +163,7 @@ func splitResourceContent() ResourceFileProcessorFunc {
 			parsedResource := &resourceYAML{}
 			err := yaml.Unmarshal([]byte(part), parsedResource)
 			if err != nil {
-				return stacktrace.Propagate(err, "Cannot parse yaml file %q. Content: %s", rf.Source, part)
+				return stacktrace.Propagate(ErrParse{rf.Source, err}, "Cannot parse yaml file %q. Content: %s", rf.Source, part)
 			}
 			resource := &Resource{
 				Name:       parsedResource.Metadata.Name,
@@ -0,0 +1,130 @@
+package project
+
+import (
+	gocontext "context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+)
+
+// ForRelease returns a copy of the project rendering the manifests of a previous release instead of its resource files
+func (p *Project) ForRelease(ctx gocontext.Context, version int) (*Project, error) {
+	release, err := p.Release(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	resourceGraph, err := releaseGraph(release)
+	if err != nil {
+		return nil, err
+	}
+	rolled := *p
+	resourceGraph.Parallelism = p.resourceGraph.Parallelism
+	resourceGraph.KeepGoing = p.resourceGraph.KeepGoing
+	resourceGraph.events = p.events
+	resourceGraph.out = p.resourceGraph.out
+	rolled.resourceGraph = resourceGraph
+	// a partial release does not tell which resources were removed, like a filtered project
+	rolled.filtered = release.Partial
+	rolled.release = release
+	return &rolled, nil
+}
+
+// Rollback re-applies the manifests of the release returned by ForRelease, group by group with waits,
+// and records them as a new release
+func (p *Project) Rollback(ctx gocontext.Context) (err error) {
+	finish := p.events.startOperation("rollback")
+	defer func() {
+		finish(err)
+	}()
+	if p.release == nil {
+		return stacktrace.NewError("project %q does not render a release", p.name)
+	}
+	kubeContext, err := p.newKubeContext(ctx)
+	if err != nil {
+		return err
+	}
+	progress := newProgress()
+	err = p.walkApply(ctx, progress, func(ctx gocontext.Context, r *Resource, g *ResourceGroup) error {
+		return p.upgradeResource(groupKubeContext(ctx, kubeContext), progress, g, r)
+	}, func(ctx gocontext.Context, g *ResourceGroup) error {
+		return p.upgradeGroup(groupKubeContext(ctx, kubeContext), progress, g)
+	}, nil, func(ctx gocontext.Context, wait *WaitConfig) error {
+		return p.waitForResource(groupKubeContext(ctx, kubeContext), wait)
+	})
+	p.reportFailure(ctx, kubeContext, err, progress, false)
+	err = p.saveInventory(kubeContext, progress, err)
+	if err != nil {
+		return err
+	}
+	return p.recordRelease(kubeContext, fmt.Sprintf("rollback to %d", p.release.Version))
+}
+
+// PrintRollbackPlan .
+func (p *Project) PrintRollbackPlan(out io.Writer, plan *Plan) {
+	if p.release != nil {
+		utils.Warnf(out, "Rolling back to release %d, deployed at %s by %s", p.release.Version, p.release.Timestamp.Local().Format("2006-01-02 15:04:05"), p.release.User)
+	}
+	p.PrintPlan(out, plan)
+}
+
+// releaseGraph rebuilds the resource graph of a release, every manifest being a resource file of its group
+func releaseGraph(release *Release) (*ResourceGraph, error) {
+	rg := &ResourceGraph{
+		ResourceGroups: make(map[string]*ResourceGroup),
+		RootNodes:      []string{},
+		LeafNodes:      []string{},
+	}
+	for _, group := range release.Groups {
+		g := &ResourceGroup{
+			Name:     group.Name,
+			Depend:   utils.NilArrayToEmpty(group.Depend),
+			Wait:     group.Wait,
+			Children: []string{},
+		}
+		if g.Wait == nil {
+			g.Wait = []*WaitConfig{}
+		}
+		rg.ResourceGroups[g.Name] = g
+	}
+	for _, manifest := range release.Manifests {
+		g, ok := rg.ResourceGroups[manifest.Group]
+		if !ok {
+			g = &ResourceGroup{
+				Name:     manifest.Group,
+				Depend:   []string{},
+				Wait:     []*WaitConfig{},
+				Children: []string{},
+			}
+			rg.ResourceGroups[g.Name] = g
+		}
+		g.ResourceFiles = append(g.ResourceFiles, &ResourceFile{
+			Source:     fmt.Sprintf("release %d", release.Version),
+			RawContent: manifest.Content,
+			Resources: []*Resource{
+				{
+					Name:       manifest.Name,
+					Kind:       manifest.Kind,
+					RawContent: manifest.Content,
+				},
+			},
+		})
+	}
+	for name, g := range rg.ResourceGroups {
+		if len(g.Depend) == 0 {
+			rg.RootNodes = append(rg.RootNodes, name)
+		}
+	}
+	sort.Strings(rg.RootNodes)
+	err := rg.resolveChildren()
+	if err != nil {
+		return nil, err
+	}
+	err = rg.cyclicCheck()
+	if err != nil {
+		return nil, err
+	}
+	return rg, nil
+}
@@ -0,0 +1,81 @@
+package project
+
+import (
+	gocontext "context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type RollbackTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *RollbackTestSuite) TestRollback() {
+	projectFile := filepath.Join(s.resourceRoot, "command-test", "rollback", "project-reduced.yml")
+	first, err := ReadProject(projectFile, s.testNamespace, "", "", nil, []string{}, nil, nil)
+	require.Nil(s.T(), err)
+	err = first.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+	p := s.readProject("rollback", map[string]string{"greeting": "hi"})
+	err = p.Upgrade(gocontext.Background())
+	require.Nil(s.T(), err)
+
+	rolled, err := p.ForRelease(gocontext.Background(), 1)
+	require.Nil(s.T(), err)
+	candidates, err := rolled.PrunePlan(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []*InventoryEntry{{Kind: "ConfigMap", Name: "extra", Group: "extras"}}, candidates)
+	err = rolled.Rollback(gocontext.Background())
+	require.Nil(s.T(), err)
+	err = rolled.Prune(gocontext.Background(), candidates)
+	require.Nil(s.T(), err)
+
+	kubeContext := s.kubeContext(p)
+	manifest, err := kubeContext.Resource().Manifest("greeting", "configmap")
+	require.Nil(s.T(), err)
+	require.Contains(s.T(), string(manifest), `"greeting":"hello"`)
+	require.False(s.T(), s.exists(kubeContext, "extra", "configmap"))
+	releases, err := p.History(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Len(s.T(), releases, 3)
+	require.Equal(s.T(), "rollback to 1", releases[0].Operation)
+	require.Equal(s.T(), releases[2].ProjectFileHash, releases[0].ProjectFileHash)
+	require.Equal(s.T(), "hello", releases[0].Variables["greeting"])
+
+	_, err = p.ForRelease(gocontext.Background(), 42)
+	require.NotNil(s.T(), err)
+	err = p.Rollback(gocontext.Background())
+	require.NotNil(s.T(), err, "only a project rendering a release can be rolled back")
+	s.down(p)
+}
+
+func (s *RollbackTestSuite) TestRollbackToPartialRelease() {
+	projectFile := filepath.Join(s.resourceRoot, "command-test", "rollback", "project.yml")
+	first, err := ReadProject(projectFile, s.testNamespace, "", "", nil, []string{}, nil, []string{"**/extra.yml"})
+	require.Nil(s.T(), err)
+	err = first.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+	p := s.up("rollback", map[string]string{"greeting": "hi"})
+
+	rolled, err := p.ForRelease(gocontext.Background(), 1)
+	require.Nil(s.T(), err)
+	_, err = rolled.PrunePlan(gocontext.Background())
+	require.NotNil(s.T(), err, "resources left out of a partial release are not removed ones")
+	err = rolled.Rollback(gocontext.Background())
+	require.Nil(s.T(), err)
+
+	kubeContext := s.kubeContext(p)
+	require.True(s.T(), s.exists(kubeContext, "extra", "configmap"))
+	releases, err := p.History(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), "rollback to 1", releases[0].Operation)
+	require.True(s.T(), releases[0].Partial)
+	s.down(p)
+}
+
+func TestRollback(t *testing.T) {
+	suite.Run(t, new(RollbackTestSuite))
+}
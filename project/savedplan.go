@@ -0,0 +1,189 @@
+package project
+
+import (
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+)
+
+const savedPlanVersion = 1
+
+// SavedPlan is a fully rendered project written by the plan command, to be applied later without rendering it again
+type SavedPlan struct {
+	Version   int    `json:"version"`
+	Operation string `json:"operation"`
+	Namespace string `json:"namespace"`
+	// Context is the name of the kubernetes context the plan was made for, resolved from the kubernetes config
+	Context string `json:"context"`
+	// Config holds the settings of the project file, without its variables
+	Config *Config `json:"config"`
+	// Release holds the rendered resource groups and manifests
+	Release *Release `json:"release"`
+	// Plan is what the operation would have done when the plan was made
+	Plan *Plan `json:"plan"`
+	// Checksum is the sha256 of the saved plan without its checksum
+	Checksum string `json:"checksum"`
+}
+
+// SavePlan renders the project and its plan for an operation into a file
+func (p *Project) SavePlan(filename string, operation string, plan *Plan) (*SavedPlan, error) {
+	if !isApplyOperation(operation) {
+		return nil, stacktrace.NewError("cannot save a plan for %q, only up, update and upgrade plans can be applied", operation)
+	}
+	contextName, err := kubernetes.ResolveContextName(p.context, p.kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	release, err := p.newRelease(operation)
+	if err != nil {
+		return nil, err
+	}
+	config := *p.config
+	config.Variables = nil
+	saved := &SavedPlan{
+		Version:   savedPlanVersion,
+		Operation: operation,
+		Namespace: p.namespace,
+		Context:   contextName,
+		Config:    &config,
+		Release:   release,
+		Plan:      plan,
+	}
+	saved.Checksum, err = saved.checksum()
+	if err != nil {
+		return nil, err
+	}
+	content, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot encode plan")
+	}
+	err = writePrivateFile(filename, content)
+	if err != nil {
+		return nil, stacktrace.Propagate(err, "cannot write plan to %q", filename)
+	}
+	return saved, nil
+}
+
+// writePrivateFile writes a file readable by its owner only, even when it already existed with a wider mode,
+// as rendered manifests hold secrets
+func writePrivateFile(filename string, content []byte) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	err = f.Chmod(0600)
+	if err == nil {
+		_, err = f.Write(content)
+	}
+	closeErr := f.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// ReadSavedPlan reads a plan written by SavePlan and returns the project it renders. The namespace and context, when
+// set, must be the ones the plan was made for.
+func ReadSavedPlan(filename, namespace, context, kubeConfig string) (*Project, *SavedPlan, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, nil, stacktrace.Propagate(err, "cannot read plan %q", filename)
+	}
+	saved := &SavedPlan{}
+	err = json.Unmarshal(content, saved)
+	if err != nil {
+		return nil, nil, stacktrace.Propagate(err, "cannot decode plan %q", filename)
+	}
+	if saved.Version != savedPlanVersion {
+		return nil, nil, stacktrace.NewError("unsupported plan version %d", saved.Version)
+	}
+	if saved.Release == nil || saved.Config == nil || !isApplyOperation(saved.Operation) {
+		return nil, nil, stacktrace.NewError("plan %q is incomplete", filename)
+	}
+	checksum, err := saved.checksum()
+	if err != nil {
+		return nil, nil, err
+	}
+	if checksum != saved.Checksum {
+		return nil, nil, stacktrace.NewError("plan %q was modified after it was saved", filename)
+	}
+	if namespace != "" && namespace != saved.Namespace {
+		return nil, nil, stacktrace.NewError("plan was made for namespace %q, not %q", saved.Namespace, namespace)
+	}
+	contextName, err := kubernetes.ResolveContextName(context, kubeConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if contextName != saved.Context {
+		return nil, nil, stacktrace.NewError("plan was made for context %q, not %q", saved.Context, contextName)
+	}
+	p := &Project{
+		name:       saved.Release.Project,
+		identity:   saved.Release.Identity,
+		deployID:   saved.Release.DeployID,
+		namespace:  saved.Namespace,
+		context:    context,
+		kubeConfig: kubeConfig,
+		variables:  saved.Release.Variables,
+		config:     saved.Config,
+		release:    saved.Release,
+		filtered:   saved.Release.Partial,
+	}
+	if p.identity == "" {
+		// plans saved before projects had an identity were keyed by their name
+		p.identity = p.name
+	}
+	p.deleteNamespaceConfig = saved.Config.DeleteNamespace
+	p.resourceGraph, err = releaseGraph(saved.Release)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, saved, nil
+}
+
+// Apply runs an operation on the project, see Up, Update and Upgrade
+func (p *Project) Apply(ctx gocontext.Context, operation string) error {
+	switch operation {
+	case OperationUp:
+		return p.Up(ctx)
+	case OperationUpdate:
+		return p.Update(ctx)
+	case OperationUpgrade:
+		return p.Upgrade(ctx)
+	default:
+		return stacktrace.NewError("cannot apply operation %q", operation)
+	}
+}
+
+// PrintSavedPlan .
+func (p *Project) PrintSavedPlan(out io.Writer, saved *SavedPlan) {
+	utils.Infof(out, "Plan for %s of project %q saved by %s at %s", saved.Operation, saved.Release.Project, saved.Release.User, saved.Release.Timestamp.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(out, "Namespace: %s\n", saved.Namespace)
+	fmt.Fprintf(out, "Context:   %s\n", saved.Context)
+	fmt.Fprintf(out, "Deploy ID: %s\n", saved.Release.DeployID)
+	fmt.Fprintf(out, "Checksum:  %s\n", saved.Checksum)
+}
+
+func (saved *SavedPlan) checksum() (string, error) {
+	unsigned := *saved
+	unsigned.Checksum = ""
+	content, err := json.Marshal(&unsigned)
+	if err != nil {
+		return "", stacktrace.Propagate(err, "cannot encode plan")
+	}
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func isApplyOperation(operation string) bool {
+	return operation == OperationUp || operation == OperationUpdate || operation == OperationUpgrade
+}
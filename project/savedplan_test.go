@@ -0,0 +1,109 @@
+package project
+
+import (
+	gocontext "context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type SavedPlanTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *SavedPlanTestSuite) TestSavedPlan() {
+	dir, err := ioutil.TempDir("", "rivendell-plan")
+	require.Nil(s.T(), err)
+	defer os.RemoveAll(dir)
+	planFile := filepath.Join(dir, "plan.out")
+	p := s.readProject("rollback", map[string]string{"greeting": "planned"})
+	plan, err := p.Plan(gocontext.Background(), OperationUp, false)
+	require.Nil(s.T(), err)
+	saved, err := p.SavePlan(planFile, OperationUp, plan)
+	require.Nil(s.T(), err)
+	require.NotEmpty(s.T(), saved.Checksum)
+	info, err := os.Stat(planFile)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), os.FileMode(0600), info.Mode().Perm(), "a plan holds secrets")
+	err = os.Chmod(planFile, 0644)
+	require.Nil(s.T(), err)
+	_, err = p.SavePlan(planFile, OperationUp, plan)
+	require.Nil(s.T(), err)
+	info, err = os.Stat(planFile)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), os.FileMode(0600), info.Mode().Perm(), "an existing plan file is made private")
+	_, err = p.SavePlan(planFile, OperationDown, plan)
+	require.NotNil(s.T(), err, "only applying operations can be saved")
+
+	_, _, err = ReadSavedPlan(planFile, "other-ns", "", "")
+	require.NotNil(s.T(), err, "a plan is refused in another namespace")
+	_, _, err = ReadSavedPlan(planFile, s.testNamespace, "other-context", "")
+	require.NotNil(s.T(), err, "a plan is refused in another context")
+	applied, saved, err := ReadSavedPlan(planFile, "", "", "")
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), OperationUp, saved.Operation)
+	require.Equal(s.T(), s.testNamespace, applied.namespace)
+	require.Equal(s.T(), p.inventoryName(), applied.inventoryName(), "a saved plan keeps the inventory of its project")
+	err = applied.Apply(gocontext.Background(), saved.Operation)
+	require.Nil(s.T(), err)
+
+	kubeContext := s.kubeContext(p)
+	manifest, err := kubeContext.Resource().Manifest("greeting", "configmap")
+	require.Nil(s.T(), err)
+	require.Contains(s.T(), string(manifest), `"greeting":"planned"`)
+	releases, err := p.History(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Len(s.T(), releases, 1)
+	require.Equal(s.T(), saved.Release.ProjectFileHash, releases[0].ProjectFileHash)
+	require.Equal(s.T(), saved.Release.DeployID, releases[0].DeployID)
+
+	content, err := ioutil.ReadFile(planFile)
+	require.Nil(s.T(), err)
+	err = ioutil.WriteFile(planFile, []byte(strings.Replace(string(content), "planned", "tampered", -1)), 0644)
+	require.Nil(s.T(), err)
+	_, _, err = ReadSavedPlan(planFile, "", "", "")
+	require.NotNil(s.T(), err, "a modified plan is refused")
+	s.down(p)
+}
+
+func (s *SavedPlanTestSuite) TestFilteredPlan() {
+	dir, err := ioutil.TempDir("", "rivendell-plan")
+	require.Nil(s.T(), err)
+	defer os.RemoveAll(dir)
+	planFile := filepath.Join(dir, "plan.out")
+	p := s.up("rollback", nil)
+	projectFile := filepath.Join(s.resourceRoot, "command-test", "rollback", "project.yml")
+	filtered, err := ReadProject(projectFile, s.testNamespace, "", "", map[string]string{"greeting": "planned"}, []string{}, nil, []string{"**/extra.yml"})
+	require.Nil(s.T(), err)
+	plan, err := filtered.Plan(gocontext.Background(), OperationUpgrade, false)
+	require.Nil(s.T(), err)
+	_, err = filtered.SavePlan(planFile, OperationUpgrade, plan)
+	require.Nil(s.T(), err)
+	applied, saved, err := ReadSavedPlan(planFile, "", "", "")
+	require.Nil(s.T(), err)
+	err = applied.Apply(gocontext.Background(), saved.Operation)
+	require.Nil(s.T(), err)
+	releases, err := p.History(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Len(s.T(), releases, 2)
+	require.True(s.T(), releases[0].Partial, "a plan saved with include or exclude patterns is a partial release")
+
+	rolled, err := p.ForRelease(gocontext.Background(), releases[0].Version)
+	require.Nil(s.T(), err)
+	_, err = rolled.PrunePlan(gocontext.Background())
+	require.NotNil(s.T(), err, "resources left out of a filtered plan are not removed ones")
+	err = rolled.Rollback(gocontext.Background())
+	require.Nil(s.T(), err)
+	kubeContext := s.kubeContext(p)
+	require.True(s.T(), s.exists(kubeContext, "extra", "configmap"))
+	s.down(p)
+}
+
+func TestSavedPlan(t *testing.T) {
+	suite.Run(t, new(SavedPlanTestSuite))
+}
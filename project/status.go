@@ -1,6 +1,17 @@
 package project
 
-import "github.com/anduintransaction/rivendell/kubernetes"
+import (
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/palantir/stacktrace"
+	yaml "gopkg.in/yaml.v2"
+)
 
 func Status(namespace, context, kubeConfig, kind, name string) (kubernetes.RsStatus, error) {
 	kubeContext, err := kubernetes.NewContext(namespace, context, kubeConfig)
@@ -9,3 +20,148 @@ func Status(namespace, context, kubeConfig, kind, name string) (kubernetes.RsSta
 	}
 	return kubeContext.Resource().GetStatus(name, kind)
 }
+
+// StatusReport is the live status of every resource of a project, group by group in the order of the graph
+type StatusReport struct {
+	Project   string         `json:"project" yaml:"project"`
+	Namespace string         `json:"namespace" yaml:"namespace"`
+	Groups    []*GroupStatus `json:"groups" yaml:"groups"`
+}
+
+// GroupStatus .
+type GroupStatus struct {
+	Name      string            `json:"name" yaml:"name"`
+	Resources []*ResourceStatus `json:"resources" yaml:"resources"`
+}
+
+// ResourceStatus is the live status of a resource. Ready, desired and restarts are only set for workloads, jobs and pods.
+type ResourceStatus struct {
+	Kind     string     `json:"kind" yaml:"kind"`
+	Name     string     `json:"name" yaml:"name"`
+	Status   string     `json:"status" yaml:"status"`
+	Ready    *int32     `json:"ready,omitempty" yaml:"ready,omitempty"`
+	Desired  *int32     `json:"desired,omitempty" yaml:"desired,omitempty"`
+	Restarts *int       `json:"restarts,omitempty" yaml:"restarts,omitempty"`
+	Created  *time.Time `json:"created,omitempty" yaml:"created,omitempty"`
+	Age      string     `json:"age,omitempty" yaml:"age,omitempty"`
+	Message  string     `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// Status queries the cluster for the status of every resource of the project
+func (p *Project) Status(ctx gocontext.Context) (*StatusReport, error) {
+	kubeContext, err := p.newKubeContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &StatusReport{
+		Project:   p.name,
+		Namespace: p.namespace,
+		Groups:    []*GroupStatus{},
+	}
+	now := time.Now()
+	err = p.resourceGraph.WalkForward(ctx, func(g *ResourceGroup) error {
+		groupStatus := &GroupStatus{Name: g.Name, Resources: []*ResourceStatus{}}
+		for _, r := range g.allResources() {
+			details, err := kubeContext.Resource().Details(r.Name, r.Kind)
+			if err != nil {
+				return err
+			}
+			resourceStatus := &ResourceStatus{
+				Kind:    r.Kind,
+				Name:    r.Name,
+				Status:  details.Status.String(),
+				Message: details.Message,
+			}
+			if details.Status == kubernetes.RsStatusNotExist {
+				resourceStatus.Message = ""
+			}
+			if details.Replicas {
+				resourceStatus.Ready = &details.Ready
+				resourceStatus.Desired = &details.Desired
+				resourceStatus.Restarts = &details.Restarts
+			}
+			if !details.Created.IsZero() {
+				resourceStatus.Created = &details.Created
+				resourceStatus.Age = formatAge(now.Sub(details.Created))
+			}
+			groupStatus.Resources = append(groupStatus.Resources, resourceStatus)
+		}
+		report.Groups = append(report.Groups, groupStatus)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Ready tells if every resource of the project is active or succeeded
+func (report *StatusReport) Ready() bool {
+	for _, groupStatus := range report.Groups {
+		for _, resourceStatus := range groupStatus.Resources {
+			if resourceStatus.Status != kubernetes.RsStatusActive.String() && resourceStatus.Status != kubernetes.RsStatusSucceeded.String() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// PrintStatusReport prints a report as a table, as json or as yaml
+func (p *Project) PrintStatusReport(out io.Writer, report *StatusReport, format string) error {
+	switch format {
+	case "json":
+		content, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return stacktrace.Propagate(err, "cannot encode status report")
+		}
+		fmt.Fprintln(out, string(content))
+		return nil
+	case "yaml":
+		content, err := yaml.Marshal(report)
+		if err != nil {
+			return stacktrace.Propagate(err, "cannot encode status report")
+		}
+		fmt.Fprint(out, string(content))
+		return nil
+	case "table", "":
+		fmt.Fprintf(out, "Project %q in namespace %q\n\n", report.Project, report.Namespace)
+		w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "GROUP\tKIND\tNAME\tSTATUS\tREADY\tRESTARTS\tAGE")
+		for _, groupStatus := range report.Groups {
+			for _, resourceStatus := range groupStatus.Resources {
+				ready, restarts, age := "-", "-", "-"
+				if resourceStatus.Ready != nil && resourceStatus.Desired != nil {
+					ready = fmt.Sprintf("%d/%d", *resourceStatus.Ready, *resourceStatus.Desired)
+				}
+				if resourceStatus.Restarts != nil {
+					restarts = fmt.Sprintf("%d", *resourceStatus.Restarts)
+				}
+				if resourceStatus.Age != "" {
+					age = resourceStatus.Age
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", groupStatus.Name, resourceStatus.Kind, resourceStatus.Name, resourceStatus.Status, ready, restarts, age)
+			}
+		}
+		w.Flush()
+		return nil
+	default:
+		return stacktrace.NewError("unknown output format %q, one of: table|json|yaml", format)
+	}
+}
+
+// formatAge formats a duration like kubectl does for ages: 45s, 12m, 5h or 3d
+func formatAge(d time.Duration) string {
+	switch {
+	case d < 0:
+		return "0s"
+	case d < 2*time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < 2*time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,52 @@
+package project
+
+import (
+	gocontext "context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type StatusTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *StatusTestSuite) TestStatus() {
+	p := s.readProject("prune", nil)
+	report, err := p.Status(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.False(s.T(), report.Ready())
+	err = p.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+	report, err = p.Status(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.True(s.T(), report.Ready())
+	require.Equal(s.T(), "fake-ns", report.Namespace)
+	resources := make(map[string]*ResourceStatus)
+	for _, groupStatus := range report.Groups {
+		for _, resourceStatus := range groupStatus.Resources {
+			resources[groupStatus.Name+"/"+resourceStatus.Kind+"/"+resourceStatus.Name] = resourceStatus
+		}
+	}
+	deployment, ok := resources["nginx/Deployment/nginx"]
+	require.True(s.T(), ok)
+	require.NotNil(s.T(), deployment.Ready)
+	require.Equal(s.T(), *deployment.Desired, *deployment.Ready)
+	require.NotNil(s.T(), deployment.Restarts)
+	require.NotEmpty(s.T(), deployment.Age)
+	configMap, ok := resources["configs/ConfigMap/nginx-conf"]
+	require.True(s.T(), ok)
+	require.Nil(s.T(), configMap.Ready)
+	require.Equal(s.T(), "Active", configMap.Status)
+	require.Nil(s.T(), p.PrintStatusReport(ioutil.Discard, report, "table"))
+	require.Nil(s.T(), p.PrintStatusReport(ioutil.Discard, report, "json"))
+	require.Nil(s.T(), p.PrintStatusReport(ioutil.Discard, report, "yaml"))
+	require.NotNil(s.T(), p.PrintStatusReport(ioutil.Discard, report, "xml"))
+	s.down(p)
+}
+
+func TestStatus(t *testing.T) {
+	suite.Run(t, new(StatusTestSuite))
+}
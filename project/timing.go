@@ -0,0 +1,283 @@
+package project
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+)
+
+// TimingReport records how long the groups, resources and waits of the operations of a project took
+type TimingReport struct {
+	mu         sync.Mutex
+	project    string
+	graph      *ResourceGraph
+	Operations []*StepTiming
+	Groups     []*StepTiming
+	Resources  []*StepTiming
+	Waits      []*StepTiming
+}
+
+// StepTiming is the duration and result of a step. Result is the last part of the type of its event, like
+// created or timed-out, and ok or failed for groups and operations.
+type StepTiming struct {
+	Operation string
+	Group     string
+	Kind      string
+	Name      string
+	For       string
+	Result    string
+	Finished  time.Time
+	Duration  time.Duration
+	Error     string
+}
+
+// RecordTimings returns a report filled by the next operations of the project
+func (p *Project) RecordTimings() *TimingReport {
+	report := &TimingReport{project: p.name, graph: p.resourceGraph}
+	p.addEventSink(report.record)
+	return report
+}
+
+func (report *TimingReport) record(event *Event) {
+	step := &StepTiming{
+		Operation: event.Operation,
+		Group:     event.Group,
+		Kind:      event.Kind,
+		Name:      event.Name,
+		For:       event.For,
+		Result:    event.Type.result(),
+		Finished:  event.Time,
+		Duration:  time.Duration(event.DurationMs) * time.Millisecond,
+		Error:     event.Error,
+	}
+	if event.Type == EventOperationFinished || event.Type == EventGroupFinished {
+		step.Result = "ok"
+		if event.Error != "" {
+			step.Result = "failed"
+		}
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	switch {
+	case event.Type == EventOperationFinished:
+		report.Operations = append(report.Operations, step)
+	case event.Type == EventGroupFinished:
+		report.Groups = append(report.Groups, step)
+	case event.Type == EventWaitStarted:
+	case strings.HasPrefix(string(event.Type), "wait."):
+		report.Waits = append(report.Waits, step)
+	case strings.HasPrefix(string(event.Type), "resource."):
+		report.Resources = append(report.Resources, step)
+	}
+}
+
+func (eventType EventType) result() string {
+	parts := strings.SplitN(string(eventType), ".", 2)
+	return parts[len(parts)-1]
+}
+
+// CriticalPath returns the chain of groups which decided when the last group finished: starting from the last
+// group, each group is preceded by the group it waited for which finished last. Down walks the groups backward,
+// waiting for the groups depending on them.
+func (report *TimingReport) CriticalPath() []*StepTiming {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	groups := make(map[string]*StepTiming)
+	var last *StepTiming
+	for _, group := range report.Groups {
+		groups[group.Group] = group
+		if last == nil || group.Finished.After(last.Finished) {
+			last = group
+		}
+	}
+	path := []*StepTiming{}
+	for current := last; current != nil; {
+		path = append([]*StepTiming{current}, path...)
+		g, ok := report.graph.ResourceGroups[current.Group]
+		if !ok {
+			break
+		}
+		var previous *StepTiming
+		for _, name := range g.dependencies(current.Operation == OperationDown) {
+			if group, ok := groups[name]; ok && (previous == nil || group.Finished.After(previous.Finished)) {
+				previous = group
+			}
+		}
+		current = previous
+	}
+	return path
+}
+
+// FailedResource returns the resource or the wait which failed last, nil if none did
+func (report *TimingReport) FailedResource() *ErrorResource {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	var failed *StepTiming
+	for _, steps := range [][]*StepTiming{report.Resources, report.Waits} {
+		for _, step := range steps {
+			if step.Error != "" && (failed == nil || step.Finished.After(failed.Finished)) {
+				failed = step
+			}
+		}
+	}
+	if failed == nil {
+		return nil
+	}
+	return &ErrorResource{Group: failed.Group, Kind: failed.Kind, Name: failed.Name}
+}
+
+// PrintTimingReport prints the duration of every group, resource and wait, marking the groups of the critical path
+func (p *Project) PrintTimingReport(out io.Writer, report *TimingReport) {
+	report.mu.Lock()
+	operations := report.Operations
+	groups := report.Groups
+	resources := report.Resources
+	waits := report.Waits
+	report.mu.Unlock()
+	if len(operations) == 0 {
+		return
+	}
+	names := []string{}
+	var total time.Duration
+	for _, operation := range operations {
+		names = append(names, operation.Operation)
+		total += operation.Duration
+	}
+	utils.Infof(out, "Timing of %s: %s", strings.Join(names, ", "), total)
+	critical := make(map[*StepTiming]bool)
+	path := report.CriticalPath()
+	for _, group := range path {
+		critical[group] = true
+	}
+	if len(groups) > 0 {
+		w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "GROUP\tRESULT\tDURATION\tCRITICAL PATH")
+		for _, group := range groups {
+			marker := ""
+			if critical[group] {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", group.Group, group.Result, group.Duration, marker)
+		}
+		w.Flush()
+		names := []string{}
+		var pathDuration time.Duration
+		for _, group := range path {
+			names = append(names, group.Group)
+			pathDuration += group.Duration
+		}
+		utils.Warnf(out, "Critical path: %s (%s)", strings.Join(names, " -> "), pathDuration)
+	}
+	if len(resources) > 0 {
+		fmt.Fprintln(out)
+		w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "GROUP\tKIND\tNAME\tRESULT\tDURATION")
+		for _, resource := range resources {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", resource.Group, resource.Kind, resource.Name, resource.Result, resource.Duration)
+		}
+		w.Flush()
+	}
+	if len(waits) > 0 {
+		fmt.Fprintln(out)
+		w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, "GROUP\tWAIT\tFOR\tRESULT\tDURATION")
+		for _, wait := range waits {
+			fmt.Fprintf(w, "%s\t%s/%s\t%s\t%s\t%s\n", wait.Group, wait.Kind, wait.Name, wait.For, wait.Result, wait.Duration)
+		}
+		w.Flush()
+	}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Time     float64           `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Time      float64          `xml:"time,attr"`
+	Timestamp string           `xml:"timestamp,attr"`
+	Cases     []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as a JUnit XML file: a test suite per operation, with a test case per group and wait
+func (report *TimingReport) WriteJUnit(filename string) error {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	suites := &junitTestSuites{Name: report.project}
+	for _, operation := range report.Operations {
+		suite := &junitTestSuite{
+			Name:      fmt.Sprintf("%s %s", report.project, operation.Operation),
+			Time:      operation.Duration.Seconds(),
+			Timestamp: operation.Finished.Add(-operation.Duration).Format("2006-01-02T15:04:05"),
+		}
+		for _, group := range report.Groups {
+			if group.Operation == operation.Operation {
+				suite.Cases = append(suite.Cases, junitCase(report.project+".group", group.Group, group))
+			}
+		}
+		for _, wait := range report.Waits {
+			if wait.Operation == operation.Operation {
+				name := fmt.Sprintf("%s: %s/%s", wait.Group, wait.Kind, wait.Name)
+				if wait.For != "" {
+					name += fmt.Sprintf(" (%s)", wait.For)
+				}
+				suite.Cases = append(suite.Cases, junitCase(report.project+".wait", name, wait))
+			}
+		}
+		for _, testCase := range suite.Cases {
+			suite.Tests++
+			if testCase.Failure != nil {
+				suite.Failures++
+			}
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Time += suite.Time
+		suites.Suites = append(suites.Suites, suite)
+	}
+	content, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return stacktrace.Propagate(err, "cannot encode junit report")
+	}
+	err = ioutil.WriteFile(filename, append([]byte(xml.Header), append(content, '\n')...), 0644)
+	if err != nil {
+		return stacktrace.Propagate(err, "cannot write junit report to %q", filename)
+	}
+	return nil
+}
+
+func junitCase(className, name string, step *StepTiming) *junitTestCase {
+	testCase := &junitTestCase{ClassName: className, Name: name, Time: step.Duration.Seconds()}
+	if step.Error != "" {
+		testCase.Failure = &junitFailure{Message: step.Error, Type: step.Result, Text: step.Error}
+	}
+	return testCase
+}
@@ -0,0 +1,60 @@
+package project
+
+import (
+	"bytes"
+	gocontext "context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TimingTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *TimingTestSuite) TestTimingReport() {
+	p := s.readProject("wait-pod", nil)
+	timings := p.RecordTimings()
+	err := p.Up(gocontext.Background())
+	require.Nil(s.T(), err)
+	require.Len(s.T(), timings.Operations, 1)
+	require.Len(s.T(), timings.Groups, 3)
+	require.NotEmpty(s.T(), timings.Resources)
+	path := []string{}
+	for _, group := range timings.CriticalPath() {
+		path = append(path, group.Group)
+	}
+	require.Equal(s.T(), []string{"jobs1", "services", "jobs2"}, path)
+	out := &bytes.Buffer{}
+	p.PrintTimingReport(out, timings)
+	require.Contains(s.T(), out.String(), "Critical path: jobs1 -> services -> jobs2")
+	require.Contains(s.T(), out.String(), "job/job1")
+	s.down(p)
+
+	s.cluster.SetOutcome(s.testNamespace, "pod", "pod1", kubernetes.FakeOutcomeFailed)
+	p = s.readProject("pod-wait-failed-in-project", nil)
+	timings = p.RecordTimings()
+	err = p.Up(gocontext.Background())
+	require.NotNil(s.T(), err)
+	dir, err := ioutil.TempDir("", "rivendell-junit")
+	require.Nil(s.T(), err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "report.xml")
+	err = timings.WriteJUnit(filename)
+	require.Nil(s.T(), err)
+	content, err := ioutil.ReadFile(filename)
+	require.Nil(s.T(), err)
+	require.Contains(s.T(), string(content), `<testsuite name="pod-wait-failed-in-project up"`)
+	require.Contains(s.T(), string(content), `name="services: pod/pod1"`)
+	require.Contains(s.T(), string(content), `<failure message=`)
+	s.down(p)
+}
+
+func TestTiming(t *testing.T) {
+	suite.Run(t, new(TimingTestSuite))
+}
@@ -1,6 +1,7 @@
 package project
 
 import (
+	gocontext "context"
 	"time"
 
 	"github.com/anduintransaction/rivendell/kubernetes"
@@ -9,36 +10,40 @@ import (
 )
 
 // Wait for pod or job to complete
-func Wait(namespace, context, kubeConfig, kind, name string, timeout int) error {
-	utils.Info("Waiting for %s %q", kind, name)
+func Wait(ctx gocontext.Context, namespace, context, kubeConfig, kind, name string, timeout int) error {
+	return WaitFor(ctx, namespace, context, kubeConfig, kind, name, "", timeout)
+}
+
+// WaitFor waits for a resource to reach a condition like condition=Ready or jsonpath={.status.phase}=Running
+func WaitFor(ctx gocontext.Context, namespace, context, kubeConfig, kind, name, condition string, timeout int) error {
+	if condition == "" {
+		utils.Info("Waiting for %s %q", kind, name)
+	} else {
+		utils.Info("Waiting for %s %q (%s)", kind, name, condition)
+	}
 	kubeContext, err := kubernetes.NewContext(namespace, context, kubeConfig)
 	if err != nil {
 		return err
 	}
-	waitChannel := make(chan error, 1)
-	go func() {
-		success, err := kubeContext.Resource().Wait(name, kind)
-		if err != nil {
-			waitChannel <- err
-			return
-		}
-		if !success {
-			waitChannel <- stacktrace.Propagate(ErrWaitFailed{name, kind}, "wait failed")
-		}
-		waitChannel <- nil
-	}()
-	if timeout <= 0 {
-		err = <-waitChannel
-	} else {
-		timer := time.NewTimer(time.Duration(timeout) * time.Second)
-		select {
-		case err = <-waitChannel:
-		case <-timer.C:
-			err = stacktrace.Propagate(ErrWaitTimeout{name, kind}, "wait timeout")
-		}
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel gocontext.CancelFunc
+		waitCtx, cancel = gocontext.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+	success, err := kubeContext.WithContext(waitCtx).Resource().WaitFor(name, kind, condition)
+	if err == nil && !success {
+		err = stacktrace.Propagate(ErrWaitFailed{name, kind}, "wait failed")
+	}
+	if err != nil && ctx.Err() == nil && waitCtx.Err() != nil {
+		err = stacktrace.Propagate(ErrWaitTimeout{name, kind}, "wait timeout")
 	}
-	if err == nil {
-		utils.Success("====> Done")
+	if err != nil {
+		if ctx.Err() == nil {
+			diagnoseFailure(kubeContext, err)
+		}
+		return err
 	}
-	return err
+	utils.Success("====> Done")
+	return nil
 }
@@ -0,0 +1,89 @@
+package project
+
+import (
+	gocontext "context"
+	"path/filepath"
+	"testing"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type WaitTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *WaitTestSuite) TestWaitPod() {
+	p := s.up("wait-pod", nil)
+	err := Wait(gocontext.Background(), s.testNamespace, "", "", "pod", "pod2", 60)
+	require.Nil(s.T(), err)
+	s.down(p)
+}
+
+func (s *WaitTestSuite) TestWaitJob() {
+	p := s.up("wait-job", nil)
+	err := Wait(gocontext.Background(), s.testNamespace, "", "", "job", "job2", 60)
+	require.Nil(s.T(), err)
+	s.down(p)
+}
+
+func (s *WaitTestSuite) TestWaitCondition() {
+	p := s.up("wait-condition", nil)
+	err := WaitFor(gocontext.Background(), s.testNamespace, "", "", "deployment", "nginx", "condition=Available", 60)
+	require.Nil(s.T(), err)
+	err = WaitFor(gocontext.Background(), s.testNamespace, "", "", "deployment", "nginx", "condition=", 60)
+	require.NotNil(s.T(), err)
+	s.down(p)
+}
+
+func (s *WaitTestSuite) TestInvalidWaitCondition() {
+	projectFile := filepath.Join(s.resourceRoot, "command-test", "wait-condition-invalid", "project.yml")
+	_, err := ReadProject(projectFile, s.testNamespace, "", "", map[string]string{}, []string{}, nil, nil)
+	require.NotNil(s.T(), err)
+	_, ok := stacktrace.RootCause(err).(kubernetes.ErrInvalidWaitCondition)
+	require.True(s.T(), ok)
+}
+
+func (s *WaitTestSuite) TestWaitNotExists() {
+	err := Wait(gocontext.Background(), "", "", "", "job", "not-exists", 0)
+	require.NotNil(s.T(), err)
+	_, ok := stacktrace.RootCause(err).(kubernetes.ErrNotExist)
+	require.True(s.T(), ok)
+}
+
+func (s *WaitTestSuite) TestJobWaitFailedInProject() {
+	s.cluster.SetOutcome(s.testNamespace, "job", "job1", kubernetes.FakeOutcomeFailed)
+	p := s.readProject("job-wait-failed-in-project", nil)
+	err := p.Up(gocontext.Background())
+	require.NotNil(s.T(), err)
+	_, ok := stacktrace.RootCause(err).(ErrWaitFailed)
+	require.True(s.T(), ok)
+	s.down(p)
+}
+
+func (s *WaitTestSuite) TestPodWaitFailedInProject() {
+	s.cluster.SetOutcome(s.testNamespace, "pod", "pod1", kubernetes.FakeOutcomeFailed)
+	p := s.readProject("pod-wait-failed-in-project", nil)
+	err := p.Up(gocontext.Background())
+	require.NotNil(s.T(), err)
+	_, ok := stacktrace.RootCause(err).(ErrWaitFailed)
+	require.True(s.T(), ok)
+	s.down(p)
+}
+
+func (s *WaitTestSuite) TestPodWaitImagePullBackOffInProject() {
+	s.cluster.SetOutcome(s.testNamespace, "pod", "pod1", kubernetes.FakeOutcomeImagePullBackOff)
+	p := s.readProject("pod-wait-failed-in-project", nil)
+	err := p.Up(gocontext.Background())
+	require.NotNil(s.T(), err)
+	failure, ok := stacktrace.RootCause(err).(kubernetes.ErrPodFailure)
+	require.True(s.T(), ok)
+	require.Equal(s.T(), "ImagePullBackOff", failure.Reason)
+	s.down(p)
+}
+
+func TestWait(t *testing.T) {
+	suite.Run(t, new(WaitTestSuite))
+}
@@ -0,0 +1,310 @@
+package project
+
+import (
+	"bufio"
+	"bytes"
+	gocontext "context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anduintransaction/rivendell/utils"
+	"github.com/palantir/stacktrace"
+)
+
+// GroupFunc processes a resource group. Its output should go to GroupOutput(ctx)
+type GroupFunc func(ctx gocontext.Context, g *ResourceGroup) error
+
+// ResourceFunc processes a resource of a group. Its output should go to GroupOutput(ctx)
+type ResourceFunc func(ctx gocontext.Context, r *Resource, g *ResourceGroup) error
+
+type groupOutputKey struct{}
+
+// GroupOutput returns where a group function should write to: a buffer printed with the group name as prefix
+// once the group is done when groups are processed concurrently, the output of the walked graph otherwise.
+// Outside of a walk, it is os.Stdout.
+func GroupOutput(ctx gocontext.Context) io.Writer {
+	if out, ok := ctx.Value(groupOutputKey{}).(io.Writer); ok {
+		return out
+	}
+	return os.Stdout
+}
+
+// walkGroups calls f on every group once all the groups it depends on (or its children when walking backward)
+// are done. Groups are walked in BFS order, or concurrently when rg.Parallelism is more than 1.
+func (rg *ResourceGraph) walkGroups(ctx gocontext.Context, backward bool, f GroupFunc) error {
+	ctx = gocontext.WithValue(ctx, groupOutputKey{}, rg.output())
+	f = rg.skipGroups(rg.observeGroups(f))
+	var outcomes *groupOutcomes
+	if rg.KeepGoing {
+		outcomes = newGroupOutcomes()
+		f = rg.keepGoing(outcomes, backward, f)
+	}
+	var err error
+	if rg.Parallelism > 1 {
+		err = rg.walkConcurrently(ctx, backward, f)
+	} else {
+		walk := rg.WalkForward
+		if backward {
+			walk = rg.WalkBackward
+		}
+		err = walk(ctx, func(g *ResourceGroup) error {
+			return f(ctx, g)
+		})
+	}
+	if err != nil || outcomes == nil {
+		return err
+	}
+	return outcomes.err()
+}
+
+// groupOutcomes records the failed, skipped and succeeded groups of a walk going on after failures
+type groupOutcomes struct {
+	mu     sync.Mutex
+	result ErrGroupsFailed
+}
+
+func newGroupOutcomes() *groupOutcomes {
+	return &groupOutcomes{result: ErrGroupsFailed{
+		Failed:  make(map[string]error),
+		Skipped: make(map[string]string),
+	}}
+}
+
+// keepGoing wraps f to record the failure of a group instead of stopping the walk, and to skip the groups after a
+// failed or skipped group. Interruptions still stop the walk.
+func (rg *ResourceGraph) keepGoing(outcomes *groupOutcomes, backward bool, f GroupFunc) GroupFunc {
+	return func(ctx gocontext.Context, g *ResourceGroup) error {
+		if cause := outcomes.failedDependency(g.dependencies(backward)); cause != "" {
+			outcomes.skip(g.Name, cause)
+			utils.Warnf(GroupOutput(ctx), "Skipping group %q, group %q failed", g.Name, cause)
+			rg.events.emit(&Event{Type: EventGroupSkipped, Group: g.Name, Error: fmt.Sprintf("group %q failed", cause)})
+			return nil
+		}
+		err := f(ctx, g)
+		if err != nil && ctx.Err() != nil {
+			return err
+		}
+		if err != nil {
+			utils.Error(err)
+			outcomes.fail(g.Name, err)
+			return nil
+		}
+		outcomes.succeed(g.Name)
+		return nil
+	}
+}
+
+// failedDependency returns the failed group which one of the dependencies failed because of, if any
+func (o *groupOutcomes) failedDependency(dependencies []string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, name := range dependencies {
+		if _, ok := o.result.Failed[name]; ok {
+			return name
+		}
+		if cause, ok := o.result.Skipped[name]; ok {
+			return cause
+		}
+	}
+	return ""
+}
+
+func (o *groupOutcomes) fail(name string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.result.Failed[name] = err
+}
+
+func (o *groupOutcomes) skip(name, cause string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.result.Skipped[name] = cause
+}
+
+func (o *groupOutcomes) succeed(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.result.Succeeded = append(o.result.Succeeded, name)
+}
+
+func (o *groupOutcomes) err() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.result.Failed) == 0 {
+		return nil
+	}
+	return stacktrace.Propagate(o.result, "groups failed")
+}
+
+// skipGroups wraps f to leave out the skipped groups
+func (rg *ResourceGraph) skipGroups(f GroupFunc) GroupFunc {
+	if len(rg.skipped) == 0 {
+		return f
+	}
+	return func(ctx gocontext.Context, g *ResourceGroup) error {
+		if !rg.skipped[g.Name] {
+			return f(ctx, g)
+		}
+		utils.Infof2(GroupOutput(ctx), "Skipping group %q, completed and unchanged since the checkpoint", g.Name)
+		rg.events.emit(&Event{Type: EventGroupSkipped, Group: g.Name})
+		return nil
+	}
+}
+
+// observeGroups wraps f to emit the start and the end of every group
+func (rg *ResourceGraph) observeGroups(f GroupFunc) GroupFunc {
+	if rg.events == nil {
+		return f
+	}
+	return func(ctx gocontext.Context, g *ResourceGroup) error {
+		rg.events.groupStarted(g)
+		start := time.Now()
+		err := f(ctx, g)
+		rg.events.groupFinished(g, start, err)
+		return err
+	}
+}
+
+type groupResult struct {
+	name   string
+	err    error
+	output *bytes.Buffer
+}
+
+// walkConcurrently starts every group as soon as its dependencies are done, running at most rg.Parallelism
+// groups at the same time. The first error cancels the running groups and no other group is started.
+func (rg *ResourceGraph) walkConcurrently(ctx gocontext.Context, backward bool, f GroupFunc) error {
+	walkCtx, cancel := gocontext.WithCancel(ctx)
+	defer cancel()
+	remaining := make(map[string]int)
+	ready := []string{}
+	for name, g := range rg.ResourceGroups {
+		remaining[name] = len(g.dependencies(backward))
+		if remaining[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	sort.Strings(ready)
+	results := make(chan *groupResult)
+	running := 0
+	var firstErr error
+	for {
+		for firstErr == nil && len(ready) > 0 && running < rg.Parallelism {
+			if ctx.Err() != nil {
+				firstErr = stacktrace.Propagate(ctx.Err(), "interrupted before group %q", ready[0])
+				break
+			}
+			name := ready[0]
+			ready = ready[1:]
+			running++
+			utils.Infof(rg.output(), "Starting group %q", name)
+			go func() {
+				output := &bytes.Buffer{}
+				groupCtx := gocontext.WithValue(walkCtx, groupOutputKey{}, &syncWriter{w: output})
+				err := f(groupCtx, rg.ResourceGroups[name])
+				results <- &groupResult{name, err, output}
+			}()
+		}
+		if running == 0 {
+			return firstErr
+		}
+		result := <-results
+		running--
+		writeGroupOutput(rg.output(), result.name, result.output)
+		if result.err != nil {
+			if firstErr == nil {
+				firstErr = result.err
+				cancel()
+			}
+			continue
+		}
+		next := []string{}
+		for _, name := range rg.ResourceGroups[result.name].dependents(backward) {
+			remaining[name]--
+			if remaining[name] == 0 {
+				next = append(next, name)
+			}
+		}
+		ready = append(ready, next...)
+		sort.Strings(ready)
+	}
+}
+
+func (g *ResourceGroup) dependencies(backward bool) []string {
+	if backward {
+		return g.Children
+	}
+	return g.Depend
+}
+
+func (g *ResourceGroup) dependents(backward bool) []string {
+	if backward {
+		return g.Depend
+	}
+	return g.Children
+}
+
+// output is where the walks write to, os.Stdout unless set by SetOutput
+func (rg *ResourceGraph) output() io.Writer {
+	if rg.out == nil {
+		return os.Stdout
+	}
+	return rg.out
+}
+
+// writeGroupOutput writes the output of a group at once, every line prefixed with the group name
+func writeGroupOutput(w io.Writer, name string, output io.Reader) {
+	buf := &bytes.Buffer{}
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		fmt.Fprintf(buf, "[%s] %s\n", name, scanner.Text())
+	}
+	_, _ = w.Write(buf.Bytes())
+}
+
+// syncWriter serializes writes from the goroutines of a group, like a timed out wait still running
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// readinessTracker makes sure the resources of a group are checked only once, even by concurrent groups
+type readinessTracker struct {
+	mu     sync.Mutex
+	groups map[*ResourceGroup]*groupReadiness
+}
+
+type groupReadiness struct {
+	once sync.Once
+	err  error
+}
+
+func newReadinessTracker() *readinessTracker {
+	return &readinessTracker{groups: make(map[*ResourceGroup]*groupReadiness)}
+}
+
+// check runs fn the first time a group is checked, later calls wait for it and return the same error
+func (t *readinessTracker) check(g *ResourceGroup, fn func() error) error {
+	t.mu.Lock()
+	readiness, ok := t.groups[g]
+	if !ok {
+		readiness = &groupReadiness{}
+		t.groups[g] = readiness
+	}
+	t.mu.Unlock()
+	readiness.once.Do(func() {
+		readiness.err = fn()
+	})
+	return readiness.err
+}
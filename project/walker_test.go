@@ -0,0 +1,36 @@
+package project
+
+import (
+	gocontext "context"
+	"testing"
+
+	"github.com/anduintransaction/rivendell/kubernetes"
+	"github.com/palantir/stacktrace"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type WalkerTestSuite struct {
+	fakeClusterSuite
+}
+
+func (s *WalkerTestSuite) TestKeepGoing() {
+	s.cluster.SetOutcome(s.testNamespace, "pod", "pod1", kubernetes.FakeOutcomeFailed)
+	p := s.readProject("keep-going", nil).SetKeepGoing(true)
+	err := p.Up(gocontext.Background())
+	require.NotNil(s.T(), err)
+	groupsErr, ok := stacktrace.RootCause(err).(ErrGroupsFailed)
+	require.True(s.T(), ok, "%v", err)
+	require.Contains(s.T(), groupsErr.Failed, "services")
+	require.Len(s.T(), groupsErr.Failed, 1)
+	require.Equal(s.T(), map[string]string{"frontend": "services"}, groupsErr.Skipped)
+	require.ElementsMatch(s.T(), []string{"pods", "configs", "workers"}, groupsErr.Succeeded)
+	kubeContext := s.kubeContext(p)
+	require.True(s.T(), s.exists(kubeContext, "workers", "configmap"))
+	require.False(s.T(), s.exists(kubeContext, "frontend", "configmap"))
+	s.down(p)
+}
+
+func TestWalker(t *testing.T) {
+	suite.Run(t, new(WalkerTestSuite))
+}
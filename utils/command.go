@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"os/exec"
@@ -13,11 +14,13 @@ import (
 
 // Command only Unix is supported
 type Command struct {
+	ctx           context.Context
 	name          string
 	args          []string
 	execCmd       *exec.Cmd
 	defaultStdout *bytes.Buffer
 	defaultStderr *bytes.Buffer
+	startTime     time.Time
 }
 
 // CommandStatus .
@@ -30,10 +33,16 @@ type CommandStatus struct {
 
 // NewCommand .
 func NewCommand(name string, args ...string) *Command {
+	return NewCommandContext(context.Background(), name, args...)
+}
+
+// NewCommandContext creates a command killed when the context is done
+func NewCommandContext(ctx context.Context, name string, args ...string) *Command {
 	cmd := &Command{
+		ctx:           ctx,
 		name:          name,
 		args:          args,
-		execCmd:       exec.Command(name, args...),
+		execCmd:       exec.CommandContext(ctx, name, args...),
 		defaultStdout: &bytes.Buffer{},
 		defaultStderr: &bytes.Buffer{},
 	}
@@ -88,10 +97,31 @@ func (cmd *Command) SilenceOutput() {
 // Run the command.
 // If the command exit non-zero, the returned error is still nil.
 func (cmd *Command) Run() (*CommandStatus, error) {
-	startTime := time.Now()
-	err := cmd.execCmd.Run()
-	endTime := time.Now()
-	elaspedTime := endTime.Sub(startTime)
+	err := cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+	return cmd.Wait()
+}
+
+// Start the command without waiting for it to complete
+func (cmd *Command) Start() error {
+	cmd.startTime = time.Now()
+	err := cmd.execCmd.Start()
+	if err != nil {
+		return stacktrace.Propagate(err, "execute command error")
+	}
+	return nil
+}
+
+// Wait for a started command to complete.
+// If the command exit non-zero, the returned error is still nil, unless it was killed because its context is done.
+func (cmd *Command) Wait() (*CommandStatus, error) {
+	err := cmd.execCmd.Wait()
+	elaspedTime := time.Since(cmd.startTime)
+	if err != nil && cmd.ctx.Err() != nil {
+		return nil, stacktrace.Propagate(cmd.ctx.Err(), "command %q cancelled", cmd.name)
+	}
 	if err == nil {
 		return &CommandStatus{
 			ExitCode:    0,
@@ -113,6 +143,14 @@ func (cmd *Command) Run() (*CommandStatus, error) {
 	}, nil
 }
 
+// Kill a started command
+func (cmd *Command) Kill() error {
+	if cmd.execCmd.Process == nil {
+		return nil
+	}
+	return cmd.execCmd.Process.Kill()
+}
+
 // ExecuteCommand and redirect output to standard output/standard error
 func ExecuteCommand(command string, args ...string) (*CommandStatus, error) {
 	silentFlag := os.Getenv("SILENCE_OUTPUT")
@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"context"
 	"io/ioutil"
 	"testing"
+	"time"
 
+	"github.com/palantir/stacktrace"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
@@ -34,6 +37,16 @@ func (s *CommandTestSuite) TestCommandFailure() {
 	require.NotNil(s.T(), err, "command should not run successfully")
 }
 
+func (s *CommandTestSuite) TestCommandCancelled() {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	startTime := time.Now()
+	_, err := NewCommandContext(ctx, "sleep", "10").Run()
+	require.NotNil(s.T(), err, "cancelled command should fail")
+	require.Equal(s.T(), context.DeadlineExceeded, stacktrace.RootCause(err))
+	require.True(s.T(), time.Since(startTime) < 5*time.Second, "command should be killed")
+}
+
 func TestCommand(t *testing.T) {
 	suite.Run(t, new(CommandTestSuite))
 }
@@ -3,16 +3,22 @@ package utils
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
 
 // Ask .
 func Ask(question string, possibleValue ...string) {
+	Askf(os.Stdout, question, possibleValue...)
+}
+
+// Askf writes a question to out
+func Askf(out io.Writer, question string, possibleValue ...string) {
 	if len(possibleValue) > 0 {
-		fmt.Printf(">>> %s (%s): ", question, strings.Join(possibleValue, ", "))
+		fmt.Fprintf(out, ">>> %s (%s): ", question, strings.Join(possibleValue, ", "))
 	} else {
-		fmt.Printf(">>> %s: ", question)
+		fmt.Fprintf(out, ">>> %s: ", question)
 	}
 }
 
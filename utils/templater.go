@@ -17,6 +17,19 @@ import (
 
 var cwdStack = NewStringStack()
 
+// ErrRender is returned when a template cannot be parsed or executed
+type ErrRender struct {
+	Err error
+}
+
+func (err ErrRender) Error() string {
+	return err.Err.Error()
+}
+
+func (err ErrRender) Unwrap() error {
+	return err.Err
+}
+
 // ExecuteTemplate .
 func ExecuteTemplate(templateFile string, variables map[string]string) ([]byte, error) {
 	content, err := ioutil.ReadFile(templateFile)
@@ -55,13 +68,13 @@ func ExecuteTemplateContent(rootDir string, content []byte, variables map[string
 		}).
 		Parse(string(content))
 	if err != nil {
-		return nil, stacktrace.Propagate(err, "cannot parse template")
+		return nil, stacktrace.Propagate(ErrRender{err}, "cannot parse template")
 	}
 	tmpl = tmpl.Option("missingkey=error")
 	b := &bytes.Buffer{}
 	err = tmpl.Execute(b, variables)
 	if err != nil {
-		return nil, stacktrace.Propagate(err, "cannot execute template")
+		return nil, stacktrace.Propagate(ErrRender{err}, "cannot execute template")
 	}
 	return b.Bytes(), nil
 }